@@ -0,0 +1,60 @@
+package migrator
+
+import (
+	"database/sql"
+	"github.com/golang-migrate/migrate/v4/database"
+	"runtime"
+	"runtime/debug"
+	"sort"
+)
+
+const (
+	modulePath        = "github.com/anyufly/file-migrator"
+	migrateModulePath = "github.com/golang-migrate/migrate/v4"
+)
+
+// About is what the binary running the migrator was built with, for triaging issues.
+type About struct {
+	Version        string   `json:"version"`
+	MigrateVersion string   `json:"migrate_version"`
+	GoVersion      string   `json:"go_version"`
+	GitCommit      string   `json:"git_commit,omitempty"`
+	Drivers        []string `json:"drivers"`
+	SQLDrivers     []string `json:"sql_drivers"`
+}
+
+// moduleVersion returns the version path was built at, "(devel)" when unknown.
+func moduleVersion(info *debug.BuildInfo, path string) string {
+	if info.Main.Path == path && info.Main.Version != "" {
+		return info.Main.Version
+	}
+
+	for _, dep := range info.Deps {
+		if dep.Path != path {
+			continue
+		}
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		if dep.Version != "" {
+			return dep.Version
+		}
+	}
+	return "(devel)"
+}
+
+// BuildAbout returns the versions of this package and golang-migrate, the Go version and git
+// commit of the binary, and the golang-migrate and database/sql drivers compiled in.
+func BuildAbout() *About {
+	about := &About{Version: "(devel)", MigrateVersion: "(devel)", GoVersion: runtime.Version(),
+		Drivers: database.List(), SQLDrivers: sql.Drivers()}
+	sort.Strings(about.Drivers)
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		about.Version = moduleVersion(info, modulePath)
+		about.MigrateVersion = moduleVersion(info, migrateModulePath)
+	}
+	_, about.GitCommit = buildInfo()
+
+	return about
+}
@@ -0,0 +1,104 @@
+package migrator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const approvalSuffix = ".approval"
+
+var (
+	errNotApproved  = errors.New("plan is not approved, run approve on it first")
+	errSelfApproval = errors.New("a plan can't be approved by the person who made it")
+	errApprovalPlan = errors.New("approval is for another plan")
+)
+
+type Approval struct {
+	PlanChecksum string    `json:"plan_checksum"`
+	Approver     string    `json:"approver"`
+	ApprovedAt   time.Time `json:"approved_at"`
+}
+
+type signedApproval struct {
+	Approval  string `json:"approval"`
+	Signature string `json:"signature"`
+}
+
+// ApprovePlan signs an approval of the plan file at path, written next to it with the .approval
+// suffix, that ApplyPlan requires once RequireApproval is set.
+func ApprovePlan(path string, signer Signer) (*Approval, error) {
+	plan, err := ReadPlan(path)
+	if err != nil {
+		return nil, err
+	}
+
+	approval := &Approval{PlanChecksum: plan.checksum, Approver: operatorName(), ApprovedAt: time.Now().UTC()}
+	if approval.Approver == plan.CreatedBy {
+		return nil, errSelfApproval
+	}
+
+	payload, err := json.Marshal(approval)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := signer.Sign(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.MarshalIndent(&signedApproval{Approval: string(payload), Signature: string(signature)}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return approval, os.WriteFile(path+approvalSuffix, append(body, '\n'), 0666)
+}
+
+func readApproval(path string) (*signedApproval, error) {
+	body, err := os.ReadFile(path + approvalSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := &signedApproval{}
+	if err = json.Unmarshal(body, signed); err != nil {
+		return nil, fmt.Errorf("%s: %w", path+approvalSuffix, err)
+	}
+	return signed, nil
+}
+
+// RequireApproval marks the database as protected: ApplyPlan only runs plans approved by someone
+// other than their author, with a signature verifier accepts.
+func (m *Migrator) RequireApproval(verifier SignatureVerifier) {
+	m.approvalVerifier = verifier
+}
+
+func (m *Migrator) checkApproval(plan *Plan) error {
+	if plan.approval == nil {
+		return errNotApproved
+	}
+
+	if err := m.approvalVerifier.Verify([]byte(plan.approval.Approval), []byte(plan.approval.Signature)); err != nil {
+		return fmt.Errorf("approval: %w", err)
+	}
+
+	approval := &Approval{}
+	if err := json.Unmarshal([]byte(plan.approval.Approval), approval); err != nil {
+		return err
+	}
+
+	if approval.PlanChecksum != plan.checksum {
+		return errApprovalPlan
+	}
+
+	if approval.Approver == plan.CreatedBy {
+		return errSelfApproval
+	}
+
+	m.logger.Info("plan approved", "approver", approval.Approver, "approved_at", approval.ApprovedAt)
+	return nil
+}
@@ -0,0 +1,207 @@
+package migrator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"os"
+	"os/exec"
+	"os/user"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+const auditTable = "schema_migrations_audit"
+
+type AuditRecord struct {
+	Operator    string            `json:"operator"`
+	Host        string            `json:"host"`
+	ToolVersion string            `json:"tool_version"`
+	GitCommit   string            `json:"git_commit,omitempty"`
+	CommandLine string            `json:"command_line"`
+	Command     string            `json:"command"`
+	StartedAt   time.Time         `json:"started_at"`
+	FinishedAt  time.Time         `json:"finished_at"`
+	FromVersion int               `json:"from_version"`
+	ToVersion   int               `json:"to_version"`
+	Success     bool              `json:"success"`
+	Error       string            `json:"error,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+}
+
+// EnableAudit records who ran each migrating command, from where, and with which outcome, in the audit table.
+func (m *Migrator) EnableAudit() {
+	m.auditEnabled = true
+}
+
+// SetReportPath makes every migrating command write its audit record as JSON to reportPath.
+func (m *Migrator) SetReportPath(reportPath string) {
+	m.reportPath = reportPath
+}
+
+func operatorName() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+func buildInfo() (toolVersion string, gitCommit string) {
+	toolVersion = "(devel)"
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+
+	if info.Main.Version != "" {
+		toolVersion = info.Main.Version
+	}
+
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			gitCommit = setting.Value
+		}
+	}
+	return
+}
+
+func gitHead() string {
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func (m *Migrator) currentVersion() int {
+	version, _, err := m.migrate.Version()
+	if err != nil {
+		return database.NilVersion
+	}
+	return int(version)
+}
+
+func (m *Migrator) newAuditRecord(command string) *AuditRecord {
+	host, _ := os.Hostname()
+	toolVersion, gitCommit := buildInfo()
+	if gitCommit == "" {
+		gitCommit = gitHead()
+	}
+
+	return &AuditRecord{
+		Operator:    operatorName(),
+		Host:        host,
+		ToolVersion: toolVersion,
+		GitCommit:   gitCommit,
+		CommandLine: strings.Join(os.Args, " "),
+		Command:     command,
+		StartedAt:   time.Now().UTC(),
+		FromVersion: m.currentVersion(),
+		Labels:      m.labels,
+	}
+}
+
+func (m *Migrator) ensureAuditTable() error {
+	_, err := m.db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	operator VARCHAR(255) NOT NULL,
+	host VARCHAR(255) NOT NULL,
+	tool_version VARCHAR(64) NOT NULL,
+	git_commit VARCHAR(64),
+	command_line TEXT NOT NULL,
+	command VARCHAR(32) NOT NULL,
+	started_at TIMESTAMP NOT NULL,
+	finished_at TIMESTAMP NOT NULL,
+	from_version BIGINT NOT NULL,
+	to_version BIGINT NOT NULL,
+	success BOOLEAN NOT NULL,
+	error TEXT,
+	labels TEXT
+)`, auditTable))
+	if err != nil {
+		return err
+	}
+	return m.ensureLabelsColumn(auditTable)
+}
+
+func (m *Migrator) insertAudit(record *AuditRecord) error {
+	if err := m.ensureAuditTable(); err != nil {
+		return err
+	}
+
+	d := m.dialect
+	placeholders := make([]string, 13)
+	for i := range placeholders {
+		placeholders[i] = d.placeholder(i + 1)
+	}
+
+	_, err := m.db.Exec(fmt.Sprintf(
+		`INSERT INTO %s (operator, host, tool_version, git_commit, command_line, command, started_at, finished_at,
+	from_version, to_version, success, error, labels) VALUES (%s)`, auditTable, strings.Join(placeholders, ", ")),
+		record.Operator, record.Host, record.ToolVersion, record.GitCommit, record.CommandLine, record.Command,
+		record.StartedAt, record.FinishedAt, record.FromVersion, record.ToVersion, record.Success, record.Error,
+		encodeLabels(record.Labels))
+	return err
+}
+
+func (m *Migrator) writeReport(record *AuditRecord) error {
+	body, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(m.reportPath, append(body, '\n'), 0666)
+}
+
+// audited runs a migrating command, recording it in the audit table, the report and the JSON log when enabled.
+func (m *Migrator) audited(command string, run func() error) error {
+	if m.readOnly {
+		return errReadOnly
+	}
+
+	m.command = command
+	defer func() {
+		m.command = ""
+		m.progress = nil
+	}()
+
+	if _, ok := applyCommands[command]; ok {
+		if err := m.checkManifest(); err != nil {
+			return err
+		}
+	}
+
+	if !m.auditEnabled && m.reportPath == "" && m.logFormat != LogFormatJSON {
+		return m.withCallbacks(command, run)
+	}
+
+	record := m.newAuditRecord(command)
+	err := m.withCallbacks(command, run)
+
+	record.FinishedAt = time.Now().UTC()
+	record.ToVersion = m.currentVersion()
+	record.Success = err == nil || errors.Is(err, migrate.ErrNoChange)
+	if !record.Success {
+		record.Error = err.Error()
+	}
+
+	m.logCommand(record)
+
+	if m.auditEnabled {
+		if m.db == nil {
+			m.logger.Error("can't record audit", "error", errNoDB)
+		} else if auditErr := m.insertAudit(record); auditErr != nil {
+			m.logger.Error("can't record audit", "error", auditErr)
+		}
+	}
+
+	if m.reportPath != "" {
+		if reportErr := m.writeReport(record); reportErr != nil {
+			m.logger.Error("can't write report", "path", m.reportPath, "error", reportErr)
+		}
+	}
+
+	return err
+}
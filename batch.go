@@ -0,0 +1,136 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const batchPlaceholder = ":batch"
+
+var (
+	errBatchSize   = errors.New("batch size must be a positive number")
+	errBatchNoSQL  = errors.New("batch directive has no statement")
+	errBatchNoDB   = errors.New("batch migrations need a database handle, call SetDB first")
+	errBatchOption = errors.New("unknown batch option")
+)
+
+type BatchOptions struct {
+	// Size replaces :batch in the statement, which should only touch that many rows per run.
+	Size int
+	// Pause is slept between batches, throttling the backfill.
+	Pause time.Duration
+	// Progress is called after every batch with the rows affected so far.
+	Progress func(batches int, rows int64)
+}
+
+// Batch runs query, with :batch replaced by the batch size, until it affects no row, so that a
+// backfill such as UPDATE ... WHERE id IN (SELECT id ... LIMIT :batch) doesn't lock the whole table
+// at once. It returns the rows affected.
+func Batch(db execer, query string, opts BatchOptions) (int64, error) {
+	if opts.Size <= 0 {
+		return 0, errBatchSize
+	}
+	query = strings.ReplaceAll(query, batchPlaceholder, strconv.Itoa(opts.Size))
+
+	var total int64
+	for batches := 1; ; batches++ {
+		result, err := db.Exec(query)
+		if err != nil {
+			return total, err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+
+		if affected == 0 {
+			return total, nil
+		}
+		total += affected
+
+		if opts.Progress != nil {
+			opts.Progress(batches, total)
+		}
+
+		if opts.Pause > 0 {
+			time.Sleep(opts.Pause)
+		}
+	}
+}
+
+// parseBatchDirective reads `-- migrator:batch size=N [pause=DURATION] [sql=STATEMENT]`, the
+// statement being the rest of the line, or else the rest of the migration.
+func parseBatchDirective(args string, body []byte) (string, BatchOptions, error) {
+	opts := BatchOptions{}
+	query := ""
+
+	for args != "" {
+		if strings.HasPrefix(args, "sql=") {
+			query = strings.TrimPrefix(args, "sql=")
+			break
+		}
+
+		field, rest, _ := strings.Cut(args, " ")
+		args = strings.TrimSpace(rest)
+
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "size":
+			size, err := strconv.Atoi(value)
+			if err != nil || size <= 0 {
+				return "", opts, errBatchSize
+			}
+			opts.Size = size
+		case "pause":
+			pause, err := time.ParseDuration(value)
+			if err != nil {
+				return "", opts, fmt.Errorf("batch pause: %w", err)
+			}
+			opts.Pause = pause
+		default:
+			return "", opts, fmt.Errorf("%w: %s", errBatchOption, key)
+		}
+	}
+
+	if query == "" {
+		lines := make([]string, 0)
+		for _, line := range strings.Split(string(body), "\n") {
+			if !strings.HasPrefix(strings.TrimSpace(line), directivePrefix) {
+				lines = append(lines, line)
+			}
+		}
+		query = stripComments(strings.Join(lines, "\n"))
+	}
+
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+	if query == "" {
+		return "", opts, errBatchNoSQL
+	}
+	return query, opts, nil
+}
+
+// runBatch runs a `-- migrator:batch` migration, logging its progress.
+func (d *databaseDriver) runBatch(args string, body []byte) error {
+	if d.migrator.db == nil {
+		return errBatchNoDB
+	}
+
+	query, opts, err := parseBatchDirective(args, body)
+	if err != nil {
+		return err
+	}
+
+	opts.Progress = func(batches int, rows int64) {
+		d.migrator.logger.Info("batch done", "batches", batches, "rows", rows)
+	}
+
+	rows, err := Batch(d.migrator.db, query, opts)
+	if err == nil {
+		d.migrator.logger.Info("batches finished", "rows", rows)
+	}
+	return err
+}
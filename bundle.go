@@ -0,0 +1,179 @@
+package migrator
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var errUnsafeBundleEntry = errors.New("bundle contains an entry outside of its root")
+
+func writeTarFile(tw *tar.Writer, name string, body []byte, modTime time.Time) error {
+	err := tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(body)),
+		ModTime:  modTime,
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = tw.Write(body)
+	return err
+}
+
+// Bundle writes a tar.gz archive of the migrations with a MANIFEST.json of their versions and
+// checksums, signed by signer when one is given, that UseBundle can run from.
+func (m *Migrator) Bundle(w io.Writer, signer Signer) error {
+	fsys := m.source.fsys
+
+	manifest, err := buildManifest(fsys, m.source.path)
+	if err != nil {
+		return err
+	}
+
+	manifestBody, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	for _, entry := range manifest.Files {
+		body, err := fs.ReadFile(fsys, entry.File)
+		if err != nil {
+			return err
+		}
+
+		// the bundle holds the included files inlined, as they can be out of the migrations directory
+		if body, err = expandIncludes(m.source.path, entry.File, body); err != nil {
+			return err
+		}
+
+		if err = writeTarFile(tw, entry.File, body, manifest.CreatedAt); err != nil {
+			return err
+		}
+	}
+
+	if err = writeTarFile(tw, manifestFile, manifestBody, manifest.CreatedAt); err != nil {
+		return err
+	}
+
+	if signer != nil {
+		signature, err := signer.Sign(manifestBody)
+		if err != nil {
+			return err
+		}
+
+		if err = writeTarFile(tw, signer.SignatureFile(), signature, manifest.CreatedAt); err != nil {
+			return err
+		}
+	}
+
+	if err = tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+func extractBundle(path string, dir string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if header.Name != filepath.Base(header.Name) || strings.HasPrefix(header.Name, ".") {
+			return fmt.Errorf("%w: %s", errUnsafeBundleEntry, header.Name)
+		}
+
+		out, err := os.OpenFile(filepath.Join(dir, header.Name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(out, tr)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// UseBundle runs the migrations of an archive made by Bundle, after checking its files against its
+// manifest, and the signature of its manifest when verifier is given.
+func (m *Migrator) UseBundle(path string, verifier SignatureVerifier) error {
+	dir, err := os.MkdirTemp("", "migrator-bundle-")
+	if err != nil {
+		return err
+	}
+
+	if err = m.verifyBundle(path, dir, verifier); err != nil {
+		_ = os.RemoveAll(dir)
+		return err
+	}
+
+	if err = m.SetMigrationsPath(dir); err != nil {
+		_ = os.RemoveAll(dir)
+		return err
+	}
+
+	if m.bundleDir != "" {
+		_ = os.RemoveAll(m.bundleDir)
+	}
+	m.bundleDir = dir
+	return nil
+}
+
+func (m *Migrator) verifyBundle(path string, dir string, verifier SignatureVerifier) error {
+	if err := extractBundle(path, dir); err != nil {
+		return err
+	}
+
+	fsys := os.DirFS(dir)
+	manifest, manifestBody, err := readManifest(fsys)
+	if err != nil {
+		return err
+	}
+
+	if verifier != nil {
+		if err = verifyManifestSignature(fsys, manifestBody, verifier); err != nil {
+			return err
+		}
+	}
+
+	return manifest.verify(fsys, dir)
+}
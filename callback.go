@@ -0,0 +1,90 @@
+package migrator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source"
+	"io/fs"
+	"strings"
+)
+
+const (
+	callbackBeforeAll  = "beforeAll"
+	callbackAfterAll   = "afterAll"
+	callbackBeforeEach = "beforeEach"
+	callbackAfterEach  = "afterEach"
+	callbackOnError    = "onError"
+)
+
+var callbackNames = []string{callbackBeforeAll, callbackAfterAll, callbackBeforeEach, callbackAfterEach, callbackOnError}
+
+// applyCommands are the commands that run migrations, and so the callback files.
+var applyCommands = map[string]struct{}{"up": {}, "down": {}, "goto": {}, "rollback": {}, "apply": {}, "resume": {}}
+
+func isCallbackFile(name string) bool {
+	for _, callback := range callbackNames {
+		if strings.HasPrefix(name, callback+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// runCallback executes the callback file of the migrations directory named after callback, if any,
+// e.g. beforeEach.sql before every migration of a run, rendered as the migrations are.
+func (m *Migrator) runCallback(callback string) error {
+	entries, err := fs.ReadDir(m.source.fsys, ".")
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), callback+".") {
+			continue
+		}
+
+		body, err := fs.ReadFile(m.source.fsys, e.Name())
+		if err != nil {
+			return err
+		}
+
+		if body, err = m.renderMigration(&source.Migration{Identifier: callback, Raw: e.Name()}, body); err != nil {
+			return err
+		}
+
+		m.logger.Printf("running callback %s", e.Name())
+
+		if err = m.driver.(*databaseDriver).Driver.Run(bytes.NewReader(body)); err != nil {
+			return fmt.Errorf("callback %s: %w", e.Name(), err)
+		}
+		return nil
+	}
+
+	return nil
+}
+
+// withCallbacks runs the beforeAll, afterAll and onError callbacks around the run of a command.
+func (m *Migrator) withCallbacks(command string, run func() error) error {
+	if _, ok := applyCommands[command]; !ok {
+		return run()
+	}
+
+	if err := m.runCallback(callbackBeforeAll); err != nil {
+		return err
+	}
+
+	err := run()
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		if callbackErr := m.runCallback(callbackOnError); callbackErr != nil {
+			m.logger.Error("can't run callback", "callback", callbackOnError, "error", callbackErr)
+		}
+		return err
+	}
+
+	if callbackErr := m.runCallback(callbackAfterAll); callbackErr != nil {
+		return callbackErr
+	}
+	return err
+}
@@ -0,0 +1,53 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+func (m *Migrator) close() (error, error) {
+	m.closeOnce.Do(func() {
+		m.closeSourceErr, m.closeDatabaseErr = m.migrate.Close()
+		m.closeDatabaseErr = errors.Join(m.closeDatabaseErr, m.closeModules())
+		if m.ownsDB {
+			m.closeDatabaseErr = errors.Join(m.closeDatabaseErr, m.db.Close())
+		}
+
+		if m.bundleDir != "" {
+			_ = os.RemoveAll(m.bundleDir)
+		}
+
+		if m.logFile != nil {
+			_ = m.logFile.Close()
+		}
+	})
+	return m.closeSourceErr, m.closeDatabaseErr
+}
+
+// CloseContext closes the source and the database driver, giving up when ctx is done, and returns
+// their errors joined. It can be called several times, only the first call closes.
+func (m *Migrator) CloseContext(ctx context.Context) error {
+	done := make(chan error, 1)
+
+	go func() {
+		sourceErr, databaseErr := m.close()
+
+		var errs []error
+		if sourceErr != nil {
+			errs = append(errs, fmt.Errorf("close source: %w", sourceErr))
+		}
+		if databaseErr != nil {
+			errs = append(errs, fmt.Errorf("close database: %w", databaseErr))
+		}
+		done <- errors.Join(errs...)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("close migrator: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,333 @@
+// Command file-migrator runs the migrate commands against a postgres, mysql or sqlite database,
+// without writing a Go wrapper supplying the driver:
+//
+//	file-migrator --database postgres://user@host/db --path migrations up
+//	file-migrator --driver sqlite3 --database app.db version
+//
+// Run without arguments, as the entrypoint of an init container, it's configured by the
+// environment instead and logs json lines, see envArgs.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/anyufly/file-migrator"
+	_ "github.com/golang-migrate/migrate/v4/database/mysql"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/spf13/cobra"
+	"os"
+	"sort"
+	"strings"
+)
+
+const usage = `usage: file-migrator --database URL [--driver DRIVER] [--path DIR] [--password-file FILE | --password-stdin] COMMAND [ARGS]
+
+  --database URL        The database to migrate, e.g. postgres://user@host:5432/db?sslmode=disable
+  --driver DRIVER       The driver of a database URL without scheme: postgres, mysql or sqlite3
+  --path DIR            The migrations directory (default: migrations)
+  --password-file FILE  Read the password of the user of the database URL from FILE
+  --password-stdin      Read the password of the user of the database URL from stdin
+  --ssh-host HOST       Connect through the SSH bastion HOST[:PORT]
+  --ssh-user USER       The user of the SSH bastion
+  --ssh-key FILE        The private key of the user of the SSH bastion
+  --ssh-known-hosts F   The known hosts checking the SSH bastion (default: ~/.ssh/known_hosts)
+  --socks-proxy URL     Connect through the SOCKS5 proxy URL, e.g. socks5://host:1080
+
+Run file-migrator --database URL help for the commands.
+
+Without arguments, DATABASE_URL, DATABASE_DRIVER, DATABASE_PASSWORD_FILE, MIGRATIONS_PATH and
+COMMAND (default: up) configure the run, and MIGRATOR_<FLAG> sets --<flag>, e.g. MIGRATOR_LOCK_TIMEOUT=30,
+or MIGRATOR_HISTORY=true to record the migration history. Variables naming no flag of the command
+are ignored with a warning.
+`
+
+const envFlagPrefix = "MIGRATOR_"
+
+// mainFlags are the flags run handles itself, before the command.
+var mainFlags = map[string]struct{}{"database": {}, "driver": {}, "path": {}, "password-file": {}, "password-stdin": {},
+	"ssh-host": {}, "ssh-user": {}, "ssh-key": {}, "ssh-known-hosts": {}, "socks-proxy": {}}
+
+var drivers = map[string]string{"postgres": "postgres", "postgresql": "postgres", "mysql": "mysql", "sqlite": "sqlite3", "sqlite3": "sqlite3"}
+
+// extractFlag removes the flag name and its value from args, returning its last value.
+func extractFlag(args []string, name string) (string, []string, error) {
+	var value string
+	rest := make([]string, 0, len(args))
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+
+		flag := strings.TrimLeft(arg, "-")
+		if flag == arg {
+			rest = append(rest, arg)
+			continue
+		}
+
+		if v, ok := strings.CutPrefix(flag, name+"="); ok {
+			value = v
+			continue
+		}
+
+		if flag != name {
+			rest = append(rest, arg)
+			continue
+		}
+
+		if i+1 == len(args) {
+			return "", nil, fmt.Errorf("flag --%s needs a value", name)
+		}
+		value = args[i+1]
+		i++
+	}
+
+	return value, rest, nil
+}
+
+// extractBoolFlag removes the flag name from args, returning whether it was set.
+func extractBoolFlag(args []string, name string) (bool, []string) {
+	set := false
+	rest := make([]string, 0, len(args))
+
+	for i, arg := range args {
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+
+		if arg == "--"+name || arg == "-"+name {
+			set = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+
+	return set, rest
+}
+
+// databaseURL returns url with the scheme of driver when it has none, checking that they agree.
+func databaseURL(url, driver string) (string, error) {
+	if driver == "" {
+		return url, nil
+	}
+
+	scheme, ok := drivers[driver]
+	if !ok {
+		return "", fmt.Errorf("unknown driver %s, use postgres, mysql or sqlite3", driver)
+	}
+
+	if urlScheme, _, ok := strings.Cut(url, "://"); ok {
+		if drivers[urlScheme] != scheme {
+			return "", fmt.Errorf("the database url is a %s url, not %s", urlScheme, driver)
+		}
+		return url, nil
+	}
+	return scheme + "://" + url, nil
+}
+
+// envFlag is a MIGRATOR_<FLAG> variable of the environment.
+type envFlag struct {
+	variable string
+	name     string
+	value    string
+}
+
+// envArgs returns the arguments configured by the environment: DATABASE_URL, DATABASE_DRIVER,
+// DATABASE_PASSWORD_FILE, MIGRATIONS_PATH and the COMMAND to run, up by default, with the flags of
+// run itself, and the other flags, MIGRATOR_LOCK_TIMEOUT=30 giving --lock-timeout=30, which are
+// only known once the command is. The log is json lines unless MIGRATOR_LOG_FORMAT is set.
+func envArgs(environ []string) ([]string, []*envFlag) {
+	env := make(map[string]string, len(environ))
+	for _, variable := range environ {
+		if name, value, ok := strings.Cut(variable, "="); ok {
+			env[name] = value
+		}
+	}
+
+	args := []string{"--database", env["DATABASE_URL"]}
+	if driver := env["DATABASE_DRIVER"]; driver != "" {
+		args = append(args, "--driver", driver)
+	}
+	if passwordFile := env["DATABASE_PASSWORD_FILE"]; passwordFile != "" {
+		args = append(args, "--password-file", passwordFile)
+	}
+	if path := env["MIGRATIONS_PATH"]; path != "" {
+		args = append(args, "--path", path)
+	}
+
+	command := strings.Fields(env["COMMAND"])
+	if len(command) == 0 {
+		command = []string{"up"}
+	}
+	args = append(args, command...)
+
+	flags := make([]*envFlag, 0)
+	for variable, value := range env {
+		if flag, ok := strings.CutPrefix(variable, envFlagPrefix); ok && flag != "" {
+			name := strings.ReplaceAll(strings.ToLower(flag), "_", "-")
+			if _, ok = mainFlags[name]; ok {
+				args = append(args, "--"+name+"="+value)
+				continue
+			}
+			flags = append(flags, &envFlag{variable: variable, name: name, value: value})
+		}
+	}
+	sort.Slice(flags, func(i, j int) bool {
+		return flags[i].name < flags[j].name
+	})
+
+	if _, ok := env[envFlagPrefix+"LOG_FORMAT"]; !ok {
+		flags = append(flags, &envFlag{variable: envFlagPrefix + "LOG_FORMAT", name: "log-format", value: "json"})
+	}
+	return args, flags
+}
+
+// envFlagArgs returns the args of the flags naming a flag of cmd, warning about the others, which
+// would fail the command, or be a typo silently ignored by commands not parsing flags.
+func envFlagArgs(cmd *cobra.Command, flags []*envFlag) []string {
+	args := make([]string, 0, len(flags))
+	for _, flag := range flags {
+		if cmd.Flag(flag.name) == nil {
+			fmt.Fprintf(os.Stderr, "file-migrator: ignoring %s, %s has no --%s flag\n", flag.variable, cmd.CommandPath(), flag.name)
+			continue
+		}
+		args = append(args, "--"+flag.name+"="+flag.value)
+	}
+	return args
+}
+
+// openTunnel returns url through the SSH bastion or the SOCKS proxy of the tunnel flags, with the
+// tunnel to close, nil without them.
+func openTunnel(url string, flags map[string]string) (string, *migrator.Tunnel, error) {
+	var tunnel *migrator.Tunnel
+	var err error
+
+	switch {
+	case flags["ssh-host"] != "" && flags["socks-proxy"] != "":
+		return "", nil, errors.New("--ssh-host and --socks-proxy can't be used together")
+	case flags["ssh-host"] != "":
+		tunnel, url, err = migrator.OpenSSHTunnel(url, migrator.SSHConfig{Host: flags["ssh-host"], User: flags["ssh-user"],
+			KeyFile: flags["ssh-key"], KnownHostsFile: flags["ssh-known-hosts"]})
+	case flags["socks-proxy"] != "":
+		tunnel, url, err = migrator.OpenSOCKSTunnel(url, flags["socks-proxy"])
+	default:
+		return url, nil, nil
+	}
+
+	if err != nil {
+		return "", nil, fmt.Errorf("can't open the tunnel: %w", err)
+	}
+	return url, tunnel, nil
+}
+
+func run(args []string) error {
+	var envFlags []*envFlag
+	if len(args) == 0 && os.Getenv("DATABASE_URL") != "" {
+		args, envFlags = envArgs(os.Environ())
+	}
+
+	url, args, err := extractFlag(args, "database")
+	if err != nil {
+		return err
+	}
+
+	driver, args, err := extractFlag(args, "driver")
+	if err != nil {
+		return err
+	}
+
+	path, args, err := extractFlag(args, "path")
+	if err != nil {
+		return err
+	}
+
+	passwordFile, args, err := extractFlag(args, "password-file")
+	if err != nil {
+		return err
+	}
+
+	passwordStdin, args := extractBoolFlag(args, "password-stdin")
+
+	tunnelFlags := make(map[string]string)
+	for _, name := range []string{"ssh-host", "ssh-user", "ssh-key", "ssh-known-hosts", "socks-proxy"} {
+		if tunnelFlags[name], args, err = extractFlag(args, name); err != nil {
+			return err
+		}
+	}
+
+	if url == "" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if passwordFile != "" && passwordStdin {
+		return errors.New("--password-file and --password-stdin can't be used together")
+	}
+
+	var password string
+	switch {
+	case passwordFile != "":
+		password, err = migrator.ReadPasswordFile(passwordFile)
+	case passwordStdin:
+		password, err = migrator.ReadPassword(os.Stdin)
+	}
+	if err != nil {
+		return fmt.Errorf("can't read the password: %w", err)
+	}
+
+	if url, err = databaseURL(url, driver); err != nil {
+		return err
+	}
+
+	if passwordFile != "" || passwordStdin {
+		if url, err = migrator.URLWithPassword(url, password); err != nil {
+			return err
+		}
+	}
+
+	url, tunnel, err := openTunnel(url, tunnelFlags)
+	if err != nil {
+		return err
+	}
+	if tunnel != nil {
+		defer tunnel.Close()
+	}
+
+	if path == "" {
+		path = "migrations"
+	}
+
+	m, err := migrator.NewFromURL(url, path, nil)
+	if err != nil {
+		return err
+	}
+
+	if err = m.OpenDB(); err != nil {
+		return err
+	}
+
+	command := m.CobraCommand(migrator.WithUse("file-migrator COMMAND"))
+	command.SilenceErrors = true
+
+	if len(envFlags) > 0 {
+		cmd, _, err := command.Find(args)
+		if err != nil {
+			return err
+		}
+		args = append(args, envFlagArgs(cmd, envFlags)...)
+	}
+
+	command.SetArgs(args)
+	return command.Execute()
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "file-migrator:", err)
+		os.Exit(1)
+	}
+}
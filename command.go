@@ -1,10 +1,13 @@
 package migrator
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/spf13/cobra"
+	"io"
 	"os"
 	"os/signal"
 	"strconv"
@@ -13,6 +16,14 @@ import (
 	"time"
 )
 
+const closeTimeout = 30 * time.Second
+
+const (
+	genExitOK             = 0
+	genExitMigrateFuncErr = 1
+	genExitError          = 2
+)
+
 const (
 	migrateUsage     = "migrate COMMAND"
 	migrateUsageDesc = `a CLI command for migrate databases`
@@ -21,32 +32,254 @@ const (
 	createUsageDesc = `Create a set of timestamped up/down migrations titled NAME, with extension E.
 			Use -seq option to generate sequential up/down migrations with N digits.
 			Use -format option to specify a Go time format string. Note: migrations with the same time cause "duplicate migration version" error.
-			Use -tz option to specify the timezone that will be used when generating non-sequential migrations (defaults: Local).`
-	gotoUsage     = "goto V"
-	gotoUsageDesc = `Migrate to version V`
+			Use -tz option to specify the timezone that will be used when generating non-sequential migrations (defaults: Local).
+			Use --dry-run to print the generated SQL instead of writing the files.
+			Use --from-db to diff the live database schema against the desired schema instead of running the migrate funcs,
+			the live tables missing from the desired schema being logged, or dropped with --drop-tables.
+			Use --pattern add-column|add-index|rename-column with --table and --column NAME:TYPE to create the migration of a common change
+			the safe way for the database, e.g. --pattern add-column --table users --column age:int --not-null --default 0, NAME being
+			optional then.
+			Use --wizard to be asked for the table to create or alter, its columns, indexes and constraints.
+			Use --from-stdin to read the up SQL from stdin and write the down migration undoing it, as far as it can be derived.`
+	gotoUsage     = "goto V|TAG"
+	gotoUsageDesc = `Migrate to version V, or to the version tagged TAG`
 
 	upUsage     = "up [N]"
-	upUsageDesc = "Apply all or N up migrations"
+	upUsageDesc = `Apply all or N up migrations
+			Repeatable migrations (R__NAME files) are re-applied after a full up whenever their rendered content changed
+			The callback files beforeAll, beforeEach, afterEach, afterAll and onError (.sql) of the migrations directory run at those points of up, down, goto and rollback
+			Fails on migrations older than the applied version that the history never saw applied, unless --out-of-order is set
+			With --not-before HH:MM --window DURATION, waits for that daily maintenance window and fails without
+			running anything when the durations in the history say the migrations would not finish within it
+			Migrations with a "-- migrator:osc gh-ost" (or pt-osc) line hand their ALTER TABLE statements to that tool, see --osc-arg
+			Use --dry-run to list the pending migrations without running them, with the risk of their statements
+			and the table locks they take on postgres
+			Use --interactive to pick the pending migrations to apply on the terminal`
 
 	downUsage     = "down [N]"
 	downUsageDesc = `Apply all or N down migrations
-			Use --all to apply all down migrations`
+			Use --all to apply all down migrations
+			Use --to V to apply the down migrations back to version V, it refuses a version above the current one`
 
 	dropUsage     = "drop"
 	dropUsageDesc = `Drop everything inside database
+			It refuses to when the connected role can't drop every object (postgres and mysql)
 			Use --f to bypass confirmation`
 
 	forceUsage     = "force V"
-	forceUsageDesc = `Set version V but don't run migration (ignores dirty state)`
+	forceUsageDesc = `Set version V but don't run migration (ignores dirty state)
+			Use nil, or -- -1, to clear the version, same as reset-version`
+
+	resetVersionUsage     = "reset-version"
+	resetVersionUsageDesc = `Clear the version record, as if no migration had been applied, e.g. after dropping the schema by hand
+			Use --f to bypass confirmation`
 
 	versionUsage     = "version"
-	versionUsageDesc = "Print current migration version"
+	versionUsageDesc = `Print current migration version
+			Use --json to print the current and latest available versions with the number of pending migrations
+			With history enabled, also print how long the pending migrations are estimated to take on this database`
+
+	seedUsage     = "seed [ENV]"
+	seedUsageDesc = `Apply the seed files of seeds/ENV that haven't been applied yet (default ENV: --profile, or dev)`
+
+	fixturesUsage     = "fixtures DIR|FILE..."
+	fixturesUsageDesc = `Load YAML/CSV fixtures named after their tables, inserting referenced tables first
+			Use --mode truncate to empty the tables first, or --mode upsert to update existing rows`
+
+	testUsage     = "test --scratch-url URL"
+	testUsageDesc = `Apply all up migrations, then all down migrations, then all up migrations again to a disposable database, failing on any error
+			The migrated database is left untouched. Use --f to bypass confirmation`
+
+	importUsage     = "import --from TOOL DIR"
+	importUsageDesc = `Convert the migrations of another tool (flyway, goose) found in DIR into the migrations directory
+			Use --history to also set the database version from the tool's history table`
+
+	exportUsage     = "export --format FORMAT"
+	exportUsageDesc = `Export the migrations for another tool (liquibase)
+			Use --out to write to a file, a .yaml or .yml extension produces a YAML changelog instead of XML`
+
+	historyUsage     = "history COMMAND"
+	historyUsageDesc = `Manage the history of applied migrations`
+
+	historyExportUsage     = "export --format FORMAT"
+	historyExportUsageDesc = `Export the applied migrations with their timestamps and durations as json or csv`
+
+	historyImportUsage     = "import FILE"
+	historyImportUsageDesc = `Seed the history table and the version from a state exported with history export --format json
+			Use --replace to overwrite an existing history`
+
+	tagUsage     = "tag [NAME]"
+	tagUsageDesc = `Associate the current version with the release NAME, so that goto can target it
+			Without NAME, list the tags. Use --f to move an existing tag`
+
+	rollbackUsage     = "rollback --to-tag TAG"
+	rollbackUsageDesc = `Apply the down migrations needed to go back to the version tagged TAG
+			Use --dry-run to list the migrations that would be reverted without running them, with their table locks on postgres`
+
+	conflictsUsage     = "conflicts --base BRANCH"
+	conflictsUsageDesc = `Use git to find the migrations added on the current branch whose version is already used on BRANCH,
+			or is older than the newest migration merged on BRANCH (default BRANCH: main)`
+
+	rebaseUsage     = "rebase --base BRANCH"
+	rebaseUsageDesc = `Renumber the migrations added on the current branch and not applied yet so that they come after
+			every migration of BRANCH and of the database, keeping their order (default BRANCH: main)
+			Use --dry-run to print the renames without applying them`
+
+	showUsage     = "show V [up|down]"
+	showUsageDesc = `Print the up and down migrations of version V, or only the given direction`
+
+	completionUsage     = "completion bash|zsh|fish|powershell"
+	completionUsageDesc = `Print the shell completion script, versions of the migrations directory are completed for goto, force and show`
+
+	genUsage     = "gen [NAME]"
+	genUsageDesc = `Run the migrate funcs and create the migration NAME when they produce changes (default NAME: auto)
+			Meant for //go:generate, it exits 0 whether or not a migration was created, 1 when the migrate funcs fail
+			and 2 on any other error, such as a migration that can't be written`
+
+	bundleUsage     = "bundle --out FILE"
+	bundleUsageDesc = `Write a tar.gz of the migrations with a manifest of their versions and checksums, run it with --bundle FILE
+			Use --signing-key to sign the manifest with an ed25519 private key (PKCS#8 PEM), or --gpg-user to sign it with gpg`
+
+	manifestUsage     = "manifest COMMAND"
+	manifestUsageDesc = `Maintain the MANIFEST.json of the migrations directory
+			Once it exists, up, down, goto and rollback refuse to run migrations that don't match it`
+
+	manifestWriteUsage     = "write"
+	manifestWriteUsageDesc = `Write the MANIFEST.json of the migrations with their versions and checksums`
+
+	manifestVerifyUsage     = "verify"
+	manifestVerifyUsageDesc = `Check the migrations against their MANIFEST.json, and its signature with --require-signed`
+
+	manifestSignUsage     = "sign"
+	manifestSignUsageDesc = `Sign the MANIFEST.json with an ed25519 private key (--signing-key, MANIFEST.json.sig)
+			or with gpg (--gpg or --gpg-user, MANIFEST.json.asc)
+			With --require-signed, up, down, goto and rollback only run migrations of a manifest signed that way`
+
+	planUsage     = "plan --out FILE"
+	planUsageDesc = `Write the migrations that take the database to the latest version, or to --to V, with the checksums
+			of the migrations directory to FILE, for apply to run once reviewed
+			With history enabled, up plans include how long their migrations are estimated to take on this database
+			Prints each statement as additive, locking, rewriting or destructive, the riskiest first`
+
+	applyUsage     = "apply FILE"
+	applyUsageDesc = `Run the migrations of a plan written by plan
+			It refuses to run when the migrations or the version of the database changed since the plan was made
+			With --protected, it also requires an approval of the plan, see approve`
+
+	approveUsage     = "approve FILE"
+	approveUsageDesc = `Approve the plan FILE for apply on protected databases, writing FILE.approval signed with --signing-key or --gpg-user
+			A plan can't be approved by the person who made it`
+
+	lockStatusUsage     = "lock-status"
+	lockStatusUsageDesc = `Show whether the migration lock is held, and by which session (postgres and mysql)`
+
+	unlockUsage     = "unlock --force"
+	unlockUsageDesc = `Clear a stale migration lock by terminating the session holding it (postgres and mysql)
+			Whatever that session is running is rolled back, only use it once sure no migration is running`
+
+	doctorUsage     = "doctor"
+	doctorUsageDesc = `Check connectivity, the migrations directory, the version table, dirty state, lock acquisition
+			and the CREATE TABLE privilege before a production run, exiting 1 when a check fails
+			With --read-only, the lock acquisition and privilege checks are skipped`
+
+	pingUsage     = "ping"
+	pingUsageDesc = `Check the database answers and the migrations directory is readable, exiting 0 or 1, without side effects`
+
+	resumeUsage     = "resume"
+	resumeUsageDesc = `Run the statements of the dirty migration that weren't committed when it failed, and mark it clean
+			Needs the progress recorded by --track-statements, and the migration file unchanged since it failed`
+
+	diagnoseUsage     = "diagnose"
+	diagnoseUsageDesc = `Explain a dirty database: the migration that failed, its file, the last error recorded in the history
+			or the audit table, and the commands that can get the database out of it`
+
+	shadowVerifyUsage     = "shadow-verify --shadow-url URL"
+	shadowVerifyUsageDesc = `Apply the migrations to the scratch database at URL and compare its schema with the models,
+			listing the statements still needed, exiting 1 on any mismatch, without touching the database`
+
+	snapshotUsage     = "snapshot [--out FILE]"
+	snapshotUsageDesc = `Write the CREATE TABLE statements of the current schema, headed by its version, to schema.sql
+			in the migrations directory, so reviewers can see the cumulative schema. Use --out - to print it`
+
+	schemaDiffUsage     = "schemadiff URL1 URL2"
+	schemaDiffUsageDesc = `Compare the schemas of two live databases, such as staging and production, listing the tables
+			and columns which differ and the statements bringing URL1 to the schema of URL2, exiting 1 when they differ`
+
+	verifyUsage     = "verify"
+	verifyUsageDesc = `Check the schema still has the checksum the history recorded after the last migration, exiting 1
+			when it was changed out of band since. Checksums are recorded with history enabled`
+
+	scriptUsage     = "script --down N"
+	scriptUsageDesc = `Write the next N down migrations as one SQL script, in the order they are reverted, each in a transaction
+			and updating the migrations table, to attach to change tickets as the rollback plan. Use --out to write to a file`
+
+	waitUsage     = "wait V"
+	waitUsageDesc = `Wait until the database reaches at least version V, e.g. in the init container of replicas which
+			don't run migrations. Use --timeout to give up, exiting 1`
+
+	generateCheckUsage     = "generate-check"
+	generateCheckUsageDesc = `Run the migrate funcs and fail if they produce any change
+			Use it in CI to prove that committed migrations fully capture the current models`
+
+	lintUsage     = "lint [V...]"
+	lintUsageDesc = `List the SQL migrations, or those of versions V, whose down migration is missing or empty, exiting 1 when any is
+			Use --suggest-down to print the down migration derived from their up migration, from the CREATE, ADD and RENAME statements`
+
+	aboutUsage     = "about"
+	aboutUsageDesc = `Print the versions of file-migrator and golang-migrate, the Go version, the git commit and the compiled drivers
+			of the binary, use --json to print them as json`
 )
 
 type migrateFlag struct {
-	verbosePtr     bool
-	prefetchPtr    uint
-	lockTimeoutPtr uint
+	verbosePtr       bool
+	prefetchPtr      uint
+	lockTimeoutPtr   uint
+	heartbeatPtr     uint
+	auditPtr         bool
+	historyPtr       bool
+	reportPtr        string
+	pathPtr          string
+	bundlePtr        string
+	verifyKeyPtr     string
+	gpgPtr           bool
+	gpgKeyringPtr    string
+	requireSignedPtr bool
+	protectedPtr     bool
+	tablePtr         string
+	driverParamPtr   []string
+	stmtTimeoutPtr   time.Duration
+	dbLockTimeoutPtr time.Duration
+	isolationPtr     string
+	quietPtr         bool
+	logLevelPtr      string
+	logFilePtr       string
+	logFormatPtr     string
+	terminatorPtr    string
+	splitPtr         bool
+	savepointsPtr    bool
+	trackPtr         bool
+	oscArgPtr        []string
+	concurrentPtr    bool
+	logMaxSizePtr    uint
+	logBackupsPtr    int
+	readOnlyPtr      bool
+	largeTablePtr    uint
+	largeActionPtr   string
+	ackLargeTablePtr bool
+	notifyPtr        string
+	modulePtr        string
+	allModulesPtr    bool
+	connTimeoutPtr   time.Duration
+	keepAlivePtr     time.Duration
+	maxOpenPtr       int
+	maxIdlePtr       int
+	connLifetimePtr  time.Duration
+	labelPtr         []string
+	downCheckPtr     string
+	tmplValuePtr     []string
+	tmplEnvPtr       []string
+	profilePtr       string
+	macrosPtr        string
 }
 
 type createFlag struct {
@@ -55,26 +288,176 @@ type createFlag struct {
 	seqDigitsPtr int
 	formatPtr    string
 	tzPtr        string
+	dryRunPtr    bool
+	fromDBPtr    bool
+	dropTblsPtr  bool
+	patternPtr   string
+	onTablePtr   string
+	columnsPtr   []string
+	notNullPtr   bool
+	defaultPtr   string
+	uniquePtr    bool
+	renameToPtr  string
+	wizardPtr    bool
+	fromStdinPtr bool
+}
+
+type upFlag struct {
+	outOfOrderPtr bool
+	notBeforePtr  string
+	windowPtr     time.Duration
+	upDryRunPtr   bool
+	untilTimePtr  string
+	pickPtr       bool
 }
 
 type downFlag struct {
-	allPtr bool
+	allPtr    bool
+	downToPtr string
 }
 
 type dropFlag struct {
 	forceDropPtr bool
 }
 
+type resetVersionFlag struct {
+	forceResetPtr bool
+}
+
+type testFlag struct {
+	forceTestPtr bool
+	scratchPtr   string
+}
+
+type importFlag struct {
+	importFromPtr    string
+	importHistoryPtr bool
+}
+
+type exportFlag struct {
+	exportFormatPtr string
+	exportOutPtr    string
+}
+
+type versionFlag struct {
+	versionJSONPtr bool
+}
+
+type tagFlag struct {
+	forceTagPtr bool
+}
+
+type conflictsFlag struct {
+	conflictsBasePtr string
+}
+
+type rebaseFlag struct {
+	rebaseBasePtr   string
+	rebaseDryRunPtr bool
+}
+
+type bundleFlag struct {
+	bundleOutPtr  string
+	signingKeyPtr string
+	gpgUserPtr    string
+}
+
+type shadowVerifyFlag struct {
+	shadowURLPtr string
+}
+
+type snapshotFlag struct {
+	snapshotOutPtr string
+}
+
+type schemaDiffFlag struct {
+	schemaDiffJSONPtr bool
+}
+
+type scriptFlag struct {
+	scriptDownPtr int
+	scriptOutPtr  string
+}
+
+type waitFlag struct {
+	waitTimeoutPtr time.Duration
+}
+
+type unlockFlag struct {
+	unlockForcePtr bool
+}
+
+type planFlag struct {
+	planOutPtr string
+	planToPtr  int
+}
+
+type rollbackFlag struct {
+	rollbackToTagPtr  string
+	rollbackDryRunPtr bool
+}
+
+type historyFlag struct {
+	historyFormatPtr  string
+	historyOutPtr     string
+	historyReplacePtr bool
+}
+
+type fixturesFlag struct {
+	fixtureModePtr string
+}
+
+type lintFlag struct {
+	suggestDownPtr bool
+}
+
+type aboutFlag struct {
+	aboutJSONPtr bool
+}
+
 type migratorCobraCommandBuilder struct {
-	migrator *Migrator
+	migrator       *Migrator
+	rootMigrator   *Migrator
+	migrateCommand *cobra.Command
+	preRuns        []RunHook
+	postRuns       []RunHook
+	use            string
+	disabled       []string
+	extraCommands  []*cobra.Command
+	flagDefaults   map[string]string
+	flagPrefix     string
 	migrateFlag
 	createFlag
+	upFlag
 	downFlag
 	dropFlag
+	resetVersionFlag
+	fixturesFlag
+	testFlag
+	importFlag
+	exportFlag
+	historyFlag
+	versionFlag
+	tagFlag
+	rollbackFlag
+	conflictsFlag
+	rebaseFlag
+	bundleFlag
+	planFlag
+	unlockFlag
+	shadowVerifyFlag
+	snapshotFlag
+	schemaDiffFlag
+	scriptFlag
+	waitFlag
+	lintFlag
+	aboutFlag
 }
 
 func (builder *migratorCobraCommandBuilder) Build() *cobra.Command {
-	return builder.buildMigrateCmd()
+	migrateCommand := builder.buildMigrateCmd()
+	builder.customize(migrateCommand)
+	return migrateCommand
 }
 
 func (builder *migratorCobraCommandBuilder) buildMigrateCmd() *cobra.Command {
@@ -82,11 +465,77 @@ func (builder *migratorCobraCommandBuilder) buildMigrateCmd() *cobra.Command {
 		Use:   migrateUsage,
 		Short: migrateUsageDesc,
 		Long:  migrateUsageDesc,
-	}
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			builder.runHooks(builder.preRuns, cmd, args)
+
+			if err := builder.selectModule(cmd); err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
 
-	migrateCommand.PersistentFlags().BoolVar(&builder.verbosePtr, "verbose", false, "Print verbose logging")
-	migrateCommand.PersistentFlags().UintVar(&builder.prefetchPtr, "prefetch", 10, "Number of migrations to load in advance before executing")
-	migrateCommand.PersistentFlags().UintVar(&builder.lockTimeoutPtr, "lock-timeout", 15, "Allow N seconds to acquire database lock")
+			if !builder.readOnlyPtr {
+				return
+			}
+
+			if err := builder.checkReadOnly(cmd); err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+			builder.migrator.SetReadOnly()
+		},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			builder.runHooks(builder.postRuns, cmd, args)
+		},
+	}
+	builder.migrateCommand = migrateCommand
+
+	migrateCommand.PersistentFlags().BoolVar(&builder.verbosePtr, builder.flagName("verbose"), false, "Print verbose logging")
+	migrateCommand.PersistentFlags().BoolVar(&builder.quietPtr, builder.flagName("quiet"), false, "Only log errors, same as --log-level error")
+	migrateCommand.PersistentFlags().StringVar(&builder.logLevelPtr, builder.flagName("log-level"), "info", "The minimum level to log: debug, info, warn or error")
+	migrateCommand.PersistentFlags().StringVar(&builder.logFormatPtr, builder.flagName("log-format"), LogFormatText, "Log as text or as json lines, json also logs an event per migration")
+	migrateCommand.PersistentFlags().StringVar(&builder.logFilePtr, builder.flagName("log-file"), "", "Also append the log to this file")
+	migrateCommand.PersistentFlags().UintVar(&builder.logMaxSizePtr, builder.flagName("log-max-size"), 0, "Rotate the log file once it grows past N megabytes (default: never)")
+	migrateCommand.PersistentFlags().IntVar(&builder.logBackupsPtr, builder.flagName("log-max-backups"), 3, "The number of rotated log files to keep")
+	migrateCommand.PersistentFlags().UintVar(&builder.prefetchPtr, builder.flagName("prefetch"), 10, "Number of migrations to load in advance before executing")
+	migrateCommand.PersistentFlags().UintVar(&builder.lockTimeoutPtr, builder.flagName("lock-timeout"), 15, "Allow N seconds to acquire database lock")
+	migrateCommand.PersistentFlags().UintVar(&builder.heartbeatPtr, builder.flagName("lock-heartbeat"), 0, "Refresh the database lock every N seconds while migrating, for drivers whose lock can expire (default: disabled)")
+	migrateCommand.PersistentFlags().DurationVar(&builder.stmtTimeoutPtr, builder.flagName("statement-timeout"), 0, "Cancel statements of the migration session running longer than this, e.g. 5m (postgres and mysql)")
+	migrateCommand.PersistentFlags().DurationVar(&builder.dbLockTimeoutPtr, builder.flagName("db-lock-timeout"), 0, "Fail statements of the migration session waiting longer than this for a table lock, e.g. 10s (postgres and mysql)")
+	migrateCommand.PersistentFlags().DurationVar(&builder.connTimeoutPtr, builder.flagName("connect-timeout"), 0, "Give up connecting to the database after this, e.g. 10s (postgres and mysql)")
+	migrateCommand.PersistentFlags().DurationVar(&builder.keepAlivePtr, builder.flagName("keepalive"), 0, "Send TCP keepalives on the database connections this often, e.g. 30s (postgres and mysql, the migration connection on mysql only)")
+	migrateCommand.PersistentFlags().IntVar(&builder.maxOpenPtr, builder.flagName("max-open-conns"), 0, "Maximum number of open connections of the databases opened for reading the schema")
+	migrateCommand.PersistentFlags().IntVar(&builder.maxIdlePtr, builder.flagName("max-idle-conns"), 0, "Maximum number of idle connections of the databases opened for reading the schema")
+	migrateCommand.PersistentFlags().DurationVar(&builder.connLifetimePtr, builder.flagName("conn-max-lifetime"), 0, "Close connections of the databases opened for reading the schema after this, e.g. 5m")
+	migrateCommand.PersistentFlags().StringVar(&builder.isolationPtr, builder.flagName("isolation"), "", "Run migration transactions at read-uncommitted, read-committed, repeatable-read or serializable, never read-only")
+	migrateCommand.PersistentFlags().StringVar(&builder.tablePtr, builder.flagName("migrations-table"), "", "Keep the version in this table instead of schema_migrations (needs a migrator created from an url)")
+	migrateCommand.PersistentFlags().StringVar(&builder.terminatorPtr, builder.flagName("terminator"), "", "End generated statements with this terminator, wrapped in DELIMITER lines, and split on it with --split-statements (default: ;)")
+	migrateCommand.PersistentFlags().BoolVar(&builder.splitPtr, builder.flagName("split-statements"), false, "Run the statements of SQL migrations one by one, honouring DELIMITER lines")
+	migrateCommand.PersistentFlags().BoolVar(&builder.savepointsPtr, builder.flagName("savepoints"), false, "Run each statement of SQL migrations in a savepoint, committing those before a failing one and reporting it")
+	migrateCommand.PersistentFlags().BoolVar(&builder.trackPtr, builder.flagName("track-statements"), false, "Record how many statements of a failed migration were committed, with --savepoints or --split-statements, for resume")
+	migrateCommand.PersistentFlags().BoolVar(&builder.concurrentPtr, builder.flagName("concurrent-indexes"), false, "Create and drop indexes CONCURRENTLY in generated postgres migrations, run without a transaction")
+	migrateCommand.PersistentFlags().UintVar(&builder.largeTablePtr, builder.flagName("large-table-threshold"), 0, "Before up and apply, look for ALTER TABLE statements rewriting tables larger than N megabytes (default: disabled)")
+	migrateCommand.PersistentFlags().StringVar(&builder.largeActionPtr, builder.flagName("large-table-action"), LargeTableWarn, "Log the ALTER TABLE statements found by --large-table-threshold with warn, or fail unless --acknowledge-large-table with require-ack")
+	migrateCommand.PersistentFlags().BoolVar(&builder.ackLargeTablePtr, builder.flagName("acknowledge-large-table"), false, "Run the migrations rewriting large tables with --large-table-action require-ack")
+	migrateCommand.PersistentFlags().StringVar(&builder.downCheckPtr, builder.flagName("down-check"), "", "Before up and apply, log the pending migrations whose down migration is missing or empty with warn, or fail with block (default: disabled)")
+	migrateCommand.PersistentFlags().StringArrayVar(&builder.oscArgPtr, builder.flagName("osc-arg"), nil, "Argument given to the online schema change tool of migrations marked -- migrator:osc gh-ost|pt-osc, repeatable")
+	migrateCommand.PersistentFlags().StringArrayVar(&builder.driverParamPtr, builder.flagName("driver-param"), nil, "Add key=value to the url the driver is opened with, e.g. x-statement-timeout=5000 (repeatable, needs a migrator created from an url)")
+	migrateCommand.PersistentFlags().StringArrayVar(&builder.labelPtr, builder.flagName("label"), nil, "Record key=value with the run in the history and audit tables, reports, JSON logs and notifications, e.g. deployer=alice (repeatable)")
+	migrateCommand.PersistentFlags().StringVar(&builder.profilePtr, builder.flagName("profile"), "", "Environment the migrations run in, selecting their -- migrator:only-env and skip-env blocks, and the default seed environment")
+	migrateCommand.PersistentFlags().StringVar(&builder.macrosPtr, builder.flagName("macros"), "", "JSON file of the @name@ macros of the migrations and their expansion by dialect, e.g. {\"money\": {\"postgres\": \"NUMERIC(12,2)\", \"mysql\": \"DECIMAL(12,2)\"}}")
+	migrateCommand.PersistentFlags().StringArrayVar(&builder.tmplValuePtr, builder.flagName("template-value"), nil, "Render the .sql.tmpl migrations with key=value as {{ .Values.key }}, e.g. tablespace=fast_ssd (repeatable)")
+	migrateCommand.PersistentFlags().StringArrayVar(&builder.tmplEnvPtr, builder.flagName("template-env"), nil, "Let the .sql.tmpl migrations read this environment variable with {{ env \"NAME\" }} (repeatable)")
+	migrateCommand.PersistentFlags().StringVar(&builder.pathPtr, builder.flagName("path"), "", "Use the migrations of this directory instead of the configured one")
+	migrateCommand.PersistentFlags().StringVar(&builder.bundlePtr, builder.flagName("bundle"), "", "Use the migrations of this bundle, after checking them against its manifest")
+	migrateCommand.PersistentFlags().StringVar(&builder.verifyKeyPtr, builder.flagName("verify-key"), "", "Check the manifest signature with this ed25519 public key (PKIX PEM)")
+	migrateCommand.PersistentFlags().BoolVar(&builder.gpgPtr, builder.flagName("gpg"), false, "Sign and check the manifest signature with gpg (MANIFEST.json.asc)")
+	migrateCommand.PersistentFlags().StringVar(&builder.gpgKeyringPtr, builder.flagName("gpg-keyring"), "", "Check gpg signatures against this keyring (default: the gpg default keyring)")
+	migrateCommand.PersistentFlags().BoolVar(&builder.protectedPtr, builder.flagName("protected"), false, "Only apply plans approved by someone else, with a signature checked by --verify-key or --gpg")
+	migrateCommand.PersistentFlags().BoolVar(&builder.requireSignedPtr, builder.flagName("require-signed"), false, "Refuse to apply migrations without a manifest signed by --verify-key or --gpg")
+	migrateCommand.PersistentFlags().BoolVar(&builder.historyPtr, builder.flagName("history"), false, "Record every migration run, its duration and outcome in the history table, used by history export, verify, diagnose, duration estimates and the out-of-order check")
+	migrateCommand.PersistentFlags().BoolVar(&builder.auditPtr, builder.flagName("audit"), false, "Record operator, host, tool version, git commit and command line of each run in the audit table")
+	migrateCommand.PersistentFlags().BoolVar(&builder.readOnlyPtr, builder.flagName("read-only"), false, "Only allow commands which don't change the database, such as version, show, plan, history export and diagnose")
+	migrateCommand.PersistentFlags().StringVar(&builder.notifyPtr, builder.flagName("notify-channel"), "", "NOTIFY each applied migration as json on this postgres channel, for the replicas waiting for a version (needs SetDB)")
+	migrateCommand.PersistentFlags().StringVar(&builder.modulePtr, builder.flagName("module"), "", "Run the command on this module, with its own migrations directory and migrations table")
+	migrateCommand.PersistentFlags().BoolVar(&builder.allModulesPtr, builder.flagName("all-modules"), false, "Run up or version on every module, each after the modules it requires")
+	migrateCommand.PersistentFlags().StringVar(&builder.reportPtr, builder.flagName("report"), "", "Write a JSON report of the run to this file")
 
 	createCommand := builder.buildCreateCmd()
 	migrateCommand.AddCommand(createCommand)
@@ -106,9 +555,111 @@ func (builder *migratorCobraCommandBuilder) buildMigrateCmd() *cobra.Command {
 	forceCommand := builder.buildForceCommand()
 	migrateCommand.AddCommand(forceCommand)
 
+	resetVersionCommand := builder.buildResetVersionCommand()
+	migrateCommand.AddCommand(resetVersionCommand)
+
 	versionCommand := builder.buildVersionCommand()
 	migrateCommand.AddCommand(versionCommand)
 
+	seedCommand := builder.buildSeedCommand()
+	migrateCommand.AddCommand(seedCommand)
+
+	fixturesCommand := builder.buildFixturesCommand()
+	migrateCommand.AddCommand(fixturesCommand)
+
+	testCommand := builder.buildTestCommand()
+	migrateCommand.AddCommand(testCommand)
+
+	importCommand := builder.buildImportCommand()
+	migrateCommand.AddCommand(importCommand)
+
+	exportCommand := builder.buildExportCommand()
+	migrateCommand.AddCommand(exportCommand)
+
+	historyCommand := builder.buildHistoryCommand()
+	migrateCommand.AddCommand(historyCommand)
+
+	tagCommand := builder.buildTagCommand()
+	migrateCommand.AddCommand(tagCommand)
+
+	rollbackCommand := builder.buildRollbackCommand()
+	migrateCommand.AddCommand(rollbackCommand)
+
+	conflictsCommand := builder.buildConflictsCommand()
+	migrateCommand.AddCommand(conflictsCommand)
+
+	rebaseCommand := builder.buildRebaseCommand()
+	migrateCommand.AddCommand(rebaseCommand)
+
+	showCommand := builder.buildShowCommand()
+	migrateCommand.AddCommand(showCommand)
+
+	completionCommand := builder.buildCompletionCommand()
+	migrateCommand.AddCommand(completionCommand)
+
+	genCommand := builder.buildGenCommand()
+	migrateCommand.AddCommand(genCommand)
+
+	bundleCommand := builder.buildBundleCommand()
+	migrateCommand.AddCommand(bundleCommand)
+
+	manifestCommand := builder.buildManifestCommand()
+	migrateCommand.AddCommand(manifestCommand)
+
+	planCommand := builder.buildPlanCommand()
+	migrateCommand.AddCommand(planCommand)
+
+	applyCommand := builder.buildApplyCommand()
+	migrateCommand.AddCommand(applyCommand)
+
+	approveCommand := builder.buildApproveCommand()
+	migrateCommand.AddCommand(approveCommand)
+
+	lockStatusCommand := builder.buildLockStatusCommand()
+	migrateCommand.AddCommand(lockStatusCommand)
+
+	unlockCommand := builder.buildUnlockCommand()
+	migrateCommand.AddCommand(unlockCommand)
+
+	doctorCommand := builder.buildDoctorCommand()
+	migrateCommand.AddCommand(doctorCommand)
+
+	pingCommand := builder.buildPingCommand()
+	migrateCommand.AddCommand(pingCommand)
+
+	resumeCommand := builder.buildResumeCommand()
+	migrateCommand.AddCommand(resumeCommand)
+
+	diagnoseCommand := builder.buildDiagnoseCommand()
+	migrateCommand.AddCommand(diagnoseCommand)
+
+	shadowVerifyCommand := builder.buildShadowVerifyCommand()
+	migrateCommand.AddCommand(shadowVerifyCommand)
+
+	snapshotCommand := builder.buildSnapshotCommand()
+	migrateCommand.AddCommand(snapshotCommand)
+
+	schemaDiffCommand := builder.buildSchemaDiffCommand()
+	migrateCommand.AddCommand(schemaDiffCommand)
+
+	verifyCommand := builder.buildVerifyCommand()
+	migrateCommand.AddCommand(verifyCommand)
+
+	scriptCommand := builder.buildScriptCommand()
+	migrateCommand.AddCommand(scriptCommand)
+
+	waitCommand := builder.buildWaitCommand()
+	migrateCommand.AddCommand(waitCommand)
+
+	generateCheckCommand := builder.buildGenerateCheckCommand()
+	migrateCommand.AddCommand(generateCheckCommand)
+
+	lintCommand := builder.buildLintCommand()
+	migrateCommand.AddCommand(lintCommand)
+
+	aboutCommand := builder.buildAboutCommand()
+	migrateCommand.AddCommand(aboutCommand)
+
 	return migrateCommand
 
 }
@@ -118,8 +669,216 @@ func (builder *migratorCobraCommandBuilder) setupMigrator() {
 		builder.migrator.logger.SetVerbose(verbose)
 	}
 
-	builder.migrator.migrate.PrefetchMigrations = builder.prefetchPtr
-	builder.migrator.migrate.LockTimeout = time.Duration(builder.lockTimeoutPtr) * time.Second
+	if builder.migrateCommand.PersistentFlags().Changed(builder.flagName("log-format")) {
+		if err := builder.migrator.SetLogFormat(builder.logFormatPtr); err != nil {
+			builder.migrator.logger.Fatal(err.Error())
+		}
+	}
+
+	if builder.logFilePtr != "" {
+		err := builder.migrator.SetLogFile(builder.logFilePtr, int64(builder.logMaxSizePtr)<<20, builder.logBackupsPtr)
+		if err != nil {
+			builder.migrator.logger.Fatal("can't open log file", "path", builder.logFilePtr, "error", err)
+		}
+	}
+
+	if builder.quietPtr {
+		builder.migrator.SetLogLevel(LogLevelError)
+	} else if builder.migrateCommand.PersistentFlags().Changed(builder.flagName("log-level")) {
+		level, err := ParseLogLevel(builder.logLevelPtr)
+		if err != nil {
+			builder.migrator.logger.Fatal(err.Error())
+		}
+		builder.migrator.SetLogLevel(level)
+	}
+
+	if builder.pathPtr != "" {
+		if err := builder.migrator.SetMigrationsPath(builder.pathPtr); err != nil {
+			builder.migrator.logger.Fatal("can't use migrations path", "path", builder.pathPtr, "error", err)
+		}
+	}
+
+	verifier := builder.verifier()
+	if builder.bundlePtr != "" {
+		if err := builder.migrator.UseBundle(builder.bundlePtr, verifier); err != nil {
+			builder.migrator.logger.Fatal("can't use bundle", "path", builder.bundlePtr, "error", err)
+		}
+	}
+
+	if builder.requireSignedPtr {
+		if verifier == nil {
+			builder.migrator.logger.Fatal(errNoVerifier.Error())
+		}
+		builder.migrator.RequireSignedManifest(verifier)
+	}
+
+	if builder.protectedPtr {
+		if verifier == nil {
+			builder.migrator.logger.Fatal("protected databases need --verify-key or --gpg to check approvals")
+		}
+		builder.migrator.RequireApproval(verifier)
+	}
+
+	if builder.terminatorPtr != "" {
+		builder.migrator.SetStatementTerminator(builder.terminatorPtr)
+	}
+
+	if builder.splitPtr {
+		builder.migrator.EnableStatementSplitting()
+	}
+
+	if builder.savepointsPtr {
+		builder.migrator.EnableSavepoints()
+	}
+
+	if builder.trackPtr {
+		builder.migrator.EnableStatementTracking()
+	}
+
+	if builder.concurrentPtr {
+		builder.migrator.EnableConcurrentIndexes()
+	}
+
+	if len(builder.oscArgPtr) > 0 {
+		builder.migrator.SetOSCArgs(builder.oscArgPtr)
+	}
+
+	if builder.largeTablePtr > 0 {
+		if err := builder.migrator.SetLargeTableCheck(int64(builder.largeTablePtr)<<20, builder.largeActionPtr); err != nil {
+			builder.migrator.logger.Fatal(err.Error())
+		}
+	}
+
+	if builder.downCheckPtr != "" {
+		if err := builder.migrator.SetDownCheck(builder.downCheckPtr); err != nil {
+			builder.migrator.logger.Fatal(err.Error())
+		}
+	}
+
+	if builder.ackLargeTablePtr {
+		builder.migrator.AcknowledgeLargeTables()
+	}
+
+	if builder.notifyPtr != "" {
+		builder.migrator.EnableNotifications(builder.notifyPtr)
+	}
+
+	if builder.historyPtr {
+		builder.migrator.EnableHistory()
+	}
+
+	if builder.auditPtr {
+		builder.migrator.EnableAudit()
+	}
+
+	if builder.reportPtr != "" {
+		builder.migrator.SetReportPath(builder.reportPtr)
+	}
+
+	if len(builder.labelPtr) > 0 {
+		labels, err := ParseLabels(builder.labelPtr)
+		if err != nil {
+			builder.migrator.logger.Fatal(err.Error())
+		}
+		builder.migrator.SetLabels(labels)
+	}
+
+	if builder.profilePtr != "" {
+		builder.migrator.SetProfile(builder.profilePtr)
+	}
+
+	if builder.macrosPtr != "" {
+		if err := builder.migrator.LoadMacros(builder.macrosPtr); err != nil {
+			builder.migrator.logger.Fatal(err.Error())
+		}
+	}
+
+	if len(builder.tmplValuePtr) > 0 {
+		values := make(map[string]string, len(builder.tmplValuePtr))
+		for _, value := range builder.tmplValuePtr {
+			key, v, ok := strings.Cut(value, "=")
+			if !ok || key == "" {
+				builder.migrator.logger.Fatal("template values must be key=value", "value", value)
+			}
+			values[key] = v
+		}
+		builder.migrator.SetTemplateValues(values)
+	}
+
+	if len(builder.tmplEnvPtr) > 0 {
+		builder.migrator.AllowTemplateEnv(builder.tmplEnvPtr...)
+	}
+
+	if len(builder.driverParamPtr) > 0 {
+		params := make(map[string]string, len(builder.driverParamPtr))
+		for _, param := range builder.driverParamPtr {
+			key, value, ok := strings.Cut(param, "=")
+			if !ok || key == "" {
+				builder.migrator.logger.Fatal("driver params must be key=value", "param", param)
+			}
+			params[key] = value
+		}
+
+		if err := builder.migrator.SetDriverParams(params); err != nil {
+			builder.migrator.logger.Fatal("can't use driver params", "error", err)
+		}
+	}
+
+	if builder.stmtTimeoutPtr > 0 || builder.dbLockTimeoutPtr > 0 {
+		if err := builder.migrator.SetSessionTimeouts(builder.stmtTimeoutPtr, builder.dbLockTimeoutPtr); err != nil {
+			builder.migrator.logger.Fatal("can't set session timeouts", "error", err)
+		}
+	}
+
+	if builder.connTimeoutPtr > 0 {
+		if err := builder.migrator.SetConnectTimeout(builder.connTimeoutPtr); err != nil {
+			builder.migrator.logger.Fatal("can't set connect timeout", "error", err)
+		}
+	}
+
+	if builder.keepAlivePtr > 0 {
+		if err := builder.migrator.SetKeepAlive(builder.keepAlivePtr); err != nil {
+			builder.migrator.logger.Fatal("can't set keepalive", "error", err)
+		}
+	}
+
+	if builder.maxOpenPtr > 0 || builder.maxIdlePtr > 0 || builder.connLifetimePtr > 0 {
+		builder.migrator.SetConnectionPool(ConnectionPool{
+			MaxOpenConns:    builder.maxOpenPtr,
+			MaxIdleConns:    builder.maxIdlePtr,
+			ConnMaxLifetime: builder.connLifetimePtr,
+		})
+	}
+
+	if builder.isolationPtr != "" {
+		level, err := ParseIsolationLevel(builder.isolationPtr)
+		if err != nil {
+			builder.migrator.logger.Fatal(err.Error())
+		}
+
+		if err = builder.migrator.SetIsolationLevel(level); err != nil {
+			builder.migrator.logger.Fatal("can't set isolation level", "error", err)
+		}
+	}
+
+	if builder.tablePtr != "" {
+		if err := builder.migrator.SetMigrationsTable(builder.tablePtr); err != nil {
+			builder.migrator.logger.Fatal("can't use migrations table", "table", builder.tablePtr, "error", err)
+		}
+	}
+
+	flags := builder.migrateCommand.PersistentFlags()
+	if flags.Changed(builder.flagName("prefetch")) {
+		builder.migrator.SetPrefetchMigrations(builder.prefetchPtr)
+	}
+
+	if flags.Changed(builder.flagName("lock-timeout")) {
+		builder.migrator.SetLockTimeout(time.Duration(builder.lockTimeoutPtr) * time.Second)
+	}
+
+	if builder.heartbeatPtr > 0 {
+		builder.migrator.SetLockHeartbeat(time.Duration(builder.heartbeatPtr)*time.Second, nil)
+	}
 
 	// handle Ctrl+c
 	signals := make(chan os.Signal, 1)
@@ -134,9 +893,16 @@ func (builder *migratorCobraCommandBuilder) setupMigrator() {
 }
 
 func (builder *migratorCobraCommandBuilder) closeMigrator() {
-	sourceErr, databaseErr := builder.migrator.Close()
-	if sourceErr != nil || databaseErr != nil {
-		builder.migrator.logger.Error("encountered an error when close migrator", "sourceErr", sourceErr, "databaseErr", databaseErr)
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+
+	migrator := builder.migrator
+	if builder.rootMigrator != nil {
+		migrator = builder.rootMigrator
+	}
+
+	if err := migrator.CloseContext(ctx); err != nil {
+		builder.migrator.logger.Error("encountered an error when close migrator", "error", err)
 	}
 }
 
@@ -149,18 +915,89 @@ func (builder *migratorCobraCommandBuilder) buildCreateCmd() *cobra.Command {
 			defer builder.closeMigrator()
 			builder.setupMigrator()
 
-			if len(args) == 0 {
+			if len(args) == 0 && (builder.fromStdinPtr || builder.patternPtr == "" && !builder.wizardPtr) {
 				builder.migrator.logger.Fatal("please specify name")
 			}
-			name := args[0]
 
-			err := builder.migrator.MakeMigrate(
-				builder.tzPtr,
-				builder.formatPtr,
-				name,
-				builder.extPtr,
-				builder.seqPtr,
-				builder.seqDigitsPtr)
+			var name string
+			if len(args) > 0 {
+				name = args[0]
+			}
+
+			var err error
+
+			switch {
+			case builder.fromStdinPtr && builder.dryRunPtr:
+				err = builder.migrator.MakeMigrateFromSQLDryRun(os.Stdout, os.Stdin,
+					builder.tzPtr, builder.formatPtr, name, builder.extPtr, builder.seqPtr, builder.seqDigitsPtr)
+			case builder.fromStdinPtr:
+				err = builder.migrator.MakeMigrateFromSQL(os.Stdin,
+					builder.tzPtr, builder.formatPtr, name, builder.extPtr, builder.seqPtr, builder.seqDigitsPtr)
+			case builder.wizardPtr:
+				var change *TableChange
+				change, name, err = builder.runWizard()
+				if err != nil {
+					break
+				}
+
+				if builder.dryRunPtr {
+					err = builder.migrator.MakeTableMigrateDryRun(os.Stdout, change,
+						builder.tzPtr, builder.formatPtr, name, builder.extPtr, builder.seqPtr, builder.seqDigitsPtr)
+				} else {
+					err = builder.migrator.MakeTableMigrate(change,
+						builder.tzPtr, builder.formatPtr, name, builder.extPtr, builder.seqPtr, builder.seqDigitsPtr)
+				}
+			case builder.patternPtr != "":
+				var params PatternParams
+				params, err = builder.patternParams()
+				if err != nil {
+					break
+				}
+
+				if builder.dryRunPtr {
+					err = builder.migrator.MakePatternMigrateDryRun(os.Stdout, builder.patternPtr, params,
+						builder.tzPtr, builder.formatPtr, name, builder.extPtr, builder.seqPtr, builder.seqDigitsPtr)
+				} else {
+					err = builder.migrator.MakePatternMigrate(builder.patternPtr, params,
+						builder.tzPtr, builder.formatPtr, name, builder.extPtr, builder.seqPtr, builder.seqDigitsPtr)
+				}
+			case builder.fromDBPtr && builder.dryRunPtr:
+				builder.migrator.SetDropExtraTables(builder.dropTblsPtr)
+				err = builder.migrator.MakeMigrateFromDBDryRun(
+					os.Stdout,
+					builder.tzPtr,
+					builder.formatPtr,
+					name,
+					builder.extPtr,
+					builder.seqPtr,
+					builder.seqDigitsPtr)
+			case builder.fromDBPtr:
+				builder.migrator.SetDropExtraTables(builder.dropTblsPtr)
+				err = builder.migrator.MakeMigrateFromDB(
+					builder.tzPtr,
+					builder.formatPtr,
+					name,
+					builder.extPtr,
+					builder.seqPtr,
+					builder.seqDigitsPtr)
+			case builder.dryRunPtr:
+				err = builder.migrator.MakeMigrateDryRun(
+					os.Stdout,
+					builder.tzPtr,
+					builder.formatPtr,
+					name,
+					builder.extPtr,
+					builder.seqPtr,
+					builder.seqDigitsPtr)
+			default:
+				err = builder.migrator.MakeMigrate(
+					builder.tzPtr,
+					builder.formatPtr,
+					name,
+					builder.extPtr,
+					builder.seqPtr,
+					builder.seqDigitsPtr)
+			}
 
 			if err != nil {
 				builder.migrator.logger.Fatal(err.Error())
@@ -168,21 +1005,48 @@ func (builder *migratorCobraCommandBuilder) buildCreateCmd() *cobra.Command {
 
 		},
 	}
-	createCommand.Flags().StringVar(&builder.extPtr, "ext", "", "File extension")
+	createCommand.Flags().StringVar(&builder.extPtr, "ext", "", "File extension, sql.tmpl for migrations rendered with Go templates")
 	createCommand.Flags().BoolVar(&builder.seqPtr, "seq", false, "Use sequential numbers instead of timestamps (default: false)")
 	createCommand.Flags().IntVar(&builder.seqDigitsPtr, "digits", 6, "The number of digits to use in sequences")
 	createCommand.Flags().StringVar(&builder.formatPtr, "format", "", `The Go time format string to use. If the string "unix" or "unixNano" is specified, then the seconds or nanoseconds since January 1, 1970 UTC respectively will be used. Caution, due to the behavior of time.Time.Format(), invalid format strings will not error`)
 	createCommand.Flags().StringVar(&builder.tzPtr, "tz", "", `The timezone that will be used for format time (default: local)`)
+	createCommand.Flags().BoolVar(&builder.dryRunPtr, "dry-run", false, "Print the up/down SQL and target filenames without writing any file")
+	createCommand.Flags().BoolVar(&builder.fromDBPtr, "from-db", false, "Generate the migration by diffing the live database schema against the desired schema")
+	createCommand.Flags().BoolVar(&builder.dropTblsPtr, "drop-tables", false, "Make --from-db drop the live tables missing from the desired schema instead of logging them")
+	createCommand.Flags().StringVar(&builder.patternPtr, "pattern", "", "Create the migration of a built-in pattern: add-column, add-index or rename-column")
+	createCommand.Flags().StringVar(&builder.onTablePtr, "table", "", "The table of --pattern")
+	createCommand.Flags().StringArrayVar(&builder.columnsPtr, "column", nil, "A NAME:TYPE column of --pattern, the type being optional for add-index (repeatable)")
+	createCommand.Flags().BoolVar(&builder.notNullPtr, "not-null", false, "Make the columns added by --pattern add-column NOT NULL, backfilling --default")
+	createCommand.Flags().StringVar(&builder.defaultPtr, "default", "", "The SQL default of the columns added by --pattern add-column, e.g. 0 or 'none'")
+	createCommand.Flags().BoolVar(&builder.uniquePtr, "unique", false, "Make the index of --pattern add-index unique")
+	createCommand.Flags().StringVar(&builder.renameToPtr, "rename-to", "", "The new name of the column of --pattern rename-column")
+	createCommand.Flags().BoolVar(&builder.wizardPtr, "wizard", false, "Ask for the table, columns, indexes and constraints of the migration")
+	createCommand.Flags().BoolVar(&builder.fromStdinPtr, "from-stdin", false, "Read the up SQL from stdin and derive the down migration from it")
 
 	return createCommand
 
 }
 
+func (builder *migratorCobraCommandBuilder) patternParams() (PatternParams, error) {
+	params := PatternParams{Table: builder.onTablePtr, Default: builder.defaultPtr, Unique: builder.uniquePtr,
+		RenameTo: builder.renameToPtr}
+
+	for _, spec := range builder.columnsPtr {
+		column, err := ParseColumnSpec(spec, builder.notNullPtr)
+		if err != nil {
+			return params, err
+		}
+		params.Columns = append(params.Columns, column)
+	}
+	return params, nil
+}
+
 func (builder *migratorCobraCommandBuilder) buildGotoCmd() *cobra.Command {
 	gotoCommand := &cobra.Command{
-		Use:   gotoUsage,
-		Short: gotoUsageDesc,
-		Long:  gotoUsageDesc,
+		Use:               gotoUsage,
+		Short:             gotoUsageDesc,
+		Long:              gotoUsageDesc,
+		ValidArgsFunction: builder.completeVersions,
 		Run: func(cmd *cobra.Command, args []string) {
 			defer builder.closeMigrator()
 			builder.setupMigrator()
@@ -191,14 +1055,14 @@ func (builder *migratorCobraCommandBuilder) buildGotoCmd() *cobra.Command {
 				builder.migrator.logger.Fatal("please specify version argument V")
 			}
 
-			v, err := strconv.ParseUint(args[0], 10, 64)
+			v, err := builder.migrator.ResolveVersion(args[0])
 			if err != nil {
-				builder.migrator.logger.Fatal("can't read version argument V")
+				builder.migrator.logger.Fatal("can't read version argument V", "error", err)
 			}
 
 			startTime := time.Now()
 
-			if err = builder.migrator.Goto(uint(v)); err != nil {
+			if err = builder.migrator.Goto(v); err != nil {
 				if err != migrate.ErrNoChange {
 					builder.migrator.logger.Fatal(err.Error())
 				}
@@ -233,8 +1097,102 @@ func (builder *migratorCobraCommandBuilder) buildUpCommand() *cobra.Command {
 				limit = int(n)
 			}
 
+			if builder.outOfOrderPtr {
+				builder.migrator.AllowOutOfOrder()
+			}
+
+			if builder.allModulesPtr {
+				if limit >= 0 || builder.upDryRunPtr || builder.notBeforePtr != "" || builder.untilTimePtr != "" {
+					builder.migrator.logger.Fatal("--all-modules only applies every pending migration")
+				}
+
+				if err := builder.migrator.UpAllModules(); err != nil {
+					builder.migrator.logger.Fatal(err.Error())
+				}
+				return
+			}
+
+			var until time.Time
+			if builder.untilTimePtr != "" {
+				if limit >= 0 || builder.notBeforePtr != "" {
+					builder.migrator.logger.Fatal("--until-time can't be used with N or --not-before")
+				}
+
+				var err error
+				if until, err = ParseUntilTime(builder.untilTimePtr); err != nil {
+					builder.migrator.logger.Fatal(err.Error())
+				}
+			}
+
+			if builder.pickPtr {
+				if limit >= 0 || builder.upDryRunPtr || builder.notBeforePtr != "" || builder.untilTimePtr != "" {
+					builder.migrator.logger.Fatal("--interactive can't be used with N, --dry-run, --not-before or --until-time")
+				}
+
+				if !isTerminal(os.Stdin) {
+					builder.migrator.logger.Fatal("--interactive needs a terminal")
+				}
+
+				plan, err := builder.migrator.UpPlan(0)
+				if err != nil {
+					builder.migrator.logger.Fatal(err.Error())
+				}
+
+				if len(plan) == 0 {
+					builder.migrator.logger.Info(migrate.ErrNoChange.Error())
+					return
+				}
+
+				versions := builder.selectPending(plan)
+				if len(versions) == 0 {
+					builder.migrator.logger.Info("no migration selected")
+					return
+				}
+
+				if err = builder.migrator.UpSelected(versions); err != nil {
+					builder.migrator.logger.Fatal(err.Error())
+				}
+				return
+			}
+
+			if builder.upDryRunPtr {
+				var plan []*PlannedMigration
+				var err error
+				if builder.untilTimePtr != "" {
+					plan, err = builder.migrator.UntilPlan(until)
+				} else {
+					plan, err = builder.migrator.UpPlan(limit)
+				}
+				if err != nil {
+					builder.migrator.logger.Fatal(err.Error())
+				}
+
+				if len(plan) == 0 {
+					builder.migrator.logger.Info(migrate.ErrNoChange.Error())
+				}
+				printPlan(plan)
+				builder.printRisks(plan)
+				builder.printLockImpacts(plan)
+				return
+			}
+
+			if (builder.notBeforePtr == "") != (builder.windowPtr == 0) {
+				builder.migrator.logger.Fatal("--not-before and --window must be used together")
+			}
+
 			startTime := time.Now()
-			if err := builder.migrator.Up(limit); err != nil {
+			var err error
+			if builder.notBeforePtr != "" {
+				ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT)
+				err = builder.migrator.UpInWindow(ctx, limit, builder.notBeforePtr, builder.windowPtr)
+				stop()
+			} else if builder.untilTimePtr != "" {
+				err = builder.migrator.UpUntil(until)
+			} else {
+				err = builder.migrator.Up(limit)
+			}
+
+			if err != nil {
 				if err != migrate.ErrNoChange {
 					builder.migrator.logger.Fatal(err.Error())
 				}
@@ -247,6 +1205,12 @@ func (builder *migratorCobraCommandBuilder) buildUpCommand() *cobra.Command {
 
 		},
 	}
+	upCommand.Flags().BoolVar(&builder.outOfOrderPtr, "out-of-order", false, "Apply migrations older than the applied version that were never applied instead of failing")
+	upCommand.Flags().StringVar(&builder.notBeforePtr, "not-before", "", "Wait for the daily maintenance window opening at HH:MM (local time)")
+	upCommand.Flags().DurationVar(&builder.windowPtr, "window", 0, "The length of the maintenance window, e.g. 2h")
+	upCommand.Flags().BoolVar(&builder.upDryRunPtr, "dry-run", false, "List the pending migrations and the table locks they take without running them")
+	upCommand.Flags().StringVar(&builder.untilTimePtr, "until-time", "", "Only apply the migrations whose timestamp version is before this time, e.g. 2024-03-01 or 2024-03-01T12:00:00Z")
+	upCommand.Flags().BoolVar(&builder.pickPtr, "interactive", false, "Pick the pending migrations to apply on the terminal, skipping older ones needs --out-of-order")
 
 	return upCommand
 }
@@ -283,6 +1247,27 @@ func (builder *migratorCobraCommandBuilder) buildDownCommand() *cobra.Command {
 			defer builder.closeMigrator()
 			builder.setupMigrator()
 
+			if builder.downToPtr != "" {
+				if builder.allPtr || len(args) > 0 {
+					builder.migrator.logger.Fatal("--to cannot be used with N or --all")
+				}
+
+				v, err := builder.migrator.ResolveVersion(builder.downToPtr)
+				if err != nil {
+					builder.migrator.logger.Fatal("can't read version of --to", "error", err)
+				}
+
+				plan, err := builder.migrator.DownTo(v)
+				if err != nil {
+					if err != migrate.ErrNoChange {
+						builder.migrator.logger.Fatal(err.Error())
+					}
+					builder.migrator.logger.Info(err.Error())
+				}
+				printPlan(plan)
+				return
+			}
+
 			num, needsConfirm, err := numDownMigrationsFromArgs(builder.allPtr, args)
 			if err != nil {
 				builder.migrator.logger.Fatal(err.Error())
@@ -318,6 +1303,7 @@ func (builder *migratorCobraCommandBuilder) buildDownCommand() *cobra.Command {
 	}
 
 	downCommand.Flags().BoolVar(&builder.allPtr, "all", false, "Apply all down migrations")
+	downCommand.Flags().StringVar(&builder.downToPtr, "to", "", "Apply the down migrations back to this version")
 
 	return downCommand
 }
@@ -362,9 +1348,10 @@ func (builder *migratorCobraCommandBuilder) buildDropCommand() *cobra.Command {
 
 func (builder *migratorCobraCommandBuilder) buildForceCommand() *cobra.Command {
 	forceCommand := &cobra.Command{
-		Use:   forceUsage,
-		Short: forceUsageDesc,
-		Long:  forceUsageDesc,
+		Use:               forceUsage,
+		Short:             forceUsageDesc,
+		Long:              forceUsageDesc,
+		ValidArgsFunction: builder.completeVersions,
 		Run: func(cmd *cobra.Command, args []string) {
 			defer builder.closeMigrator()
 			builder.setupMigrator()
@@ -373,7 +1360,12 @@ func (builder *migratorCobraCommandBuilder) buildForceCommand() *cobra.Command {
 				builder.migrator.logger.Fatal("please specify version argument V")
 			}
 
-			v, err := strconv.ParseInt(args[0], 10, 64)
+			arg := args[0]
+			if arg == "nil" {
+				arg = "-1"
+			}
+
+			v, err := strconv.ParseInt(arg, 10, 64)
 			if err != nil {
 				builder.migrator.logger.Fatal("can't read version argument V")
 			}
@@ -397,6 +1389,39 @@ func (builder *migratorCobraCommandBuilder) buildForceCommand() *cobra.Command {
 	return forceCommand
 }
 
+func (builder *migratorCobraCommandBuilder) buildResetVersionCommand() *cobra.Command {
+	resetVersionCommand := &cobra.Command{
+		Use:   resetVersionUsage,
+		Short: resetVersionUsageDesc,
+		Long:  resetVersionUsageDesc,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			if !builder.forceResetPtr {
+				fmt.Println("Are you sure you want to clear the version record? [y/N]")
+				var response string
+				_, _ = fmt.Scanln(&response)
+
+				response = strings.ToLower(strings.TrimSpace(response))
+
+				if response != "y" {
+					builder.migrator.logger.Fatal("Not clearing the version record")
+				}
+			}
+
+			if err := builder.migrator.ResetVersion(); err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+			builder.migrator.logger.Info("version record cleared")
+		},
+	}
+	resetVersionCommand.Flags().BoolVar(&builder.forceResetPtr, "f", false, "Bypass confirmation")
+
+	return resetVersionCommand
+}
+
 func (builder *migratorCobraCommandBuilder) buildVersionCommand() *cobra.Command {
 	versionCommand := &cobra.Command{
 		Use:   versionUsage,
@@ -406,6 +1431,23 @@ func (builder *migratorCobraCommandBuilder) buildVersionCommand() *cobra.Command
 			defer builder.closeMigrator()
 			builder.setupMigrator()
 
+			if builder.allModulesPtr {
+				builder.printModulesStatus()
+				return
+			}
+
+			if builder.versionJSONPtr {
+				status, err := builder.migrator.Status()
+				if err != nil {
+					builder.migrator.logger.Fatal(err.Error())
+				}
+
+				if err = json.NewEncoder(os.Stdout).Encode(status); err != nil {
+					builder.migrator.logger.Fatal(err.Error())
+				}
+				return
+			}
+
 			if version, dirty, err := builder.migrator.Version(); err != nil {
 				builder.migrator.logger.Fatal(err.Error())
 			} else {
@@ -415,8 +1457,1381 @@ func (builder *migratorCobraCommandBuilder) buildVersionCommand() *cobra.Command
 					builder.migrator.logger.Printf("%v", version)
 				}
 			}
+
+			if builder.migrator.historyEnabled {
+				status, err := builder.migrator.Status()
+				if err != nil {
+					builder.migrator.logger.Fatal(err.Error())
+				}
+
+				if status.EstimatedMs != nil {
+					builder.migrator.logger.Info("pending migrations", "count", status.PendingCount,
+						"estimated", time.Duration(*status.EstimatedMs)*time.Millisecond)
+				}
+			}
 		},
 	}
+	versionCommand.Flags().BoolVar(&builder.versionJSONPtr, "json", false, "Print the version status as json")
 
 	return versionCommand
 }
+
+func (builder *migratorCobraCommandBuilder) buildTagCommand() *cobra.Command {
+	tagCommand := &cobra.Command{
+		Use:   tagUsage,
+		Short: tagUsageDesc,
+		Long:  tagUsageDesc,
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			if len(args) == 0 {
+				tags, err := builder.migrator.Tags()
+				if err != nil {
+					builder.migrator.logger.Fatal(err.Error())
+				}
+
+				for _, tag := range tags {
+					fmt.Printf("%s\t%d\t%s\n", tag.Name, tag.Version, tag.CreatedAt.Format(time.RFC3339))
+				}
+				return
+			}
+
+			tag, err := builder.migrator.Tag(args[0], builder.forceTagPtr)
+			if err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+
+			builder.migrator.logger.Info(fmt.Sprintf("tagged version %d as %s", tag.Version, tag.Name))
+		},
+	}
+	tagCommand.Flags().BoolVar(&builder.forceTagPtr, "f", false, "Move the tag if it already exists")
+
+	return tagCommand
+}
+
+func printPlan(plan []*PlannedMigration) {
+	for _, planned := range plan {
+		line := fmt.Sprintf("%s %d %s", planned.Direction, planned.Version, planned.Identifier)
+		if planned.Missing {
+			line += " (no down migration, only the version is reverted)"
+		}
+		fmt.Println(line)
+	}
+}
+
+// printLockImpacts prints the table locks the migrations of plan take, on postgres only.
+func (builder *migratorCobraCommandBuilder) printLockImpacts(plan []*PlannedMigration) {
+	if builder.migrator.dialect != DialectPostgres || len(plan) == 0 {
+		return
+	}
+
+	impacts, err := builder.migrator.AnalyzeLocks(plan)
+	if err != nil {
+		builder.migrator.logger.Fatal(err.Error())
+	}
+
+	if len(impacts) > 0 {
+		fmt.Println()
+	}
+	for _, impact := range impacts {
+		statement := strings.Join(strings.Fields(impact.Statement), " ")
+		if len(statement) > 80 {
+			statement = statement[:77] + "..."
+		}
+
+		fmt.Printf("%s %d: %s\n", impact.Direction, impact.Version, statement)
+		lock := impact.Lock
+		if len(impact.Tables) > 0 {
+			lock += " on " + strings.Join(impact.Tables, ", ")
+		}
+		fmt.Printf("\t%s, %s\n", lock, impact.Blocks)
+		if impact.Note != "" {
+			fmt.Printf("\t%s\n", impact.Note)
+		}
+	}
+}
+
+func (builder *migratorCobraCommandBuilder) buildRollbackCommand() *cobra.Command {
+	rollbackCommand := &cobra.Command{
+		Use:   rollbackUsage,
+		Short: rollbackUsageDesc,
+		Long:  rollbackUsageDesc,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			if builder.rollbackToTagPtr == "" {
+				builder.migrator.logger.Fatal("please specify the tag with --to-tag")
+			}
+
+			if builder.rollbackDryRunPtr {
+				plan, err := builder.migrator.RollbackToTagPlan(builder.rollbackToTagPtr)
+				if err != nil {
+					builder.migrator.logger.Fatal(err.Error())
+				}
+
+				if len(plan) == 0 {
+					builder.migrator.logger.Info(migrate.ErrNoChange.Error())
+				}
+				printPlan(plan)
+				builder.printRisks(plan)
+				builder.printLockImpacts(plan)
+				return
+			}
+
+			startTime := time.Now()
+			plan, err := builder.migrator.RollbackToTag(builder.rollbackToTagPtr)
+			if err != nil {
+				if err != migrate.ErrNoChange {
+					builder.migrator.logger.Fatal(err.Error())
+				}
+				builder.migrator.logger.Info(err.Error())
+			}
+			printPlan(plan)
+
+			if builder.verbosePtr {
+				builder.migrator.logger.Info(fmt.Sprintf("Finished After %d ms", time.Since(startTime).Microseconds()))
+			}
+		},
+	}
+	rollbackCommand.Flags().StringVar(&builder.rollbackToTagPtr, "to-tag", "", "The tag to roll back to")
+	rollbackCommand.Flags().BoolVar(&builder.rollbackDryRunPtr, "dry-run", false, "List the migrations that would be reverted without running them")
+
+	return rollbackCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildConflictsCommand() *cobra.Command {
+	conflictsCommand := &cobra.Command{
+		Use:   conflictsUsage,
+		Short: conflictsUsageDesc,
+		Long:  conflictsUsageDesc,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			conflicts, err := builder.migrator.Conflicts(builder.conflictsBasePtr)
+			if err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+
+			for _, conflict := range conflicts {
+				fmt.Println(conflict.String())
+			}
+
+			if len(conflicts) > 0 {
+				builder.migrator.logger.Fatal(errConflicts.Error())
+			}
+		},
+	}
+	conflictsCommand.Flags().StringVar(&builder.conflictsBasePtr, "base", "main", "The branch the current branch will be merged into")
+
+	return conflictsCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildRebaseCommand() *cobra.Command {
+	rebaseCommand := &cobra.Command{
+		Use:   rebaseUsage,
+		Short: rebaseUsageDesc,
+		Long:  rebaseUsageDesc,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			var (
+				renames []*MigrationRename
+				err     error
+			)
+
+			if builder.rebaseDryRunPtr {
+				renames, err = builder.migrator.RebasePlan(builder.rebaseBasePtr)
+			} else {
+				renames, err = builder.migrator.Rebase(builder.rebaseBasePtr)
+			}
+			if err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+
+			if len(renames) == 0 {
+				builder.migrator.logger.Info("migrations are already after " + builder.rebaseBasePtr)
+			}
+
+			for _, rename := range renames {
+				fmt.Printf("%s -> %s\n", rename.From, rename.To)
+			}
+		},
+	}
+	rebaseCommand.Flags().StringVar(&builder.rebaseBasePtr, "base", "main", "The branch the current branch will be merged into")
+	rebaseCommand.Flags().BoolVar(&builder.rebaseDryRunPtr, "dry-run", false, "Print the renames without applying them")
+
+	return rebaseCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildShowCommand() *cobra.Command {
+	showCommand := &cobra.Command{
+		Use:               showUsage,
+		Short:             showUsageDesc,
+		Long:              showUsageDesc,
+		ValidArgsFunction: builder.completeVersions,
+		Args:              cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			v, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				builder.migrator.logger.Fatal("can't read version argument V")
+			}
+
+			if err = builder.migrator.Show(os.Stdout, uint(v), args[1:]...); err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+		},
+	}
+
+	return showCommand
+}
+
+func (builder *migratorCobraCommandBuilder) completeVersions(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		if cmd.Name() == "show" && len(args) == 1 {
+			return []string{directionUp, directionDown}, cobra.ShellCompDirectiveNoFileComp
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	if builder.pathPtr != "" {
+		if err := builder.migrator.SetMigrationsPath(builder.pathPtr); err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+	}
+
+	versions := builder.migrator.source.versions()
+	completions := make([]string, 0, len(versions))
+	for _, version := range versions {
+		completions = append(completions, strconv.FormatUint(uint64(version), 10))
+	}
+
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+func (builder *migratorCobraCommandBuilder) buildCompletionCommand() *cobra.Command {
+	completionCommand := &cobra.Command{
+		Use:       completionUsage,
+		Short:     completionUsageDesc,
+		Long:      completionUsageDesc,
+		Args:      cobra.ExactValidArgs(1),
+		ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+
+			root := cmd.Root()
+			switch args[0] {
+			case "bash":
+				err = root.GenBashCompletionV2(os.Stdout, true)
+			case "zsh":
+				err = root.GenZshCompletion(os.Stdout)
+			case "fish":
+				err = root.GenFishCompletion(os.Stdout, true)
+			case "powershell":
+				err = root.GenPowerShellCompletionWithDesc(os.Stdout)
+			}
+
+			if err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+		},
+	}
+
+	return completionCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildGenCommand() *cobra.Command {
+	genCommand := &cobra.Command{
+		Use:   genUsage,
+		Short: genUsageDesc,
+		Long:  genUsageDesc,
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			builder.setupMigrator()
+
+			name := "auto"
+			if len(args) > 0 {
+				name = args[0]
+			}
+
+			code := genExitOK
+			created, err := builder.migrator.Generate(
+				builder.tzPtr,
+				builder.formatPtr,
+				name,
+				builder.extPtr,
+				builder.seqPtr,
+				builder.seqDigitsPtr)
+
+			switch {
+			case errors.Is(err, ErrMigrateFunc):
+				builder.migrator.logger.Error(err.Error())
+				code = genExitMigrateFuncErr
+			case err != nil:
+				builder.migrator.logger.Error(err.Error())
+				code = genExitError
+			case !created:
+				builder.migrator.logger.Info("no change")
+			}
+
+			builder.closeMigrator()
+			os.Exit(code)
+		},
+	}
+	genCommand.Flags().StringVar(&builder.extPtr, "ext", "", "File extension")
+	genCommand.Flags().BoolVar(&builder.seqPtr, "seq", false, "Use sequential numbers instead of timestamps (default: false)")
+	genCommand.Flags().IntVar(&builder.seqDigitsPtr, "digits", 6, "The number of digits to use in sequences")
+	genCommand.Flags().StringVar(&builder.formatPtr, "format", "", `The Go time format string to use. If the string "unix" or "unixNano" is specified, then the seconds or nanoseconds since January 1, 1970 UTC respectively will be used`)
+	genCommand.Flags().StringVar(&builder.tzPtr, "tz", "", `The timezone that will be used for format time (default: local)`)
+
+	return genCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildBundleCommand() *cobra.Command {
+	bundleCommand := &cobra.Command{
+		Use:   bundleUsage,
+		Short: bundleUsageDesc,
+		Long:  bundleUsageDesc,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			if builder.bundleOutPtr == "" {
+				builder.migrator.logger.Fatal("please specify the bundle file with --out")
+			}
+
+			signer := builder.signer()
+
+			f, err := os.Create(builder.bundleOutPtr)
+			if err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+
+			err = builder.migrator.Bundle(f, signer)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				_ = os.Remove(builder.bundleOutPtr)
+				builder.migrator.logger.Fatal(err.Error())
+			}
+
+			builder.migrator.logger.Info("wrote bundle", "path", builder.bundleOutPtr, "signed", signer != nil)
+		},
+	}
+	bundleCommand.Flags().StringVar(&builder.bundleOutPtr, "out", "", "The bundle file to write")
+	bundleCommand.Flags().StringVar(&builder.signingKeyPtr, "signing-key", "", "Sign the manifest with this ed25519 private key (PKCS#8 PEM)")
+	bundleCommand.Flags().StringVar(&builder.gpgUserPtr, "gpg-user", "", "Sign the manifest with this gpg key, implies --gpg")
+
+	return bundleCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildManifestCommand() *cobra.Command {
+	manifestCommand := &cobra.Command{
+		Use:   manifestUsage,
+		Short: manifestUsageDesc,
+		Long:  manifestUsageDesc,
+	}
+
+	manifestWriteCommand := &cobra.Command{
+		Use:   manifestWriteUsage,
+		Short: manifestWriteUsageDesc,
+		Long:  manifestWriteUsageDesc,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			if err := builder.migrator.WriteManifest(); err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+			builder.migrator.logger.Info("wrote manifest")
+		},
+	}
+	manifestCommand.AddCommand(manifestWriteCommand)
+
+	manifestVerifyCommand := &cobra.Command{
+		Use:   manifestVerifyUsage,
+		Short: manifestVerifyUsageDesc,
+		Long:  manifestVerifyUsageDesc,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			if err := builder.migrator.VerifyManifest(); err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+			builder.migrator.logger.Info("migrations match the manifest")
+		},
+	}
+	manifestCommand.AddCommand(manifestVerifyCommand)
+
+	manifestSignCommand := &cobra.Command{
+		Use:   manifestSignUsage,
+		Short: manifestSignUsageDesc,
+		Long:  manifestSignUsageDesc,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			signer := builder.signer()
+			if signer == nil {
+				builder.migrator.logger.Fatal("please specify --signing-key, --gpg or --gpg-user")
+			}
+
+			if err := builder.migrator.SignManifest(signer); err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+			builder.migrator.logger.Info("signed manifest", "signature", signer.SignatureFile())
+		},
+	}
+	manifestSignCommand.Flags().StringVar(&builder.signingKeyPtr, "signing-key", "", "Sign the manifest with this ed25519 private key (PKCS#8 PEM)")
+	manifestSignCommand.Flags().StringVar(&builder.gpgUserPtr, "gpg-user", "", "Sign the manifest with this gpg key, implies --gpg")
+	manifestCommand.AddCommand(manifestSignCommand)
+
+	return manifestCommand
+}
+
+// signer returns the signer of the flags, nil when no signing was asked for.
+func (builder *migratorCobraCommandBuilder) signer() Signer {
+	if builder.gpgPtr || builder.gpgUserPtr != "" {
+		return &GPGSigner{User: builder.gpgUserPtr}
+	}
+
+	if builder.signingKeyPtr == "" {
+		return nil
+	}
+
+	signer, err := LoadSigningKey(builder.signingKeyPtr)
+	if err != nil {
+		builder.migrator.logger.Fatal("can't read signing key", "error", err)
+	}
+	return signer
+}
+
+// verifier returns the signature verifier of the flags, nil when none was given.
+func (builder *migratorCobraCommandBuilder) verifier() SignatureVerifier {
+	if builder.gpgPtr || builder.gpgKeyringPtr != "" {
+		return &GPGVerifier{Keyring: builder.gpgKeyringPtr}
+	}
+
+	if builder.verifyKeyPtr == "" {
+		return nil
+	}
+
+	verifier, err := LoadVerifyKey(builder.verifyKeyPtr)
+	if err != nil {
+		builder.migrator.logger.Fatal("can't read verify key", "error", err)
+	}
+	return verifier
+}
+
+func (builder *migratorCobraCommandBuilder) buildPlanCommand() *cobra.Command {
+	planCommand := &cobra.Command{
+		Use:   planUsage,
+		Short: planUsageDesc,
+		Long:  planUsageDesc,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			if builder.planOutPtr == "" {
+				builder.migrator.logger.Fatal("please specify the plan file with --out")
+			}
+
+			plan, err := builder.migrator.Plan(builder.planToPtr)
+			if err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+
+			if err = WritePlan(builder.planOutPtr, plan); err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+
+			if len(plan.Migrations) == 0 {
+				builder.migrator.logger.Info(migrate.ErrNoChange.Error())
+			}
+			printPlan(plan.Migrations)
+			builder.printRisks(plan.Migrations)
+
+			if plan.EstimatedMs != nil {
+				fmt.Printf("estimated duration: %s\n", time.Duration(*plan.EstimatedMs)*time.Millisecond)
+			}
+		},
+	}
+	planCommand.Flags().StringVar(&builder.planOutPtr, "out", "", "The plan file to write")
+	planCommand.Flags().IntVar(&builder.planToPtr, "to", -1, "The version to plan for, 0 to revert every migration (default: the latest version)")
+
+	return planCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildApplyCommand() *cobra.Command {
+	applyCommand := &cobra.Command{
+		Use:   applyUsage,
+		Short: applyUsageDesc,
+		Long:  applyUsageDesc,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			plan, err := ReadPlan(args[0])
+			if err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+
+			startTime := time.Now()
+			if err = builder.migrator.ApplyPlan(plan); err != nil {
+				if err != migrate.ErrNoChange {
+					builder.migrator.logger.Fatal(err.Error())
+				}
+				builder.migrator.logger.Info(err.Error())
+			}
+
+			if builder.verbosePtr {
+				builder.migrator.logger.Info(fmt.Sprintf("Finished After %d ms", time.Since(startTime).Microseconds()))
+			}
+		},
+	}
+
+	return applyCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildApproveCommand() *cobra.Command {
+	approveCommand := &cobra.Command{
+		Use:   approveUsage,
+		Short: approveUsageDesc,
+		Long:  approveUsageDesc,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			signer := builder.signer()
+			if signer == nil {
+				builder.migrator.logger.Fatal("please specify --signing-key, --gpg or --gpg-user")
+			}
+
+			approval, err := ApprovePlan(args[0], signer)
+			if err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+			builder.migrator.logger.Info("approved plan", "path", args[0], "approver", approval.Approver)
+		},
+	}
+	approveCommand.Flags().StringVar(&builder.signingKeyPtr, "signing-key", "", "Sign the approval with this ed25519 private key (PKCS#8 PEM)")
+	approveCommand.Flags().StringVar(&builder.gpgUserPtr, "gpg-user", "", "Sign the approval with this gpg key, implies --gpg")
+
+	return approveCommand
+}
+
+func printLockStatus(status *LockStatus) {
+	if !status.Held {
+		fmt.Printf("lock %s is free\n", status.LockID)
+		return
+	}
+
+	fmt.Printf("lock %s is held by session %d\n", status.LockID, status.PID)
+	if status.User != "" {
+		fmt.Printf("  user: %s\n", status.User)
+	}
+	if status.Application != "" {
+		fmt.Printf("  application: %s\n", status.Application)
+	}
+	if status.ClientAddr != "" {
+		fmt.Printf("  client: %s\n", status.ClientAddr)
+	}
+	if status.SessionFrom != nil {
+		fmt.Printf("  session started: %s\n", status.SessionFrom.Format(time.RFC3339))
+	}
+}
+
+func (builder *migratorCobraCommandBuilder) buildLockStatusCommand() *cobra.Command {
+	lockStatusCommand := &cobra.Command{
+		Use:   lockStatusUsage,
+		Short: lockStatusUsageDesc,
+		Long:  lockStatusUsageDesc,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			status, err := builder.migrator.LockStatus()
+			if err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+			printLockStatus(status)
+		},
+	}
+
+	return lockStatusCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildUnlockCommand() *cobra.Command {
+	unlockCommand := &cobra.Command{
+		Use:   unlockUsage,
+		Short: unlockUsageDesc,
+		Long:  unlockUsageDesc,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			if !builder.unlockForcePtr {
+				builder.migrator.logger.Fatal("unlock terminates the session holding the lock, confirm with --force")
+			}
+
+			builder.migrator.logger.Error("WARNING: forcing the migration lock, a migration still running will be interrupted and may leave the database dirty")
+			status, err := builder.migrator.ForceUnlock()
+			if err != nil {
+				if errors.Is(err, errNotLocked) {
+					builder.migrator.logger.Info(err.Error())
+					return
+				}
+				builder.migrator.logger.Fatal(err.Error())
+			}
+
+			builder.migrator.logger.Error("WARNING: terminated the session holding the migration lock, check the version with version before migrating",
+				"pid", status.PID, "user", status.User)
+		},
+	}
+	unlockCommand.Flags().BoolVar(&builder.unlockForcePtr, "force", false, "Terminate the session holding the lock")
+
+	return unlockCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildDoctorCommand() *cobra.Command {
+	doctorCommand := &cobra.Command{
+		Use:   doctorUsage,
+		Short: doctorUsageDesc,
+		Long:  doctorUsageDesc,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			failed := false
+			for _, check := range builder.migrator.Doctor() {
+				line := fmt.Sprintf("[%s] %s", check.Result, check.Name)
+				if check.Detail != "" {
+					line += ": " + check.Detail
+				}
+				fmt.Println(line)
+
+				failed = failed || check.Result == DoctorFail
+			}
+
+			if failed {
+				builder.closeMigrator()
+				os.Exit(1)
+			}
+		},
+	}
+
+	return doctorCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildPingCommand() *cobra.Command {
+	pingCommand := &cobra.Command{
+		Use:   pingUsage,
+		Short: pingUsageDesc,
+		Long:  pingUsageDesc,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			if err := builder.migrator.Ping(); err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+			builder.migrator.logger.Info("ok")
+		},
+	}
+
+	return pingCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildResumeCommand() *cobra.Command {
+	resumeCommand := &cobra.Command{
+		Use:   resumeUsage,
+		Short: resumeUsageDesc,
+		Long:  resumeUsageDesc,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			startTime := time.Now()
+			if err := builder.migrator.Resume(); err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+
+			if builder.verbosePtr {
+				builder.migrator.logger.Info(fmt.Sprintf("Finished After %d ms", time.Since(startTime).Microseconds()))
+			}
+		},
+	}
+
+	return resumeCommand
+}
+
+func printDiagnosis(diagnosis *Diagnosis) {
+	if !diagnosis.Dirty {
+		fmt.Println("database is not dirty")
+		return
+	}
+
+	fmt.Printf("version %d is dirty, its %s migration failed\n", diagnosis.Version, diagnosis.Direction)
+	if diagnosis.File != "" {
+		fmt.Printf("  file: %s\n", diagnosis.File)
+	}
+	if diagnosis.FailedAt != nil {
+		fmt.Printf("  failed at: %s\n", diagnosis.FailedAt.Format(time.RFC3339))
+	}
+	if diagnosis.LastError != "" {
+		fmt.Printf("  error: %s\n", diagnosis.LastError)
+	} else {
+		fmt.Println("  error: not recorded, enable the history or the audit to keep it")
+	}
+	if diagnosis.Progress != nil {
+		fmt.Printf("  statements committed: %d of %d\n", diagnosis.Progress.Committed, diagnosis.Progress.Total)
+	}
+
+	fmt.Println("suggested commands:")
+	for _, suggestion := range diagnosis.Suggestions {
+		fmt.Printf("  %s\n", suggestion)
+	}
+}
+
+func (builder *migratorCobraCommandBuilder) buildDiagnoseCommand() *cobra.Command {
+	diagnoseCommand := &cobra.Command{
+		Use:   diagnoseUsage,
+		Short: diagnoseUsageDesc,
+		Long:  diagnoseUsageDesc,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			diagnosis, err := builder.migrator.Diagnose()
+			if err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+			printDiagnosis(diagnosis)
+		},
+	}
+
+	return diagnoseCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildShadowVerifyCommand() *cobra.Command {
+	shadowVerifyCommand := &cobra.Command{
+		Use:   shadowVerifyUsage,
+		Short: shadowVerifyUsageDesc,
+		Long:  shadowVerifyUsageDesc,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			if builder.shadowURLPtr == "" {
+				builder.migrator.logger.Fatal("shadow-verify needs --shadow-url")
+			}
+
+			startTime := time.Now()
+			report, err := builder.migrator.ShadowVerify(builder.shadowURLPtr)
+			if err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+
+			builder.migrator.logger.Info("applied migrations to the shadow database", "pending", len(report.Pending), "version", report.Version)
+			for _, statement := range report.Mismatches {
+				fmt.Println(statement)
+			}
+
+			if builder.verbosePtr {
+				builder.migrator.logger.Info(fmt.Sprintf("Finished After %d ms", time.Since(startTime).Microseconds()))
+			}
+
+			if len(report.Mismatches) > 0 {
+				builder.migrator.logger.Error("the schema of the shadow database doesn't match the models", "mismatches", len(report.Mismatches))
+				builder.closeMigrator()
+				os.Exit(1)
+			}
+			builder.migrator.logger.Info("the schema of the shadow database matches the models")
+		},
+	}
+
+	shadowVerifyCommand.Flags().StringVar(&builder.shadowURLPtr, "shadow-url", "", "The url of a scratch database the migrations are applied to")
+
+	return shadowVerifyCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildSnapshotCommand() *cobra.Command {
+	snapshotCommand := &cobra.Command{
+		Use:   snapshotUsage,
+		Short: snapshotUsageDesc,
+		Long:  snapshotUsageDesc,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			if builder.snapshotOutPtr == "-" {
+				snapshot, err := builder.migrator.Snapshot()
+				if err != nil {
+					builder.migrator.logger.Fatal(err.Error())
+				}
+				fmt.Print(snapshot)
+				return
+			}
+
+			path, err := builder.migrator.WriteSnapshot(builder.snapshotOutPtr)
+			if err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+			builder.migrator.logger.Info("wrote schema snapshot", "path", path)
+		},
+	}
+	snapshotCommand.Flags().StringVar(&builder.snapshotOutPtr, "out", "", "The file to write the snapshot to, - for stdout (default: schema.sql in the migrations directory)")
+
+	return snapshotCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildSchemaDiffCommand() *cobra.Command {
+	schemaDiffCommand := &cobra.Command{
+		Use:   schemaDiffUsage,
+		Short: schemaDiffUsageDesc,
+		Long:  schemaDiffUsageDesc,
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			diff, err := builder.migrator.DiffDatabases(args[0], args[1])
+			if err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+
+			if builder.schemaDiffJSONPtr {
+				body, err := json.MarshalIndent(diff, "", "  ")
+				if err != nil {
+					builder.migrator.logger.Fatal(err.Error())
+				}
+				fmt.Println(string(body))
+			} else {
+				printSchemaDiff(diff)
+			}
+
+			if !diff.Empty() {
+				builder.closeMigrator()
+				os.Exit(1)
+			}
+		},
+	}
+	schemaDiffCommand.Flags().BoolVar(&builder.schemaDiffJSONPtr, "json", false, "Print the differences and the statements as json")
+
+	return schemaDiffCommand
+}
+
+func printSchemaDiff(diff *SchemaDiff) {
+	if diff.Empty() {
+		fmt.Println("the schemas are the same")
+		return
+	}
+
+	for _, difference := range diff.Differences {
+		name := difference.Table
+		if difference.Column != "" {
+			name += "." + difference.Column
+		}
+
+		switch difference.Kind {
+		case DifferenceChanged:
+			fmt.Printf("%s: %s, %s in first, %s in second\n", name, difference.Kind, difference.First, difference.Second)
+		default:
+			fmt.Printf("%s: %s\n", name, difference.Kind)
+		}
+	}
+
+	fmt.Println()
+	for _, statement := range diff.SQL {
+		fmt.Println(statement + defaultTerminator)
+	}
+}
+
+func (builder *migratorCobraCommandBuilder) buildVerifyCommand() *cobra.Command {
+	verifyCommand := &cobra.Command{
+		Use:   verifyUsage,
+		Short: verifyUsageDesc,
+		Long:  verifyUsageDesc,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			entry, err := builder.migrator.VerifySchema()
+			if err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+
+			builder.migrator.logger.Info("the schema matches the last migration", "version", entry.Version,
+				"direction", entry.Direction, "checksum", entry.SchemaChecksum)
+		},
+	}
+
+	return verifyCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildScriptCommand() *cobra.Command {
+	scriptCommand := &cobra.Command{
+		Use:   scriptUsage,
+		Short: scriptUsageDesc,
+		Long:  scriptUsageDesc,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			if builder.scriptDownPtr <= 0 {
+				builder.migrator.logger.Fatal("please specify the number of down migrations with --down")
+			}
+
+			if builder.scriptOutPtr == "" {
+				if err := builder.migrator.WriteRollbackScript(os.Stdout, builder.scriptDownPtr); err != nil {
+					builder.migrator.logger.Fatal(err.Error())
+				}
+				return
+			}
+
+			f, err := os.Create(builder.scriptOutPtr)
+			if err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+
+			err = builder.migrator.WriteRollbackScript(f, builder.scriptDownPtr)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				_ = os.Remove(builder.scriptOutPtr)
+				builder.migrator.logger.Fatal(err.Error())
+			}
+
+			builder.migrator.logger.Info("wrote rollback script", "path", builder.scriptOutPtr)
+		},
+	}
+	scriptCommand.Flags().IntVar(&builder.scriptDownPtr, "down", 0, "The number of down migrations to write")
+	scriptCommand.Flags().StringVar(&builder.scriptOutPtr, "out", "", "The file to write the script to (default: stdout)")
+
+	return scriptCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildWaitCommand() *cobra.Command {
+	waitCommand := &cobra.Command{
+		Use:   waitUsage,
+		Short: waitUsageDesc,
+		Long:  waitUsageDesc,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			version, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				builder.migrator.logger.Fatal("can't read version argument V", "error", err)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+			if builder.waitTimeoutPtr > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, builder.waitTimeoutPtr)
+				defer cancel()
+			}
+
+			if err = builder.migrator.WaitForVersion(ctx, uint(version)); err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+			builder.migrator.logger.Info("the database reached the version", "version", version)
+		},
+	}
+	waitCommand.Flags().DurationVar(&builder.waitTimeoutPtr, "timeout", 0, "Give up after this long, e.g. 5m (default: wait forever)")
+
+	return waitCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildGenerateCheckCommand() *cobra.Command {
+	generateCheckCommand := &cobra.Command{
+		Use:   generateCheckUsage,
+		Short: generateCheckUsageDesc,
+		Long:  generateCheckUsageDesc,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			if err := builder.migrator.GenerateCheck(); err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+
+			builder.migrator.logger.Info("migrations are up to date with the models")
+		},
+	}
+
+	return generateCheckCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildSeedCommand() *cobra.Command {
+	seedCommand := &cobra.Command{
+		Use:   seedUsage,
+		Short: seedUsageDesc,
+		Long:  seedUsageDesc,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			env := "dev"
+			if builder.profilePtr != "" {
+				env = builder.profilePtr
+			}
+			if len(args) > 0 {
+				env = args[0]
+			}
+
+			startTime := time.Now()
+			applied, err := builder.migrator.Seed(env)
+			if err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+
+			builder.migrator.logger.Info(fmt.Sprintf("applied %d seeds", applied), "env", env)
+
+			if builder.verbosePtr {
+				builder.migrator.logger.Info(fmt.Sprintf("Finished After %d ms", time.Since(startTime).Microseconds()))
+			}
+		},
+	}
+
+	return seedCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildFixturesCommand() *cobra.Command {
+	fixturesCommand := &cobra.Command{
+		Use:   fixturesUsage,
+		Short: fixturesUsageDesc,
+		Long:  fixturesUsageDesc,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			if len(args) == 0 {
+				builder.migrator.logger.Fatal("please specify fixture directory or files")
+			}
+
+			mode := FixtureMode(builder.fixtureModePtr)
+			startTime := time.Now()
+
+			var err error
+			if info, statErr := os.Stat(args[0]); statErr == nil && info.IsDir() && len(args) == 1 {
+				err = builder.migrator.LoadFixturesDir(cmd.Context(), args[0], mode)
+			} else {
+				err = builder.migrator.LoadFixtures(cmd.Context(), args, mode)
+			}
+
+			if err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+
+			if builder.verbosePtr {
+				builder.migrator.logger.Info(fmt.Sprintf("Finished After %d ms", time.Since(startTime).Microseconds()))
+			}
+		},
+	}
+
+	fixturesCommand.Flags().StringVar(&builder.fixtureModePtr, "mode", string(FixtureInsert), "How to load the fixtures: insert, truncate or upsert")
+
+	return fixturesCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildTestCommand() *cobra.Command {
+	testCommand := &cobra.Command{
+		Use:   testUsage,
+		Short: testUsageDesc,
+		Long:  testUsageDesc,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			if builder.scratchPtr == "" {
+				builder.migrator.logger.Fatal("test needs --scratch-url")
+			}
+
+			if !builder.forceTestPtr {
+				fmt.Println("This applies all down migrations to the scratch database, whose data is lost. Continue? [y/N]")
+				var response string
+				_, _ = fmt.Scanln(&response)
+				response = strings.ToLower(strings.TrimSpace(response))
+
+				if response != "y" {
+					builder.migrator.logger.Fatal("Aborted testing migrations")
+				}
+			}
+
+			startTime := time.Now()
+			if err := builder.migrator.TestReversibility(builder.scratchPtr); err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+
+			builder.migrator.logger.Info("all migrations are reversible")
+
+			if builder.verbosePtr {
+				builder.migrator.logger.Info(fmt.Sprintf("Finished After %d ms", time.Since(startTime).Microseconds()))
+			}
+		},
+	}
+
+	testCommand.Flags().BoolVar(&builder.forceTestPtr, "f", false, "Bypass the confirmation prompt")
+	testCommand.Flags().StringVar(&builder.scratchPtr, "scratch-url", "", "The url of a disposable database the migrations are applied to")
+
+	return testCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildImportCommand() *cobra.Command {
+	importCommand := &cobra.Command{
+		Use:   importUsage,
+		Short: importUsageDesc,
+		Long:  importUsageDesc,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			if len(args) == 0 {
+				builder.migrator.logger.Fatal("please specify the directory to import")
+			}
+
+			if builder.importFromPtr == "" {
+				builder.migrator.logger.Fatal("please specify the tool to import from with --from")
+			}
+
+			if err := builder.migrator.Import(builder.importFromPtr, args[0]); err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+
+			if builder.importHistoryPtr {
+				if err := builder.migrator.ImportHistory(builder.importFromPtr, args[0]); err != nil {
+					builder.migrator.logger.Fatal(err.Error())
+				}
+			}
+		},
+	}
+
+	importCommand.Flags().StringVar(&builder.importFromPtr, "from", "", "The tool the migrations are imported from: flyway or goose")
+	importCommand.Flags().BoolVar(&builder.importHistoryPtr, "history", false, "Set the database version from the tool's history table")
+
+	return importCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildExportCommand() *cobra.Command {
+	exportCommand := &cobra.Command{
+		Use:   exportUsage,
+		Short: exportUsageDesc,
+		Long:  exportUsageDesc,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			var w io.Writer = os.Stdout
+
+			if builder.exportOutPtr != "" {
+				f, err := os.Create(builder.exportOutPtr)
+				if err != nil {
+					builder.migrator.logger.Fatal(err.Error())
+				}
+				defer f.Close()
+				w = f
+			}
+
+			if err := builder.migrator.Export(w, builder.exportFormatPtr, builder.exportOutPtr); err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+		},
+	}
+
+	exportCommand.Flags().StringVar(&builder.exportFormatPtr, "format", exportFormatLiquibase, "The export format: liquibase")
+	exportCommand.Flags().StringVar(&builder.exportOutPtr, "out", "", "Write the export to this file instead of stdout")
+
+	return exportCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildHistoryCommand() *cobra.Command {
+	historyCommand := &cobra.Command{
+		Use:   historyUsage,
+		Short: historyUsageDesc,
+		Long:  historyUsageDesc,
+	}
+
+	historyExportCommand := builder.buildHistoryExportCommand()
+	historyCommand.AddCommand(historyExportCommand)
+
+	historyImportCommand := builder.buildHistoryImportCommand()
+	historyCommand.AddCommand(historyImportCommand)
+
+	return historyCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildHistoryExportCommand() *cobra.Command {
+	historyExportCommand := &cobra.Command{
+		Use:   historyExportUsage,
+		Short: historyExportUsageDesc,
+		Long:  historyExportUsageDesc,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			var w io.Writer = os.Stdout
+
+			if builder.historyOutPtr != "" {
+				f, err := os.Create(builder.historyOutPtr)
+				if err != nil {
+					builder.migrator.logger.Fatal(err.Error())
+				}
+				defer f.Close()
+				w = f
+			}
+
+			if err := builder.migrator.ExportHistory(w, builder.historyFormatPtr); err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+		},
+	}
+
+	historyExportCommand.Flags().StringVar(&builder.historyFormatPtr, "format", historyFormatJSON, "The export format: json or csv")
+	historyExportCommand.Flags().StringVar(&builder.historyOutPtr, "out", "", "Write the export to this file instead of stdout")
+
+	return historyExportCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildHistoryImportCommand() *cobra.Command {
+	historyImportCommand := &cobra.Command{
+		Use:   historyImportUsage,
+		Short: historyImportUsageDesc,
+		Long:  historyImportUsageDesc,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			if len(args) == 0 {
+				builder.migrator.logger.Fatal("please specify the state file")
+			}
+
+			f, err := os.Open(args[0])
+			if err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+			defer f.Close()
+
+			if err = builder.migrator.RestoreHistory(f, builder.historyReplacePtr); err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+		},
+	}
+
+	historyImportCommand.Flags().BoolVar(&builder.historyReplacePtr, "replace", false, "Overwrite an existing history")
+
+	return historyImportCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildLintCommand() *cobra.Command {
+	lintCommand := &cobra.Command{
+		Use:   lintUsage,
+		Short: lintUsageDesc,
+		Long:  lintUsageDesc,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer builder.closeMigrator()
+			builder.setupMigrator()
+
+			versions := make([]uint, 0, len(args))
+			for _, arg := range args {
+				version, err := builder.migrator.ResolveVersion(arg)
+				if err != nil {
+					builder.migrator.logger.Fatal(err.Error())
+				}
+				versions = append(versions, version)
+			}
+
+			lints, err := builder.migrator.LintDowns(versions...)
+			if err != nil {
+				builder.migrator.logger.Fatal(err.Error())
+			}
+
+			for _, lint := range lints {
+				problem := "empty down migration"
+				if lint.Missing {
+					problem = "missing down migration"
+				}
+				fmt.Printf("%d %s: %s\n", lint.Version, lint.Name, problem)
+
+				if builder.suggestDownPtr {
+					fmt.Printf("-- ==> suggested down migration\n%s\n", lint.Suggested)
+				}
+			}
+
+			if len(lints) > 0 {
+				builder.migrator.logger.Fatal("migrations can't be rolled back", "count", len(lints))
+			}
+		},
+	}
+
+	lintCommand.Flags().BoolVar(&builder.suggestDownPtr, "suggest-down", false, "Print the down migration derived from the up migration of the migrations listed")
+
+	return lintCommand
+}
+
+func (builder *migratorCobraCommandBuilder) buildAboutCommand() *cobra.Command {
+	aboutCommand := &cobra.Command{
+		Use:   aboutUsage,
+		Short: aboutUsageDesc,
+		Long:  aboutUsageDesc,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			about := BuildAbout()
+
+			if builder.aboutJSONPtr {
+				if err := json.NewEncoder(os.Stdout).Encode(about); err != nil {
+					builder.migrator.logger.Fatal(err.Error())
+				}
+				return
+			}
+
+			gitCommit := about.GitCommit
+			if gitCommit == "" {
+				gitCommit = "unknown"
+			}
+
+			fmt.Printf("file-migrator:  %s\n", about.Version)
+			fmt.Printf("golang-migrate: %s\n", about.MigrateVersion)
+			fmt.Printf("go:             %s\n", about.GoVersion)
+			fmt.Printf("git commit:     %s\n", gitCommit)
+			fmt.Printf("drivers:        %s\n", strings.Join(about.Drivers, ", "))
+			fmt.Printf("sql drivers:    %s\n", strings.Join(about.SQLDrivers, ", "))
+		},
+	}
+
+	aboutCommand.Flags().BoolVar(&builder.aboutJSONPtr, "json", false, "Print the build information as json")
+
+	return aboutCommand
+}
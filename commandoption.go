@@ -0,0 +1,134 @@
+package migrator
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"strings"
+)
+
+// CommandOption customizes the command returned by CobraCommand.
+type CommandOption func(*migratorCobraCommandBuilder)
+
+// RunHook runs before or after every subcommand, see WithPreRun and WithPostRun.
+type RunHook func(cmd *cobra.Command, args []string) error
+
+// WithPreRun runs hook before every subcommand, such as to load a config or check a feature flag,
+// failing the command when it returns an error.
+func WithPreRun(hook RunHook) CommandOption {
+	return func(builder *migratorCobraCommandBuilder) {
+		builder.preRuns = append(builder.preRuns, hook)
+	}
+}
+
+// WithPostRun runs hook after every subcommand that succeeded, once the migrator is closed.
+func WithPostRun(hook RunHook) CommandOption {
+	return func(builder *migratorCobraCommandBuilder) {
+		builder.postRuns = append(builder.postRuns, hook)
+	}
+}
+
+func (builder *migratorCobraCommandBuilder) runHooks(hooks []RunHook, cmd *cobra.Command, args []string) {
+	for _, hook := range hooks {
+		if err := hook(cmd, args); err != nil {
+			builder.migrator.logger.Fatal(err.Error())
+		}
+	}
+}
+
+// WithUse replaces the usage line of the root command, "migrate COMMAND", whose first word is the
+// name the subcommands are run under.
+func WithUse(use string) CommandOption {
+	return func(builder *migratorCobraCommandBuilder) {
+		builder.use = use
+	}
+}
+
+// WithoutCommands removes subcommands, such as drop from production builds. Nested subcommands are
+// named by their path below the root command, such as "history import".
+func WithoutCommands(names ...string) CommandOption {
+	return func(builder *migratorCobraCommandBuilder) {
+		builder.disabled = append(builder.disabled, names...)
+	}
+}
+
+// WithCommands adds subcommands of the project to the root command.
+func WithCommands(commands ...*cobra.Command) CommandOption {
+	return func(builder *migratorCobraCommandBuilder) {
+		builder.extraCommands = append(builder.extraCommands, commands...)
+	}
+}
+
+// WithFlagDefault changes the default value of the persistent flag name, or of the flags of the
+// subcommands named name, such as "lock-timeout" or "dry-run".
+func WithFlagDefault(name, value string) CommandOption {
+	return func(builder *migratorCobraCommandBuilder) {
+		if builder.flagDefaults == nil {
+			builder.flagDefaults = make(map[string]string)
+		}
+		builder.flagDefaults[name] = value
+	}
+}
+
+// WithFlagPrefix prefixes the persistent flags, such as --verbose or --path, with prefix, such as
+// "migrate-" for --migrate-verbose, so they don't collide with the flags of the root command of an
+// application the command is added to. The flags of the subcommands keep their names.
+func WithFlagPrefix(prefix string) CommandOption {
+	return func(builder *migratorCobraCommandBuilder) {
+		builder.flagPrefix = prefix
+	}
+}
+
+// flagName returns the name of the persistent flag name, see WithFlagPrefix.
+func (builder *migratorCobraCommandBuilder) flagName(name string) string {
+	return builder.flagPrefix + name
+}
+
+// customize applies the CommandOption changing the built root command.
+func (builder *migratorCobraCommandBuilder) customize(root *cobra.Command) {
+	if builder.use != "" {
+		root.Use = builder.use
+	}
+
+	for _, name := range builder.disabled {
+		command, _, err := root.Find(strings.Fields(name))
+		if err != nil || command == root || command.CommandPath() != root.Name()+" "+name {
+			builder.migrator.logger.Error("can't remove unknown command", "command", name)
+			continue
+		}
+		command.Parent().RemoveCommand(command)
+	}
+
+	root.AddCommand(builder.extraCommands...)
+
+	for name, value := range builder.flagDefaults {
+		flags := make([]*pflag.Flag, 0)
+		if flag := root.PersistentFlags().Lookup(builder.flagName(name)); flag != nil {
+			flags = append(flags, flag)
+		}
+
+		var visit func(command *cobra.Command)
+		visit = func(command *cobra.Command) {
+			if flag := command.Flags().Lookup(name); flag != nil {
+				flags = append(flags, flag)
+			}
+			for _, child := range command.Commands() {
+				visit(child)
+			}
+		}
+		for _, command := range root.Commands() {
+			visit(command)
+		}
+
+		if len(flags) == 0 {
+			builder.migrator.logger.Error("can't change the default of unknown flag", "flag", name)
+		}
+
+		for _, flag := range flags {
+			if err := flag.Value.Set(value); err != nil {
+				builder.migrator.logger.Error("can't change the default of flag", "flag", name, "value", value, "error", err)
+				continue
+			}
+			flag.DefValue = value
+		}
+	}
+}
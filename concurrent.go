@@ -0,0 +1,132 @@
+package migrator
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const concurrentIndexAttempts = 2
+
+var (
+	indexStatementRegexp   = regexp.MustCompile(`(?i)^(\s*(?:CREATE\s+(?:UNIQUE\s+)?|DROP\s+)INDEX\s+)(CONCURRENTLY\s+)?`)
+	concurrentIndexRegexp  = regexp.MustCompile(`(?is)^CREATE\s+(?:UNIQUE\s+)?INDEX\s+CONCURRENTLY\s+(?:IF\s+NOT\s+EXISTS\s+)?("[^"]+"|[\w.]+)\s+ON\s`)
+	noTransactionDirective = directivePrefix + "no-transaction\n"
+)
+
+// EnableConcurrentIndexes makes generated postgres migrations create and drop indexes CONCURRENTLY,
+// marking them -- migrator:no-transaction so that their statements run one by one.
+func (m *Migrator) EnableConcurrentIndexes() {
+	m.concurrentIndexes = true
+}
+
+func WithConcurrentIndexes() Option {
+	return func(m *Migrator) {
+		m.concurrentIndexes = true
+	}
+}
+
+// concurrentIndexSQL rewrites the CREATE INDEX and DROP INDEX statements of sqlMap to run
+// CONCURRENTLY, reporting whether any was.
+func concurrentIndexSQL(sqlMap map[string][]string) (map[string][]string, bool) {
+	rewritten := make(map[string][]string, len(sqlMap))
+	changed := false
+
+	for table, statements := range sqlMap {
+		rewritten[table] = make([]string, len(statements))
+		for i, statement := range statements {
+			if match := indexStatementRegexp.FindStringSubmatch(statement); match != nil && match[2] == "" {
+				statement = match[1] + "CONCURRENTLY " + statement[len(match[0]):]
+				changed = true
+			}
+			rewritten[table][i] = statement
+		}
+	}
+	return rewritten, changed
+}
+
+// renderMigrationSQL renders the statements of a generated migration.
+func (m *Migrator) renderMigrationSQL(sqlMap map[string][]string, tableNames []string) []byte {
+	if !m.concurrentIndexes || m.dialect != DialectPostgres {
+		return renderSQL(sqlMap, tableNames, m.statementTerminator())
+	}
+
+	sqlMap, changed := concurrentIndexSQL(sqlMap)
+	body := renderSQL(sqlMap, tableNames, m.statementTerminator())
+	if changed {
+		body = append([]byte(noTransactionDirective), body...)
+	}
+	return body
+}
+
+// runNoTransaction runs the statements of a `-- migrator:no-transaction` migration one by one, as
+// postgres runs the statements of a single query in one transaction, where CREATE INDEX CONCURRENTLY
+// isn't allowed.
+func (d *databaseDriver) runNoTransaction(body []byte) error {
+	for _, statement := range splitStatements(string(body), d.migrator.statementTerminator()) {
+		if strings.TrimSpace(stripComments(statement)) == "" {
+			continue
+		}
+
+		var err error
+		if match := concurrentIndexRegexp.FindStringSubmatch(stripComments(statement)); match != nil {
+			err = d.runConcurrentIndex(statement, strings.Trim(match[1], `"`))
+		} else {
+			err = d.Driver.Run(strings.NewReader(statement))
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runConcurrentIndex runs a CREATE INDEX CONCURRENTLY, dropping the invalid index a failed build
+// leaves behind before retrying, as it would otherwise make the retry fail or be skipped.
+func (d *databaseDriver) runConcurrentIndex(statement string, name string) error {
+	var err error
+	for attempt := 1; attempt <= concurrentIndexAttempts; attempt++ {
+		if err = d.dropInvalidIndex(name); err != nil {
+			return err
+		}
+
+		if err = d.Driver.Run(strings.NewReader(statement)); err == nil {
+			return nil
+		}
+		d.migrator.logger.Error("concurrent index build failed", "index", name, "attempt", attempt, "error", err)
+	}
+
+	if cleanupErr := d.dropInvalidIndex(name); cleanupErr != nil {
+		return errors.Join(err, cleanupErr)
+	}
+	return err
+}
+
+func (d *databaseDriver) dropInvalidIndex(name string) error {
+	db := d.migrator.db
+	if db == nil || d.migrator.dialect != DialectPostgres {
+		return nil
+	}
+
+	if _, indexName, ok := strings.Cut(name, "."); ok {
+		name = indexName
+	}
+
+	var valid bool
+	err := db.QueryRow(`SELECT i.indisvalid FROM pg_index i JOIN pg_class c ON c.oid = i.indexrelid
+WHERE c.relname = $1 AND pg_table_is_visible(c.oid)`, name).Scan(&valid)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && valid) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	d.migrator.logger.Info("dropping invalid index left by a failed concurrent build", "index", name)
+	_, err = db.Exec(fmt.Sprintf("DROP INDEX CONCURRENTLY IF EXISTS %s", DialectPostgres.quote(name)))
+	return err
+}
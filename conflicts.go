@@ -0,0 +1,145 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4/source"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	ConflictCollision = "collision"
+	ConflictOrdering  = "ordering"
+)
+
+var errConflicts = errors.New("migrations conflict with the base branch")
+
+type MigrationConflict struct {
+	File    string
+	Version uint
+	Kind    string
+	With    string
+}
+
+func (c *MigrationConflict) String() string {
+	if c.Kind == ConflictCollision {
+		return fmt.Sprintf("%s: version %d is already used by %s", c.File, c.Version, c.With)
+	}
+	return fmt.Sprintf("%s: version %d is older than %s merged on the base branch", c.File, c.Version, c.With)
+}
+
+func git(dir string, args ...string) ([]string, error) {
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
+	}
+
+	lines := make([]string, 0)
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// parsedMigrations keeps the versioned migration files among names, ignoring files in subdirectories.
+func parsedMigrations(names []string) map[string]*source.Migration {
+	migrations := make(map[string]*source.Migration)
+
+	for _, name := range names {
+		if strings.Contains(name, "/") {
+			continue
+		}
+
+		if migration, err := source.DefaultParse(name); err == nil {
+			migrations[name] = migration
+		}
+	}
+	return migrations
+}
+
+// branchMigrations returns the migration files added since the branch left base, including
+// uncommitted and untracked ones, and the migration files present on base.
+func (m *Migrator) branchMigrations(base string) (added map[string]*source.Migration, merged map[string]*source.Migration, err error) {
+	dir := m.migrationsFilePath
+
+	mergeBase, err := git(dir, "merge-base", "HEAD", base)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(mergeBase) == 0 {
+		return nil, nil, fmt.Errorf("no common ancestor with %s", base)
+	}
+
+	changed, err := git(dir, "diff", "--relative", "--name-only", "--diff-filter=A", mergeBase[0], "--", ".")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	untracked, err := git(dir, "ls-files", "--others", "--exclude-standard", "--", ".")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	onBase, err := git(dir, "ls-tree", "--name-only", base, "--", ".")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return parsedMigrations(append(changed, untracked...)), parsedMigrations(onBase), nil
+}
+
+// Conflicts compares the migrations added on the current git branch with those merged on base,
+// reporting versions already used on base and versions older than the newest migration of base.
+func (m *Migrator) Conflicts(base string) ([]*MigrationConflict, error) {
+	added, merged, err := m.branchMigrations(base)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint]string)
+	var newest *source.Migration
+	var newestName string
+
+	for name, migration := range merged {
+		if _, ok := added[name]; ok {
+			continue
+		}
+
+		byVersion[migration.Version] = name
+		if newest == nil || migration.Version > newest.Version {
+			newest, newestName = migration, name
+		}
+	}
+
+	conflicts := make([]*MigrationConflict, 0)
+	for name, migration := range added {
+		if other, ok := byVersion[migration.Version]; ok {
+			conflicts = append(conflicts, &MigrationConflict{
+				File: filepath.Join(m.migrationsFilePath, name), Version: migration.Version, Kind: ConflictCollision, With: other,
+			})
+			continue
+		}
+
+		if newest != nil && migration.Version < newest.Version {
+			conflicts = append(conflicts, &MigrationConflict{
+				File: filepath.Join(m.migrationsFilePath, name), Version: migration.Version, Kind: ConflictOrdering, With: newestName,
+			})
+		}
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].File < conflicts[j].File
+	})
+
+	return conflicts, nil
+}
@@ -0,0 +1,249 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	errConnectTimeoutDialect = errors.New("connect timeout is only supported on postgres and mysql")
+	errKeepAliveDialect      = errors.New("keepalive is only supported on postgres and mysql")
+)
+
+// ConnectionPool sizes the database/sql pools the migrator opens itself: by OpenDB, and for shadow
+// databases and schema diffs, a zero field keeping the database/sql default. The connection
+// golang-migrate runs the migrations on isn't pooled by these settings.
+type ConnectionPool struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// connectTimeoutParams returns the driver url params giving up connecting after timeout.
+func connectTimeoutParams(dialect Dialect, timeout time.Duration) (map[string]string, error) {
+	switch dialect {
+	case DialectPostgres:
+		// connect_timeout is in seconds, and at least 1
+		seconds := max(1, int64((timeout+time.Second-1)/time.Second))
+		return map[string]string{"connect_timeout": fmt.Sprint(seconds)}, nil
+	case DialectMySQL:
+		return map[string]string{"timeout": timeout.String()}, nil
+	default:
+		return nil, errConnectTimeoutDialect
+	}
+}
+
+func (pool ConnectionPool) apply(db *sql.DB) {
+	if pool.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(pool.MaxOpenConns)
+	}
+	if pool.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(pool.MaxIdleConns)
+	}
+	if pool.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	}
+	if pool.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+	}
+}
+
+// WithConnectionPool see SetConnectionPool.
+func WithConnectionPool(pool ConnectionPool) Option {
+	return func(m *Migrator) {
+		m.pool = pool
+	}
+}
+
+// SetConnectionPool sizes the pools of the databases the migrator opens: by OpenDB, and for
+// shadow databases and schema diffs. The driver golang-migrate runs the migrations with keeps its
+// own connection.
+func (m *Migrator) SetConnectionPool(pool ConnectionPool) {
+	m.pool = pool
+	if m.ownsDB {
+		pool.apply(m.db)
+	}
+}
+
+// WithConnectTimeout see SetConnectTimeout, for a migrator created with NewFromURL.
+func WithConnectTimeout(timeout time.Duration) Option {
+	return func(m *Migrator) {
+		driverParams, err := connectTimeoutParams(m.dialect, timeout)
+		if err != nil {
+			m.logger.Error("can't set connect timeout", "error", err)
+			return
+		}
+
+		m.connectTimeout = timeout
+		m.driverParams = mergeParams(m.driverParams, driverParams)
+	}
+}
+
+// WithKeepAlive see SetKeepAlive, for a migrator created with NewFromURL.
+func WithKeepAlive(period time.Duration) Option {
+	return func(m *Migrator) {
+		if m.dialect != DialectPostgres && m.dialect != DialectMySQL {
+			m.logger.Error("can't set keepalive", "error", errKeepAliveDialect)
+			return
+		}
+		m.keepAlive = period
+	}
+}
+
+// SetKeepAlive sends TCP keepalives every period on the connections the migrator dials itself, so
+// that firewalls and load balancers don't drop them during long migrations or while waiting for a
+// lock. It reopens the database opened by OpenDB, and applies to the databases opened later for
+// shadow databases and schema diffs. On mysql it reopens the database driver too, so it applies to
+// the connection golang-migrate runs the migrations on; on postgres that connection keeps the
+// keepalive of lib/pq, every 15s.
+func (m *Migrator) SetKeepAlive(period time.Duration) error {
+	if m.dialect != DialectPostgres && m.dialect != DialectMySQL {
+		return errKeepAliveDialect
+	}
+
+	previousKeepAlive := m.keepAlive
+	m.keepAlive = period
+
+	if m.dialect == DialectMySQL && m.databaseURL != "" {
+		if err := m.reopenDriver(); err != nil {
+			m.keepAlive = previousKeepAlive
+			return err
+		}
+	}
+
+	if !m.ownsDB {
+		return nil
+	}
+
+	previous := m.db
+	if err := m.OpenDB(); err != nil {
+		return err
+	}
+	return previous.Close()
+}
+
+// keepAliveDialer dials postgres with a keepalive period, as pq.Dialer.
+type keepAliveDialer struct {
+	*net.Dialer
+}
+
+func (d keepAliveDialer) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+	dialer := *d.Dialer
+	dialer.Timeout = timeout
+	return dialer.Dial(network, address)
+}
+
+// keepAliveConnector opens lib/pq connections with keepAliveDialer.
+type keepAliveConnector struct {
+	dsn    string
+	dialer keepAliveDialer
+}
+
+func (c keepAliveConnector) Connect(context.Context) (driver.Conn, error) {
+	return pq.DialOpen(c.dialer, c.dsn)
+}
+
+func (c keepAliveConnector) Driver() driver.Driver {
+	return &pq.Driver{}
+}
+
+// keepAliveDSN makes the mysql dsn, or golang-migrate url, dial its tcp address through a network
+// registered with the driver sending keepalives every period.
+func keepAliveDSN(dsn string, period time.Duration) string {
+	before, rest, ok := strings.Cut(dsn, "@tcp(")
+	if !ok {
+		return dsn
+	}
+
+	address, after, ok := strings.Cut(rest, ")")
+	if !ok {
+		return dsn
+	}
+
+	// the driver only adds the default port to tcp addresses
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(strings.Trim(address, "[]"), "3306")
+	}
+
+	network := "keepalive-" + period.String()
+	mysql.RegisterDialContext(network, func(ctx context.Context, address string) (net.Conn, error) {
+		dialer := net.Dialer{KeepAlive: period}
+		return dialer.DialContext(ctx, "tcp", address)
+	})
+	return before + "@" + network + "(" + address + ")" + after
+}
+
+// SetConnectTimeout reopens the database driver, and the database opened by OpenDB, so that
+// connecting gives up after timeout: connect_timeout on postgres, rounded up to the second, and
+// timeout on mysql. The databases opened later for shadow databases and schema diffs use it too.
+func (m *Migrator) SetConnectTimeout(timeout time.Duration) error {
+	driverParams, err := connectTimeoutParams(m.dialect, timeout)
+	if err != nil {
+		return err
+	}
+
+	if err = m.setSessionParams(driverParams, nil); err != nil {
+		return err
+	}
+	m.connectTimeout = timeout
+
+	if !m.ownsDB {
+		return nil
+	}
+
+	previous := m.db
+	if err = m.OpenDB(); err != nil {
+		return err
+	}
+	return previous.Close()
+}
+
+// sqlOpen opens the golang-migrate url databaseURL with database/sql, with the connect timeout,
+// the keepalive and the pool of the migrator.
+func (m *Migrator) sqlOpen(databaseURL string) (*sql.DB, error) {
+	driverName, dsn, err := sqlOpenArgs(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.connectTimeout > 0 {
+		scheme, _, _ := strings.Cut(databaseURL, "://")
+		params, err := connectTimeoutParams(dialectOf(scheme), m.connectTimeout)
+		if err == nil {
+			separator := "?"
+			if strings.Contains(dsn, "?") {
+				separator = "&"
+			}
+			for key, value := range params {
+				dsn += separator + key + "=" + url.QueryEscape(value)
+				separator = "&"
+			}
+		}
+	}
+
+	var db *sql.DB
+	switch {
+	case m.keepAlive > 0 && driverName == "postgres":
+		db = sql.OpenDB(keepAliveConnector{dsn: dsn, dialer: keepAliveDialer{&net.Dialer{KeepAlive: m.keepAlive}}})
+	case m.keepAlive > 0 && driverName == "mysql":
+		db, err = sql.Open(driverName, keepAliveDSN(dsn, m.keepAlive))
+	default:
+		db, err = sql.Open(driverName, dsn)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m.pool.apply(db)
+	return db, nil
+}
@@ -0,0 +1,117 @@
+package migrator
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4"
+	"path/filepath"
+	"time"
+)
+
+type Diagnosis struct {
+	Dirty       bool               `json:"dirty"`
+	Version     uint               `json:"version,omitempty"`
+	Direction   string             `json:"direction,omitempty"`
+	File        string             `json:"file,omitempty"`
+	LastError   string             `json:"last_error,omitempty"`
+	FailedAt    *time.Time         `json:"failed_at,omitempty"`
+	Progress    *StatementProgress `json:"progress,omitempty"`
+	Suggestions []string           `json:"suggestions,omitempty"`
+}
+
+// lastFailure returns the last failed migration of the history, nil when there is none.
+func (m *Migrator) lastFailure() *HistoryEntry {
+	entries, err := m.History()
+	if err != nil {
+		return nil
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !entries[i].Success {
+			return entries[i]
+		}
+	}
+	return nil
+}
+
+// lastAuditError returns the error of the last failed command of the audit table, if any.
+func (m *Migrator) lastAuditError() (string, *time.Time) {
+	var (
+		errText    sql.NullString
+		finishedAt time.Time
+	)
+
+	err := m.db.QueryRow(fmt.Sprintf("SELECT error, finished_at FROM %s WHERE success = %s ORDER BY finished_at DESC LIMIT 1",
+		auditTable, m.dialect.placeholder(1)), false).Scan(&errText, &finishedAt)
+	if err != nil {
+		return "", nil
+	}
+	return errText.String, &finishedAt
+}
+
+// Diagnose explains a dirty database: the migration that failed, its file, the last error found in
+// the history or the audit table, and the commands that can get the database out of it.
+func (m *Migrator) Diagnose() (*Diagnosis, error) {
+	version, dirty, err := m.migrate.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return &Diagnosis{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	diagnosis := &Diagnosis{Dirty: dirty, Version: version}
+	if !dirty {
+		return diagnosis, nil
+	}
+
+	// a failed down migration leaves the version it was going to dirty, the history tells which one failed
+	failedVersion, direction := version, directionUp
+	var failure *HistoryEntry
+	if m.db != nil && m.historyEnabled {
+		failure = m.lastFailure()
+	}
+
+	if failure != nil {
+		failedVersion, direction = failure.Version, failure.Direction
+		diagnosis.LastError = failure.Error
+		failedAt := failure.AppliedAt
+		diagnosis.FailedAt = &failedAt
+	} else if m.db != nil && m.auditEnabled {
+		diagnosis.LastError, diagnosis.FailedAt = m.lastAuditError()
+	}
+
+	if m.db != nil && m.statementTracking {
+		if diagnosis.Progress, err = m.StatementProgress(version); err != nil {
+			return nil, err
+		}
+	}
+	diagnosis.Direction = direction
+
+	migration, ok := m.source.migrations.Up(failedVersion)
+	if direction == directionDown {
+		migration, ok = m.source.migrations.Down(failedVersion)
+	}
+	if ok && migration.Raw != "" {
+		diagnosis.File = filepath.Join(m.migrationsFilePath, migration.Raw)
+	}
+
+	previous := "-1"
+	if prev, ok := m.source.migrations.Prev(version); ok {
+		previous = fmt.Sprint(prev)
+	}
+
+	if diagnosis.Progress != nil {
+		diagnosis.Suggestions = append(diagnosis.Suggestions,
+			fmt.Sprintf("migrate resume  # runs the %d statements after the %d committed ones, once the cause is fixed",
+				diagnosis.Progress.Total-diagnosis.Progress.Committed, diagnosis.Progress.Committed))
+	}
+	diagnosis.Suggestions = append(diagnosis.Suggestions,
+		fmt.Sprintf("migrate show %d %s  # review what ran", failedVersion, direction),
+		fmt.Sprintf("migrate force %d  # once the changes of the migration are completed by hand", version),
+		fmt.Sprintf("migrate force %s && migrate up  # once its partial changes are reverted by hand, to run it again", previous),
+	)
+
+	return diagnosis, nil
+}
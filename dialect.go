@@ -0,0 +1,44 @@
+package migrator
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Dialect string
+
+const (
+	DialectPostgres Dialect = "postgres"
+	DialectMySQL    Dialect = "mysql"
+	DialectSQLite   Dialect = "sqlite"
+	DialectUnknown  Dialect = ""
+)
+
+func dialectOf(databaseName string) Dialect {
+	name := strings.ToLower(databaseName)
+
+	switch {
+	case strings.HasPrefix(name, "postgres"), strings.HasPrefix(name, "pgx"), strings.HasPrefix(name, "cockroach"), strings.HasPrefix(name, "redshift"):
+		return DialectPostgres
+	case strings.HasPrefix(name, "mysql"), strings.HasPrefix(name, "mariadb"):
+		return DialectMySQL
+	case strings.HasPrefix(name, "sqlite"):
+		return DialectSQLite
+	default:
+		return DialectUnknown
+	}
+}
+
+func (d Dialect) quote(identifier string) string {
+	if d == DialectMySQL {
+		return "`" + strings.ReplaceAll(identifier, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(identifier, `"`, `""`) + `"`
+}
+
+func (d Dialect) placeholder(n int) string {
+	if d == DialectPostgres {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
@@ -0,0 +1,38 @@
+package migrator
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+const directivePrefix = "-- migrator:"
+
+// directive returns the arguments of the first `-- migrator:name` line of body.
+func directive(body []byte, name string) (string, bool) {
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		rest, ok := strings.CutPrefix(line, directivePrefix+name)
+		if ok && (rest == "" || rest[0] == ' ' || rest[0] == '\t') {
+			return strings.TrimSpace(rest), true
+		}
+	}
+	return "", false
+}
+
+// stripComments removes the comment lines leading a statement.
+func stripComments(statement string) string {
+	for {
+		statement = strings.TrimLeft(statement, " \t\r\n")
+		if !strings.HasPrefix(statement, "--") {
+			return statement
+		}
+
+		_, rest, ok := strings.Cut(statement, "\n")
+		if !ok {
+			return ""
+		}
+		statement = rest
+	}
+}
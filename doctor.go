@@ -0,0 +1,167 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+const doctorTable = "schema_migrations_doctor"
+
+type DoctorResult string
+
+const (
+	DoctorPass DoctorResult = "PASS"
+	DoctorFail DoctorResult = "FAIL"
+	DoctorSkip DoctorResult = "SKIP"
+)
+
+type DoctorCheck struct {
+	Name   string       `json:"name"`
+	Result DoctorResult `json:"result"`
+	Detail string       `json:"detail,omitempty"`
+}
+
+func doctorCheck(name string, err error, detail string) *DoctorCheck {
+	if err != nil {
+		return &DoctorCheck{Name: name, Result: DoctorFail, Detail: err.Error()}
+	}
+	return &DoctorCheck{Name: name, Result: DoctorPass, Detail: detail}
+}
+
+// Doctor runs the preflight checks of a migration run: connectivity, migrations directory, version
+// table, dirty state, lock acquisition and the CREATE TABLE privilege. It changes nothing, the
+// privilege check creating a scratch table it rolls back or drops. A read-only migrator skips the
+// lock and privilege checks, which write to the database.
+func (m *Migrator) Doctor() []*DoctorCheck {
+	checks := make([]*DoctorCheck, 0, 6)
+
+	if m.db == nil {
+		checks = append(checks, &DoctorCheck{Name: "connectivity", Result: DoctorSkip, Detail: errNoDB.Error()})
+	} else {
+		checks = append(checks, doctorCheck("connectivity", m.db.Ping(), ""))
+	}
+
+	count, err := m.checkMigrationsDirectory()
+	checks = append(checks, doctorCheck("migrations directory", err, fmt.Sprintf("%d migrations in %s", count, m.migrationsFilePath)))
+
+	version, dirty, err := m.migrate.Version()
+	detail := fmt.Sprintf("version %d", version)
+	if errors.Is(err, migrate.ErrNilVersion) {
+		err, detail = nil, "no migration applied yet"
+	}
+	checks = append(checks, doctorCheck("version table", err, detail))
+
+	if err == nil && dirty {
+		err = migrate.ErrDirty{Version: int(version)}
+	}
+	checks = append(checks, doctorCheck("clean state", err, ""))
+
+	if m.readOnly {
+		checks = append(checks, &DoctorCheck{Name: "lock acquisition", Result: DoctorSkip, Detail: errReadOnly.Error()})
+	} else {
+		err = m.driver.Lock()
+		if err == nil {
+			err = m.driver.Unlock()
+		}
+		checks = append(checks, doctorCheck("lock acquisition", err, ""))
+	}
+
+	if m.readOnly {
+		checks = append(checks, &DoctorCheck{Name: "create table privilege", Result: DoctorSkip, Detail: errReadOnly.Error()})
+	} else if m.db == nil || m.dialect == DialectUnknown {
+		checks = append(checks, &DoctorCheck{Name: "create table privilege", Result: DoctorSkip, Detail: "needs SetDB and a known dialect"})
+	} else {
+		checks = append(checks, doctorCheck("create table privilege", m.checkCreateTable(), ""))
+	}
+
+	return checks
+}
+
+// checkMigrationsDirectory reads every migration of the source, checking each version has an up
+// migration and the files match the manifest when there is one.
+func (m *Migrator) checkMigrationsDirectory() (int, error) {
+	versions := m.source.versions()
+	problems := make([]string, 0)
+
+	for _, version := range versions {
+		up, ok := m.source.migrations.Up(version)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("version %d has no up migration", version))
+			continue
+		}
+
+		migrations := []*source.Migration{up}
+		if down, ok := m.source.migrations.Down(version); ok {
+			migrations = append(migrations, down)
+		}
+
+		for _, migration := range migrations {
+			body, _, err := m.source.read(migration)
+			if err == nil {
+				_, err = io.Copy(io.Discard, body)
+				_ = body.Close()
+			}
+
+			if err != nil {
+				problems = append(problems, err.Error())
+			}
+		}
+	}
+
+	if _, err := fs.Stat(m.source.fsys, manifestFile); err == nil {
+		if err = m.VerifyManifest(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return len(versions), errors.New(strings.Join(problems, ", "))
+	}
+	return len(versions), nil
+}
+
+// checkCreateTable creates a scratch table: in a transaction rolled back on postgres, where DDL is
+// transactional, and otherwise with IF NOT EXISTS, so that a table left by an interrupted check
+// doesn't fail it, then dropped.
+func (m *Migrator) checkCreateTable() error {
+	table := m.dialect.quote(doctorTable)
+
+	if m.dialect == DialectPostgres {
+		tx, err := m.db.Begin()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		_, err = tx.Exec(fmt.Sprintf("CREATE TABLE %s (id INTEGER)", table))
+		return err
+	}
+
+	if _, err := m.db.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id INTEGER)", table)); err != nil {
+		return err
+	}
+
+	_, err := m.db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", table))
+	return err
+}
+
+// Ping checks the database answers and the migrations directory is readable, without changing anything.
+func (m *Migrator) Ping() error {
+	if m.db != nil {
+		if err := m.db.Ping(); err != nil {
+			return fmt.Errorf("database: %w", err)
+		}
+	} else if _, _, err := m.migrate.Version(); err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return fmt.Errorf("database: %w", err)
+	}
+
+	if _, err := fs.ReadDir(m.source.fsys, "."); err != nil {
+		return fmt.Errorf("migrations: %w", err)
+	}
+	return nil
+}
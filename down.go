@@ -0,0 +1,262 @@
+package migrator
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	createObjectRegexp = regexp.MustCompile(`(?is)^CREATE\s+(?:UNLOGGED\s+|TEMP(?:ORARY)?\s+)?(TABLE|VIEW|MATERIALIZED\s+VIEW|SEQUENCE|SCHEMA|TYPE)\s+(IF\s+NOT\s+EXISTS\s+)?` + identPattern)
+	createNamedIndex   = regexp.MustCompile(`(?is)^CREATE\s+(?:UNIQUE\s+)?INDEX\s+(CONCURRENTLY\s+)?(IF\s+NOT\s+EXISTS\s+)?` + identPattern + `\s+ON\s+(?:ONLY\s+)?` + identPattern)
+	addColumnAction    = regexp.MustCompile(`(?is)^ADD\s+(?:COLUMN\s+)?(IF\s+NOT\s+EXISTS\s+)?` + identPattern)
+	addConstraintRegex = regexp.MustCompile(`(?is)^ADD\s+CONSTRAINT\s+` + identPattern)
+	addIndexAction     = regexp.MustCompile(`(?is)^ADD\s+(?:UNIQUE\s+)?(?:INDEX|KEY)\s+` + identPattern)
+	renameColumnAction = regexp.MustCompile(`(?is)^RENAME\s+(?:COLUMN\s+)?` + identPattern + `\s+TO\s+` + identPattern + `$`)
+	renameTableAction  = regexp.MustCompile(`(?is)^RENAME\s+TO\s+` + identPattern + `$`)
+	notColumnRegexp    = regexp.MustCompile(`(?i)^(?:CONSTRAINT|PRIMARY|FOREIGN|UNIQUE|CHECK|INDEX|KEY|FULLTEXT|SPATIAL)$`)
+)
+
+// splitActions splits the actions of an ALTER TABLE on the commas outside of parentheses and quotes.
+func splitActions(actions string) []string {
+	var (
+		split   = make([]string, 0, 1)
+		depth   int
+		quote   rune
+		current strings.Builder
+	)
+
+	for _, r := range actions {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			split = append(split, strings.TrimSpace(current.String()))
+			current.Reset()
+			continue
+		}
+		current.WriteRune(r)
+	}
+	return append(split, strings.TrimSpace(current.String()))
+}
+
+// inverseAction returns the ALTER TABLE action undoing action.
+func inverseAction(action string) (string, bool) {
+	if match := addConstraintRegex.FindStringSubmatch(action); match != nil {
+		return "DROP CONSTRAINT " + match[1], true
+	}
+
+	if match := addIndexAction.FindStringSubmatch(action); match != nil {
+		return "DROP INDEX " + match[1], true
+	}
+
+	if match := addColumnAction.FindStringSubmatch(action); match != nil && !notColumnRegexp.MatchString(match[2]) {
+		if match[1] != "" {
+			return "DROP COLUMN IF EXISTS " + match[2], true
+		}
+		return "DROP COLUMN " + match[2], true
+	}
+
+	if match := renameColumnAction.FindStringSubmatch(action); match != nil {
+		return fmt.Sprintf("RENAME COLUMN %s TO %s", match[2], match[1]), true
+	}
+	return "", false
+}
+
+// inverseStatement returns the statement undoing statement on the dialect d, for the statements
+// creating objects, indexes and columns, and renaming tables and columns.
+func inverseStatement(d Dialect, statement string) (string, bool) {
+	statement = strings.TrimSpace(stripComments(statement))
+
+	if match := createObjectRegexp.FindStringSubmatch(statement); match != nil {
+		kind := strings.ToUpper(strings.Join(strings.Fields(match[1]), " "))
+		if match[2] != "" {
+			return fmt.Sprintf("DROP %s IF EXISTS %s", kind, match[3]), true
+		}
+		return fmt.Sprintf("DROP %s %s", kind, match[3]), true
+	}
+
+	if match := createNamedIndex.FindStringSubmatch(statement); match != nil {
+		drop := "DROP INDEX "
+		if match[1] != "" {
+			drop += "CONCURRENTLY "
+		}
+		if match[2] != "" && d != DialectMySQL {
+			drop += "IF EXISTS "
+		}
+		if d == DialectMySQL {
+			return drop + match[3] + " ON " + match[4], true
+		}
+		return drop + match[3], true
+	}
+
+	match := alterTableLockRegexp.FindStringSubmatch(statement)
+	if match == nil {
+		return "", false
+	}
+	table, actions := match[1], match[2]
+
+	if rename := renameTableAction.FindStringSubmatch(strings.TrimSpace(actions)); rename != nil {
+		return fmt.Sprintf("ALTER TABLE %s RENAME TO %s", rename[1], table), true
+	}
+
+	split := splitActions(actions)
+	inverses := make([]string, 0, len(split))
+	for i := len(split) - 1; i >= 0; i-- {
+		inverse, ok := inverseAction(split[i])
+		if !ok {
+			return "", false
+		}
+		inverses = append(inverses, inverse)
+	}
+	return fmt.Sprintf("ALTER TABLE %s %s", table, strings.Join(inverses, ", ")), true
+}
+
+// SuggestDown proposes the down migration of the up migration body on the dialect d: the inverse
+// of its statements in reverse order, the statements it can't invert being left as comments to
+// complete by hand. It returns the proposed body and those statements.
+func SuggestDown(d Dialect, up string, terminator string) (string, []string) {
+	statements := splitStatements(up, terminator)
+
+	var (
+		body     strings.Builder
+		unknowns = make([]string, 0)
+	)
+	for i := len(statements) - 1; i >= 0; i-- {
+		statement := strings.TrimSpace(stripComments(statements[i]))
+		if statement == "" || strings.HasPrefix(statement, directivePrefix) {
+			continue
+		}
+
+		inverse, ok := inverseStatement(d, statement)
+		if !ok {
+			unknowns = append(unknowns, statement)
+			body.WriteString("-- can't derive the inverse of:\n-- ")
+			body.WriteString(strings.ReplaceAll(statement, "\n", "\n-- "))
+			body.WriteString("\n")
+			continue
+		}
+
+		body.WriteString(inverse)
+		body.WriteString(terminator)
+		body.WriteString("\n")
+	}
+	return body.String(), unknowns
+}
+
+func (m *Migrator) generateFromSQL(up string,
+	timeZoneName string, format string, name string, ext string, seq bool, seqDigits int) (*generatedMigration, []string, error) {
+
+	upFile, downFile, err := m.upAndDownFilePath(timeZoneName, format, name, ext, seq, seqDigits)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	down, unknowns := SuggestDown(m.dialect, up, m.statementTerminator())
+	if !strings.HasSuffix(up, "\n") {
+		up += "\n"
+	}
+
+	return &generatedMigration{upFile: upFile, downFile: downFile, upSQL: []byte(up), downSQL: []byte(down)}, unknowns, nil
+}
+
+// MakeMigrateFromSQL creates the migration name with the handwritten up SQL read from r, and the
+// down migration SuggestDown proposes for it, logging the statements left to invert by hand.
+func (m *Migrator) MakeMigrateFromSQL(r io.Reader,
+	timeZoneName string, format string, name string, ext string, seq bool, seqDigits int) error {
+
+	up, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	generated, unknowns, err := m.generateFromSQL(string(up), timeZoneName, format, name, ext, seq, seqDigits)
+	if err != nil {
+		return err
+	}
+
+	if err = m.writeMigrate(generated); err != nil {
+		return err
+	}
+
+	if len(unknowns) > 0 {
+		m.logger.Error("complete the down migration by hand", "path", generated.downFile, "statements", len(unknowns))
+	}
+	return nil
+}
+
+func (m *Migrator) MakeMigrateFromSQLDryRun(w io.Writer, r io.Reader,
+	timeZoneName string, format string, name string, ext string, seq bool, seqDigits int) error {
+
+	up, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	generated, _, err := m.generateFromSQL(string(up), timeZoneName, format, name, ext, seq, seqDigits)
+	if err != nil {
+		return err
+	}
+	return m.printMigrate(w, generated)
+}
+
+// DownLint is a SQL migration whose down migration is missing or has no statement, with the down
+// migration SuggestDown proposes for it.
+type DownLint struct {
+	Version   uint   `json:"version"`
+	Name      string `json:"name"`
+	Missing   bool   `json:"missing"`
+	Suggested string `json:"suggested"`
+}
+
+// downIsEmpty tells whether a down migration has no statement but comments and directives.
+func downIsEmpty(body string, terminator string) bool {
+	for _, statement := range splitStatements(body, terminator) {
+		statement = strings.TrimSpace(stripComments(statement))
+		if statement != "" && !strings.HasPrefix(statement, directivePrefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// LintDowns returns the SQL migrations of versions, every one when empty, whose down migration is
+// missing or empty, with a suggested down migration.
+func (m *Migrator) LintDowns(versions ...uint) ([]*DownLint, error) {
+	if len(versions) == 0 {
+		versions = m.source.versions()
+	}
+
+	lints := make([]*DownLint, 0)
+	for _, version := range versions {
+		migration, ok := m.source.migrations.Up(version)
+		if !ok || migration.Raw == "" {
+			// down only and go migrations
+			continue
+		}
+
+		lint := &DownLint{Version: version, Name: migration.Raw}
+		if _, body, err := m.MigrationSQL(version, directionDown); err != nil {
+			lint.Missing = true
+		} else if !downIsEmpty(body, m.statementTerminator()) {
+			continue
+		}
+
+		_, up, err := m.MigrationSQL(version, directionUp)
+		if err != nil {
+			return nil, err
+		}
+		lint.Suggested, _ = SuggestDown(m.dialect, up, m.statementTerminator())
+		lints = append(lints, lint)
+	}
+	return lints, nil
+}
@@ -0,0 +1,72 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	DownCheckWarn  = "warn"
+	DownCheckBlock = "block"
+)
+
+var (
+	errMissingDown     = errors.New("pending migrations have no down migration to roll them back")
+	errDownCheckPolicy = errors.New("down check policy must be warn or block")
+)
+
+// SetDownCheck looks for the pending up migrations whose down migration is missing or empty before
+// up and apply, logging them with the warn policy, and failing the run with the block policy.
+func (m *Migrator) SetDownCheck(policy string) error {
+	if policy != DownCheckWarn && policy != DownCheckBlock {
+		return fmt.Errorf("%w: %s", errDownCheckPolicy, policy)
+	}
+
+	m.downCheck = policy
+	return nil
+}
+
+func WithDownCheck(policy string) Option {
+	return func(m *Migrator) {
+		if err := m.SetDownCheck(policy); err != nil {
+			m.logger.Error("can't set down check", "error", err)
+		}
+	}
+}
+
+// checkDowns logs the up migrations of plan without a down migration, failing with errMissingDown
+// with the block policy.
+func (m *Migrator) checkDowns(plan []*PlannedMigration) error {
+	if m.downCheck == "" {
+		return nil
+	}
+
+	versions := make([]uint, 0, len(plan))
+	for _, planned := range plan {
+		if planned.Direction == directionUp && !planned.Missing {
+			versions = append(versions, planned.Version)
+		}
+	}
+
+	if len(versions) == 0 {
+		return nil
+	}
+
+	lints, err := m.LintDowns(versions...)
+	if err != nil {
+		return err
+	}
+
+	for _, lint := range lints {
+		problem := "empty"
+		if lint.Missing {
+			problem = "missing"
+		}
+		m.logger.Error("pending migration has no down migration", "version", lint.Version, "name", lint.Name, "down", problem)
+	}
+
+	if len(lints) > 0 && m.downCheck == DownCheckBlock {
+		return fmt.Errorf("%w: %d", errMissingDown, len(lints))
+	}
+	return nil
+}
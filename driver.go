@@ -0,0 +1,176 @@
+package migrator
+
+import (
+	"bytes"
+	"github.com/golang-migrate/migrate/v4/database"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	directionUp   = "up"
+	directionDown = "down"
+)
+
+// databaseDriver wraps the user supplied driver so that the Migrator can take part in
+// running each migration.
+type databaseDriver struct {
+	database.Driver
+	migrator  *Migrator
+	running   *runningMigration
+	heartbeat *lockHeartbeat
+}
+
+type runningMigration struct {
+	version   uint
+	direction string
+	startedAt time.Time
+	body      []byte
+}
+
+func (d *databaseDriver) Run(migration io.Reader) error {
+	body, err := io.ReadAll(migration)
+	if err != nil {
+		return err
+	}
+
+	if d.running != nil {
+		d.running.body = body
+
+		if err = d.migrator.runCallback(callbackBeforeEach); err != nil {
+			d.finish(err)
+			return err
+		}
+	}
+
+	if version, direction, ok := parseGoMigrationMarker(body); ok {
+		err = d.migrator.runGoMigration(version, direction)
+	} else if tool, ok := directive(body, "osc"); ok {
+		err = d.runOSC(tool, body)
+	} else if args, ok := directive(body, "batch"); ok {
+		err = d.runBatch(args, body)
+	} else if _, ok := directive(body, "no-transaction"); ok {
+		err = d.runNoTransaction(body)
+	} else if d.migrator.savepoints {
+		err = d.runSavepoints(d.statements(body), 0)
+	} else if d.migrator.splitStatements {
+		err = d.runStatements(body)
+	} else {
+		err = d.Driver.Run(bytes.NewReader(body))
+	}
+
+	if err != nil {
+		d.recordStatementProgress(body, err)
+		d.finish(err)
+	}
+	return err
+}
+
+// statements splits body into its statements, leaving out empty ones.
+func (d *databaseDriver) statements(body []byte) []string {
+	statements := make([]string, 0)
+	for _, statement := range splitStatements(string(body), d.migrator.statementTerminator()) {
+		if strings.TrimSpace(stripComments(statement)) != "" {
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
+
+func (d *databaseDriver) runStatements(body []byte) error {
+	return d.runStatementsFrom(d.statements(body), 0)
+}
+
+// runStatementsFrom runs statements from the start-th one, each committing on its own.
+func (d *databaseDriver) runStatementsFrom(statements []string, start int) error {
+	for i := start; i < len(statements); i++ {
+		if err := d.exec(statements[i]); err != nil {
+			var version uint
+			if d.running != nil {
+				version = d.running.version
+			}
+			return &StatementError{Version: version, Index: i, Total: len(statements), Committed: i, Statement: statements[i], Err: err}
+		}
+	}
+	return nil
+}
+
+// SetVersion is called with dirty set before golang-migrate runs a migration, and with
+// dirty unset once it succeeded.
+func (d *databaseDriver) SetVersion(version int, dirty bool) error {
+	if dirty {
+		d.begin(version)
+	}
+
+	if err := d.Driver.SetVersion(version, dirty); err != nil {
+		d.running = nil
+		return err
+	}
+
+	if !dirty {
+		running := d.running
+		d.finish(nil)
+
+		if running == nil {
+			return nil
+		}
+
+		if running.direction == directionUp {
+			if err := d.migrator.runVersionHooks(running.version); err != nil {
+				return err
+			}
+		}
+		return d.migrator.runCallback(callbackAfterEach)
+	}
+	return nil
+}
+
+func (d *databaseDriver) begin(targetVersion int) {
+	currentVersion, _, err := d.Driver.Version()
+	if err != nil {
+		d.running = nil
+		return
+	}
+
+	running := &runningMigration{startedAt: time.Now()}
+
+	if targetVersion > currentVersion {
+		running.version = uint(targetVersion)
+		running.direction = directionUp
+	} else {
+		running.version = uint(currentVersion)
+		running.direction = directionDown
+	}
+
+	d.running = running
+}
+
+func (d *databaseDriver) finish(err error) {
+	running := d.running
+	if running == nil {
+		return
+	}
+	d.running = nil
+
+	entry := &HistoryEntry{
+		Version:    running.version,
+		Direction:  running.direction,
+		AppliedAt:  running.startedAt.UTC(),
+		DurationMs: time.Since(running.startedAt).Milliseconds(),
+		Success:    err == nil,
+		Labels:     d.migrator.labels,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if running.body != nil {
+		d.migrator.attachHistorySQL(entry, running.body)
+	}
+	d.migrator.attachSchemaChecksum(entry)
+
+	d.migrator.logMigration(entry)
+	d.migrator.reportProgress(entry)
+	d.migrator.recordHistory(entry)
+	d.migrator.notifyMigration(entry)
+}
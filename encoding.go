@@ -0,0 +1,61 @@
+package migrator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+
+	errEncoding = errors.New("SQL file isn't valid UTF-8")
+)
+
+// normalizeEncoding returns body as UTF-8 without byte order mark, decoding UTF-16 files saved
+// with one, since drivers report a BOM as a confusing syntax error on the first statement. Bodies
+// mixing encodings are reported with the line of their first invalid byte.
+func normalizeEncoding(name string, body []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(body, utf8BOM):
+		body = body[len(utf8BOM):]
+	case bytes.HasPrefix(body, utf16LEBOM), bytes.HasPrefix(body, utf16BEBOM):
+		return decodeUTF16(name, body)
+	}
+
+	if utf8.Valid(body) {
+		return body, nil
+	}
+
+	for i := 0; i < len(body); {
+		r, size := utf8.DecodeRune(body[i:])
+		if r == utf8.RuneError && size == 1 {
+			line := bytes.Count(body[:i], []byte("\n")) + 1
+			return nil, fmt.Errorf("%w: %s line %d, byte 0x%02X", errEncoding, name, line, body[i])
+		}
+		i += size
+	}
+	return body, nil
+}
+
+func decodeUTF16(name string, body []byte) ([]byte, error) {
+	bigEndian := bytes.HasPrefix(body, utf16BEBOM)
+	body = body[len(utf16LEBOM):]
+	if len(body)%2 != 0 {
+		return nil, fmt.Errorf("%w: %s has an odd number of UTF-16 bytes", errEncoding, name)
+	}
+
+	units := make([]uint16, 0, len(body)/2)
+	for i := 0; i < len(body); i += 2 {
+		if bigEndian {
+			units = append(units, uint16(body[i])<<8|uint16(body[i+1]))
+		} else {
+			units = append(units, uint16(body[i+1])<<8|uint16(body[i]))
+		}
+	}
+	return []byte(string(utf16.Decode(units))), nil
+}
@@ -0,0 +1,76 @@
+package migrator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeEncoding(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+		err  string
+	}{
+		{
+			name: "UTF-8",
+			body: "SELECT 'é';\n",
+			want: "SELECT 'é';\n",
+		},
+		{
+			name: "UTF-8 with BOM",
+			body: "\xEF\xBB\xBFSELECT 1;",
+			want: "SELECT 1;",
+		},
+		{
+			name: "UTF-16 LE with BOM",
+			body: "\xFF\xFES\x00;\x00\n\x00\xE9\x00",
+			want: "S;\né",
+		},
+		{
+			name: "UTF-16 BE with BOM",
+			body: "\xFE\xFF\x00S\x00;\x00\n\x00\xE9",
+			want: "S;\né",
+		},
+		{
+			name: "UTF-16 surrogate pair",
+			body: "\xFF\xFE\x3D\xD8\x00\xDE",
+			want: "😀",
+		},
+		{
+			name: "empty",
+			body: "",
+			want: "",
+		},
+		{
+			name: "odd UTF-16",
+			body: "\xFF\xFES\x00;",
+			err:  "odd number of UTF-16 bytes",
+		},
+		{
+			name: "Latin-1 byte",
+			body: "SELECT 1;\nSELECT 'caf\xE9';\n",
+			err:  "1.up.sql line 2, byte 0xE9",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := normalizeEncoding("1.up.sql", []byte(test.body))
+			if test.err != "" {
+				if !errors.Is(err, errEncoding) || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("err = %v, want %v containing %q", err, errEncoding, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
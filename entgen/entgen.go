@@ -0,0 +1,72 @@
+// Package entgen converts the Atlas plan computed by ent's schema migration into a
+// MigrateSQLResult. The plan can be captured with ent's schema.WithApplyHook and
+// handed to FromPlan instead of being applied.
+package entgen
+
+import (
+	"ariga.io/atlas/sql/migrate"
+	"ariga.io/atlas/sql/schema"
+	"fmt"
+	"github.com/anyufly/migrate-sql-result"
+	"strings"
+)
+
+const schemaTable = "schema"
+
+func changeTable(change schema.Change) string {
+	switch c := change.(type) {
+	case *schema.AddTable:
+		return c.T.Name
+	case *schema.DropTable:
+		return c.T.Name
+	case *schema.ModifyTable:
+		return c.T.Name
+	case *schema.RenameTable:
+		return c.To.Name
+	default:
+		return schemaTable
+	}
+}
+
+func trimStmt(stmt string) string {
+	return strings.TrimSuffix(strings.TrimSpace(stmt), ";")
+}
+
+func FromPlan(plan *migrate.Plan) (*result.MigrateSQLResult, error) {
+	migrateResult := result.NewMigrateSQLResult()
+
+	if plan == nil {
+		return migrateResult, nil
+	}
+
+	for _, change := range plan.Changes {
+		if len(change.Args) > 0 {
+			return nil, fmt.Errorf("change %q has placeholder arguments and can't be written to a migration file", change.Cmd)
+		}
+		migrateResult.AppendUp(result.NewSQLForTable(changeTable(change.Source), trimStmt(change.Cmd)))
+	}
+
+	for i := len(plan.Changes) - 1; i >= 0; i-- {
+		change := plan.Changes[i]
+		reverse, err := change.ReverseStmts()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, stmt := range reverse {
+			migrateResult.AppendDown(result.NewSQLForTable(changeTable(change.Source), trimStmt(stmt)))
+		}
+	}
+
+	return migrateResult, nil
+}
+
+func MigrateFunc(planFunc func() (*migrate.Plan, error)) func() (*result.MigrateSQLResult, error) {
+	return func() (*result.MigrateSQLResult, error) {
+		plan, err := planFunc()
+		if err != nil {
+			return nil, err
+		}
+		return FromPlan(plan)
+	}
+}
@@ -0,0 +1,136 @@
+package migrator
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4/source"
+	"strings"
+)
+
+const (
+	onlyEnvDirective = "only-env"
+	skipEnvDirective = "skip-env"
+	endEnvDirective  = "end-env"
+)
+
+var (
+	errEnvBlockNested   = errors.New("environment blocks can't be nested")
+	errEnvBlockEnd      = errors.New("end-env without an only-env or skip-env block")
+	errEnvBlockOpen     = errors.New("environment block isn't closed by -- migrator:end-env")
+	errEnvBlockNoTarget = errors.New("environment block needs at least one environment")
+)
+
+// SetProfile sets the environment the migrations run in, e.g. prod: the statements between
+// -- migrator:only-env prod,staging and -- migrator:end-env only run in those environments, the
+// ones between -- migrator:skip-env dev and -- migrator:end-env run in all the others. Without
+// profile, only-env blocks are skipped and skip-env blocks run.
+func (m *Migrator) SetProfile(profile string) {
+	m.profile = profile
+}
+
+func WithProfile(profile string) Option {
+	return func(m *Migrator) {
+		m.profile = profile
+	}
+}
+
+// envDirective returns the name and the environments of a block directive line.
+func envDirective(line string) (string, []string, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), directivePrefix)
+	if !ok {
+		return "", nil, false
+	}
+
+	name, args, _ := strings.Cut(rest, " ")
+	switch name {
+	case onlyEnvDirective, skipEnvDirective, endEnvDirective:
+	default:
+		return "", nil, false
+	}
+
+	envs := strings.FieldsFunc(args, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+	return name, envs, true
+}
+
+// filterEnvBlocks blanks the lines of the environment blocks of body not meant for profile,
+// keeping the line numbers of the errors of the database.
+func filterEnvBlocks(body []byte, profile string) ([]byte, error) {
+	if !bytes.Contains(body, []byte(directivePrefix)) {
+		return body, nil
+	}
+
+	var (
+		filtered bytes.Buffer
+		inBlock  bool
+		keep     = true
+		lineNo   int
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(body)+1)
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNo++
+
+		name, envs, ok := envDirective(line)
+		switch {
+		case !ok:
+			if keep {
+				filtered.WriteString(line)
+			}
+		case name == endEnvDirective:
+			if !inBlock {
+				return nil, fmt.Errorf("%w: line %d", errEnvBlockEnd, lineNo)
+			}
+			inBlock, keep = false, true
+			filtered.WriteString(line)
+		default:
+			if inBlock {
+				return nil, fmt.Errorf("%w: line %d", errEnvBlockNested, lineNo)
+			}
+			if len(envs) == 0 {
+				return nil, fmt.Errorf("%w: line %d", errEnvBlockNoTarget, lineNo)
+			}
+
+			matches := false
+			for _, env := range envs {
+				matches = matches || env == profile
+			}
+			inBlock, keep = true, matches == (name == onlyEnvDirective)
+			filtered.WriteString(line)
+		}
+		filtered.WriteString("\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if inBlock {
+		return nil, errEnvBlockOpen
+	}
+	return filtered.Bytes(), nil
+}
+
+// renderMigration normalizes the encoding of the SQL migration, expands its includes, renders its templates, expands its
+// macros, then drops its environment blocks not meant for the profile.
+func (m *Migrator) renderMigration(migration *source.Migration, body []byte) ([]byte, error) {
+	body, err := normalizeEncoding(migration.Raw, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if body, err = expandIncludes(m.source.path, migration.Raw, body); err != nil {
+		return nil, err
+	}
+
+	if body, err = m.renderTemplate(migration, body); err != nil {
+		return nil, err
+	}
+
+	if body, err = m.expandMacros(body); err != nil {
+		return nil, err
+	}
+	return filterEnvBlocks(body, m.profile)
+}
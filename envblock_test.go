@@ -0,0 +1,131 @@
+package migrator
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestEnvDirective(t *testing.T) {
+	tests := []struct {
+		line string
+		name string
+		envs []string
+		ok   bool
+	}{
+		{line: "-- migrator:only-env prod", name: onlyEnvDirective, envs: []string{"prod"}, ok: true},
+		{line: "  -- migrator:skip-env dev, test\tci", name: skipEnvDirective, envs: []string{"dev", "test", "ci"}, ok: true},
+		{line: "-- migrator:end-env", name: endEnvDirective, envs: []string{}, ok: true},
+		{line: "-- migrator:only-env", name: onlyEnvDirective, envs: []string{}, ok: true},
+		{line: "-- migrator:no-transaction", ok: false},
+		{line: "-- only-env prod", ok: false},
+		{line: "SELECT 1; -- migrator:only-env prod", ok: false},
+	}
+
+	for _, test := range tests {
+		name, envs, ok := envDirective(test.line)
+		if ok != test.ok {
+			t.Errorf("envDirective(%q) ok = %v, want %v", test.line, ok, test.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		if name != test.name || !reflect.DeepEqual(envs, test.envs) {
+			t.Errorf("envDirective(%q) = %q, %q, want %q, %q", test.line, name, envs, test.name, test.envs)
+		}
+	}
+}
+
+func TestFilterEnvBlocks(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		profile string
+		want    string
+		err     error
+	}{
+		{
+			name:    "no directive",
+			body:    "SELECT 1;",
+			profile: "prod",
+			want:    "SELECT 1;",
+		},
+		{
+			name:    "only-env matching",
+			body:    "A;\n-- migrator:only-env prod,staging\nB;\n-- migrator:end-env\nC;\n",
+			profile: "staging",
+			want:    "A;\n-- migrator:only-env prod,staging\nB;\n-- migrator:end-env\nC;\n",
+		},
+		{
+			name:    "only-env not matching keeps the line numbers",
+			body:    "A;\n-- migrator:only-env prod\nB;\n-- migrator:end-env\nC;\n",
+			profile: "dev",
+			want:    "A;\n-- migrator:only-env prod\n\n-- migrator:end-env\nC;\n",
+		},
+		{
+			name:    "only-env without profile",
+			body:    "-- migrator:only-env prod\nB;\n-- migrator:end-env\n",
+			profile: "",
+			want:    "-- migrator:only-env prod\n\n-- migrator:end-env\n",
+		},
+		{
+			name:    "skip-env matching",
+			body:    "-- migrator:skip-env dev\nB;\n-- migrator:end-env\n",
+			profile: "dev",
+			want:    "-- migrator:skip-env dev\n\n-- migrator:end-env\n",
+		},
+		{
+			name:    "skip-env not matching",
+			body:    "-- migrator:skip-env dev\nB;\n-- migrator:end-env\n",
+			profile: "prod",
+			want:    "-- migrator:skip-env dev\nB;\n-- migrator:end-env\n",
+		},
+		{
+			name:    "several blocks",
+			body:    "-- migrator:only-env dev\nA;\n-- migrator:end-env\n-- migrator:skip-env dev\nB;\n-- migrator:end-env\n",
+			profile: "dev",
+			want:    "-- migrator:only-env dev\nA;\n-- migrator:end-env\n-- migrator:skip-env dev\n\n-- migrator:end-env\n",
+		},
+		{
+			name: "nested",
+			body: "-- migrator:only-env dev\n-- migrator:skip-env prod\n-- migrator:end-env\n",
+			err:  errEnvBlockNested,
+		},
+		{
+			name: "end without block",
+			body: "A;\n-- migrator:end-env\n",
+			err:  errEnvBlockEnd,
+		},
+		{
+			name: "not closed",
+			body: "-- migrator:only-env dev\nA;\n",
+			err:  errEnvBlockOpen,
+		},
+		{
+			name: "no environment",
+			body: "-- migrator:only-env\nA;\n-- migrator:end-env\n",
+			err:  errEnvBlockNoTarget,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := filterEnvBlocks([]byte(test.body), test.profile)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("err = %v, want %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package migrator
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	exportFormatLiquibase = "liquibase"
+	liquibaseAuthor       = "file-migrator"
+	liquibaseXMLNS        = "http://www.liquibase.org/xml/ns/dbchangelog"
+)
+
+var errUnknownExportFormat = errors.New("unknown export format")
+
+type liquibaseSQLFile struct {
+	Path                    string `xml:"path,attr" yaml:"path"`
+	RelativeToChangelogFile bool   `xml:"relativeToChangelogFile,attr" yaml:"relativeToChangelogFile"`
+	SplitStatements         bool   `xml:"splitStatements,attr" yaml:"splitStatements"`
+}
+
+type liquibaseRollback struct {
+	SQLFile *liquibaseSQLFile `xml:"sqlFile"`
+}
+
+type liquibaseChangeSet struct {
+	ID       string             `xml:"id,attr"`
+	Author   string             `xml:"author,attr"`
+	SQLFile  *liquibaseSQLFile  `xml:"sqlFile"`
+	Rollback *liquibaseRollback `xml:"rollback,omitempty"`
+}
+
+type liquibaseChangeLog struct {
+	XMLName    xml.Name              `xml:"databaseChangeLog"`
+	XMLNS      string                `xml:"xmlns,attr"`
+	ChangeSets []*liquibaseChangeSet `xml:"changeSet"`
+}
+
+func (changeSet *liquibaseChangeSet) yamlValue() map[string]interface{} {
+	value := map[string]interface{}{
+		"id":      changeSet.ID,
+		"author":  changeSet.Author,
+		"changes": []map[string]interface{}{{"sqlFile": changeSet.SQLFile}},
+	}
+
+	if changeSet.Rollback != nil {
+		value["rollback"] = []map[string]interface{}{{"sqlFile": changeSet.Rollback.SQLFile}}
+	}
+
+	return map[string]interface{}{"changeSet": value}
+}
+
+func (m *Migrator) liquibaseChangeLog(changelogDir string) (*liquibaseChangeLog, error) {
+	list, err := listMigrationFiles(m.migrationsFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlFile := func(path string) (*liquibaseSQLFile, error) {
+		rel, err := filepath.Rel(changelogDir, path)
+		if err != nil {
+			return nil, err
+		}
+		return &liquibaseSQLFile{Path: filepath.ToSlash(rel), RelativeToChangelogFile: true, SplitStatements: true}, nil
+	}
+
+	changeLog := &liquibaseChangeLog{XMLNS: liquibaseXMLNS}
+
+	for _, files := range list {
+		if files.up == "" {
+			continue
+		}
+
+		changeSet := &liquibaseChangeSet{
+			ID:     strconv.FormatUint(uint64(files.version), 10),
+			Author: liquibaseAuthor,
+		}
+
+		if changeSet.SQLFile, err = sqlFile(files.up); err != nil {
+			return nil, err
+		}
+
+		if files.down != "" {
+			rollback, err := sqlFile(files.down)
+			if err != nil {
+				return nil, err
+			}
+			changeSet.Rollback = &liquibaseRollback{SQLFile: rollback}
+		}
+
+		changeLog.ChangeSets = append(changeLog.ChangeSets, changeSet)
+	}
+
+	return changeLog, nil
+}
+
+// ExportLiquibase writes a liquibase changelog referencing the migration files to w, as YAML when
+// asYAML is set and XML otherwise. File paths are relative to changelogDir.
+func (m *Migrator) ExportLiquibase(w io.Writer, changelogDir string, asYAML bool) error {
+	changeLog, err := m.liquibaseChangeLog(changelogDir)
+	if err != nil {
+		return err
+	}
+
+	if asYAML {
+		changeSets := make([]map[string]interface{}, len(changeLog.ChangeSets))
+		for i, changeSet := range changeLog.ChangeSets {
+			changeSets[i] = changeSet.yamlValue()
+		}
+
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err = encoder.Encode(map[string]interface{}{"databaseChangeLog": changeSets}); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+
+	if _, err = io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err = encoder.Encode(changeLog); err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func (m *Migrator) Export(w io.Writer, format string, out string) error {
+	switch format {
+	case exportFormatLiquibase:
+		changelogDir := "."
+		if out != "" {
+			changelogDir = filepath.Dir(out)
+		}
+
+		ext := strings.ToLower(filepath.Ext(out))
+		return m.ExportLiquibase(w, changelogDir, ext == ".yaml" || ext == ".yml")
+	default:
+		return fmt.Errorf("%w: %s", errUnknownExportFormat, format)
+	}
+}
@@ -0,0 +1,423 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type FixtureMode string
+
+const (
+	FixtureInsert   FixtureMode = "insert"
+	FixtureTruncate FixtureMode = "truncate"
+	FixtureUpsert   FixtureMode = "upsert"
+
+	csvNull = `\N`
+)
+
+var (
+	errUnknownFixtureMode = errors.New("fixture mode must be one of insert, truncate, upsert")
+	errFixtureCycle       = errors.New("foreign keys between fixture tables form a cycle")
+)
+
+type fixture struct {
+	table   string
+	path    string
+	columns []string
+	rows    [][]interface{}
+}
+
+func isFixtureFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yml", ".yaml", ".csv":
+		return true
+	default:
+		return false
+	}
+}
+
+func readYAMLFixture(path string) ([]string, [][]interface{}, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var records []map[string]interface{}
+	if err = yaml.Unmarshal(body, &records); err != nil {
+		return nil, nil, fmt.Errorf("fixture %s: %w", path, err)
+	}
+
+	columnSet := make(map[string]struct{})
+	for _, record := range records {
+		for column := range record {
+			columnSet[column] = struct{}{}
+		}
+	}
+
+	columns := make([]string, 0, len(columnSet))
+	for column := range columnSet {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	rows := make([][]interface{}, 0, len(records))
+	for _, record := range records {
+		row := make([]interface{}, len(columns))
+		for i, column := range columns {
+			row[i] = record[column]
+		}
+		rows = append(rows, row)
+	}
+
+	return columns, rows, nil
+}
+
+func readCSVFixture(path string) ([]string, [][]interface{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("fixture %s: %w", path, err)
+	}
+
+	if len(records) == 0 {
+		return nil, nil, nil
+	}
+
+	columns := records[0]
+	rows := make([][]interface{}, 0, len(records)-1)
+
+	for _, record := range records[1:] {
+		row := make([]interface{}, len(record))
+		for i, value := range record {
+			if value == csvNull {
+				row[i] = nil
+			} else {
+				row[i] = value
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return columns, rows, nil
+}
+
+func readFixture(path string) (*fixture, error) {
+	var (
+		columns []string
+		rows    [][]interface{}
+		err     error
+	)
+
+	ext := filepath.Ext(path)
+	if strings.EqualFold(ext, ".csv") {
+		columns, rows, err = readCSVFixture(path)
+	} else {
+		columns, rows, err = readYAMLFixture(path)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &fixture{
+		table:   strings.TrimSuffix(filepath.Base(path), ext),
+		path:    path,
+		columns: columns,
+		rows:    rows,
+	}, nil
+}
+
+const (
+	postgresForeignKeysQuery = `SELECT cl.relname, ref.relname
+FROM pg_catalog.pg_constraint c
+	JOIN pg_catalog.pg_class cl ON c.conrelid = cl.oid
+	JOIN pg_catalog.pg_class ref ON c.confrelid = ref.oid
+	JOIN pg_catalog.pg_namespace n ON cl.relnamespace = n.oid
+WHERE c.contype = 'f' AND n.nspname = current_schema()`
+
+	mysqlForeignKeysQuery = `SELECT TABLE_NAME, REFERENCED_TABLE_NAME
+FROM information_schema.KEY_COLUMN_USAGE
+WHERE TABLE_SCHEMA = DATABASE() AND REFERENCED_TABLE_NAME IS NOT NULL`
+)
+
+func queryPairs(db *sql.DB, query string) (map[string][]string, error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pairs := make(map[string][]string)
+	for rows.Next() {
+		var from, to string
+		if err = rows.Scan(&from, &to); err != nil {
+			return nil, err
+		}
+		pairs[from] = append(pairs[from], to)
+	}
+
+	return pairs, rows.Err()
+}
+
+func sqliteForeignKeys(db *sql.DB, tables []string) (map[string][]string, error) {
+	references := make(map[string][]string)
+
+	for _, table := range tables {
+		rows, err := db.Query(fmt.Sprintf("PRAGMA foreign_key_list(%s)", DialectSQLite.quote(table)))
+		if err != nil {
+			return nil, err
+		}
+
+		columns, err := rows.Columns()
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		for rows.Next() {
+			values := make([]interface{}, len(columns))
+			var referenced string
+			for i := range values {
+				if columns[i] == "table" {
+					values[i] = &referenced
+				} else {
+					values[i] = new(interface{})
+				}
+			}
+
+			if err = rows.Scan(values...); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			references[table] = append(references[table], referenced)
+		}
+
+		if err = rows.Err(); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rows.Close()
+	}
+
+	return references, nil
+}
+
+func (m *Migrator) foreignKeys(tables []string) (map[string][]string, error) {
+	switch m.dialect {
+	case DialectPostgres:
+		return queryPairs(m.db, postgresForeignKeysQuery)
+	case DialectMySQL:
+		return queryPairs(m.db, mysqlForeignKeysQuery)
+	case DialectSQLite:
+		return sqliteForeignKeys(m.db, tables)
+	default:
+		return nil, errUnsupportedDialect
+	}
+}
+
+// sortFixtures orders fixtures so that referenced tables are loaded before the tables referencing
+// them, the fixtures of a table, e.g. users.yml and users.csv, keeping their order.
+func sortFixtures(fixtures []*fixture, references map[string][]string) ([]*fixture, error) {
+	byTable := make(map[string][]*fixture, len(fixtures))
+	for _, f := range fixtures {
+		byTable[f.table] = append(byTable[f.table], f)
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	state := make(map[string]int, len(fixtures))
+	sorted := make([]*fixture, 0, len(fixtures))
+
+	var visit func(table string) error
+	visit = func(table string) error {
+		switch state[table] {
+		case visiting:
+			return fmt.Errorf("%w: %s", errFixtureCycle, table)
+		case visited:
+			return nil
+		}
+
+		state[table] = visiting
+		for _, referenced := range references[table] {
+			if _, ok := byTable[referenced]; ok && referenced != table {
+				if err := visit(referenced); err != nil {
+					return err
+				}
+			}
+		}
+		state[table] = visited
+		sorted = append(sorted, byTable[table]...)
+		return nil
+	}
+
+	for _, f := range fixtures {
+		if err := visit(f.table); err != nil {
+			return nil, err
+		}
+	}
+
+	return sorted, nil
+}
+
+func (m *Migrator) insertFixtureSQL(f *fixture, primaryKeys []string, mode FixtureMode) string {
+	d := m.dialect
+
+	quotedColumns := make([]string, len(f.columns))
+	placeholders := make([]string, len(f.columns))
+	for i, column := range f.columns {
+		quotedColumns[i] = d.quote(column)
+		placeholders[i] = d.placeholder(i + 1)
+	}
+
+	insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		d.quote(f.table), strings.Join(quotedColumns, ", "), strings.Join(placeholders, ", "))
+
+	if mode != FixtureUpsert {
+		return insert
+	}
+
+	updates := make([]string, 0, len(f.columns))
+	for _, column := range f.columns {
+		if d == DialectMySQL {
+			updates = append(updates, fmt.Sprintf("%s = VALUES(%s)", d.quote(column), d.quote(column)))
+		} else {
+			updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", d.quote(column), d.quote(column)))
+		}
+	}
+
+	if d == DialectMySQL {
+		return fmt.Sprintf("%s ON DUPLICATE KEY UPDATE %s", insert, strings.Join(updates, ", "))
+	}
+
+	quotedKeys := make([]string, len(primaryKeys))
+	for i, key := range primaryKeys {
+		quotedKeys[i] = d.quote(key)
+	}
+	return fmt.Sprintf("%s ON CONFLICT (%s) DO UPDATE SET %s", insert, strings.Join(quotedKeys, ", "), strings.Join(updates, ", "))
+}
+
+func (m *Migrator) loadFixtures(ctx context.Context, tx *sql.Tx, fixtures []*fixture, mode FixtureMode) error {
+	tables := make([]string, len(fixtures))
+	for i, f := range fixtures {
+		tables[i] = f.table
+	}
+
+	references, err := m.foreignKeys(tables)
+	if err != nil {
+		return err
+	}
+
+	fixtures, err = sortFixtures(fixtures, references)
+	if err != nil {
+		return err
+	}
+
+	primaryKeys := make(map[string][]string)
+	if mode == FixtureUpsert {
+		live, err := m.LiveSchema()
+		if err != nil {
+			return err
+		}
+		for _, table := range live.Tables {
+			for _, column := range table.Columns {
+				if column.PrimaryKey {
+					primaryKeys[table.Name] = append(primaryKeys[table.Name], column.Name)
+				}
+			}
+		}
+	}
+
+	if mode == FixtureTruncate {
+		for i := len(fixtures) - 1; i >= 0; i-- {
+			if _, err = tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s", m.dialect.quote(fixtures[i].table))); err != nil {
+				return fmt.Errorf("truncate %s: %w", fixtures[i].table, err)
+			}
+		}
+	}
+
+	for _, f := range fixtures {
+		if len(f.columns) == 0 {
+			continue
+		}
+
+		if mode == FixtureUpsert && m.dialect != DialectMySQL && len(primaryKeys[f.table]) == 0 {
+			return fmt.Errorf("can't upsert fixture %s: table has no primary key", f.path)
+		}
+
+		insert := m.insertFixtureSQL(f, primaryKeys[f.table], mode)
+		for _, row := range f.rows {
+			if _, err = tx.ExecContext(ctx, insert, row...); err != nil {
+				return fmt.Errorf("fixture %s: %w", f.path, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// LoadFixtures loads the YAML/CSV fixture files named after their tables (users.yml, orders.csv, ...)
+// in a single transaction, inserting referenced tables first.
+func (m *Migrator) LoadFixtures(ctx context.Context, paths []string, mode FixtureMode) error {
+	switch mode {
+	case FixtureInsert, FixtureTruncate, FixtureUpsert:
+	default:
+		return errUnknownFixtureMode
+	}
+
+	if m.db == nil {
+		return errNoDB
+	}
+
+	fixtures := make([]*fixture, 0, len(paths))
+	for _, path := range paths {
+		f, err := readFixture(path)
+		if err != nil {
+			return err
+		}
+		fixtures = append(fixtures, f)
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err = m.loadFixtures(ctx, tx, fixtures, mode); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *Migrator) LoadFixturesDir(ctx context.Context, dir string, mode FixtureMode) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() && isFixtureFile(e.Name()) {
+			paths = append(paths, filepath.Join(dir, e.Name()))
+		}
+	}
+
+	return m.LoadFixtures(ctx, paths, mode)
+}
@@ -0,0 +1,87 @@
+package migrator
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSortFixtures(t *testing.T) {
+	tests := []struct {
+		name       string
+		fixtures   []string
+		references map[string][]string
+		want       []string
+		err        error
+	}{
+		{
+			name:     "no references keeps the order",
+			fixtures: []string{"b", "a", "c"},
+			want:     []string{"b", "a", "c"},
+		},
+		{
+			name:       "referenced tables first",
+			fixtures:   []string{"orders", "users", "products"},
+			references: map[string][]string{"orders": {"users", "products"}},
+			want:       []string{"users", "products", "orders"},
+		},
+		{
+			name:       "transitive references",
+			fixtures:   []string{"items", "orders", "users"},
+			references: map[string][]string{"items": {"orders"}, "orders": {"users"}},
+			want:       []string{"users", "orders", "items"},
+		},
+		{
+			name:       "references to tables without fixture",
+			fixtures:   []string{"orders"},
+			references: map[string][]string{"orders": {"users"}},
+			want:       []string{"orders"},
+		},
+		{
+			name:       "self reference",
+			fixtures:   []string{"employees", "teams"},
+			references: map[string][]string{"employees": {"employees", "teams"}},
+			want:       []string{"teams", "employees"},
+		},
+		{
+			name:       "fixtures of the same table",
+			fixtures:   []string{"orders", "users", "users"},
+			references: map[string][]string{"orders": {"users"}},
+			want:       []string{"users", "users", "orders"},
+		},
+		{
+			name:       "cycle",
+			fixtures:   []string{"a", "b"},
+			references: map[string][]string{"a": {"b"}, "b": {"a"}},
+			err:        errFixtureCycle,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fixtures := make([]*fixture, len(test.fixtures))
+			for i, table := range test.fixtures {
+				fixtures[i] = &fixture{table: table}
+			}
+
+			sorted, err := sortFixtures(fixtures, test.references)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("err = %v, want %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]string, len(sorted))
+			for i, f := range sorted {
+				got[i] = f.table
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package migrator
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const flywayHistoryTable = "flyway_schema_history"
+
+var flywayFileRegexp = regexp.MustCompile(`^([VU])([0-9]+(?:[._][0-9]+)*)__(.+)\.sql$`)
+
+type flywayMigration struct {
+	kind    string
+	version string
+	parts   []uint64
+	name    string
+	path    string
+}
+
+func parseFlywayVersion(version string) ([]uint64, error) {
+	fields := strings.FieldsFunc(version, func(r rune) bool {
+		return r == '.' || r == '_'
+	})
+
+	parts := make([]uint64, len(fields))
+	for i, field := range fields {
+		part, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		parts[i] = part
+	}
+	return parts, nil
+}
+
+func compareFlywayVersions(a, b []uint64) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y uint64
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func normalizeFlywayVersion(parts []uint64) string {
+	for len(parts) > 1 && parts[len(parts)-1] == 0 {
+		parts = parts[:len(parts)-1]
+	}
+
+	fields := make([]string, len(parts))
+	for i, part := range parts {
+		fields[i] = strconv.FormatUint(part, 10)
+	}
+	return strings.Join(fields, ".")
+}
+
+func readFlywayMigrations(dir string) ([]*flywayMigration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]*flywayMigration, 0, len(entries))
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		matches := flywayFileRegexp.FindStringSubmatch(e.Name())
+		if matches == nil {
+			continue
+		}
+
+		parts, err := parseFlywayVersion(matches[2])
+		if err != nil {
+			return nil, fmt.Errorf("malformed flyway migration filename: %s", e.Name())
+		}
+
+		migrations = append(migrations, &flywayMigration{
+			kind:    matches[1],
+			version: normalizeFlywayVersion(parts),
+			parts:   parts,
+			name:    matches[3],
+			path:    filepath.Join(dir, e.Name()),
+		})
+	}
+
+	sort.SliceStable(migrations, func(i, j int) bool {
+		return compareFlywayVersions(migrations[i].parts, migrations[j].parts) < 0
+	})
+
+	return migrations, nil
+}
+
+// flywayVersionMap maps flyway versions onto golang-migrate versions. Plain integer versions
+// are kept, dotted versions force every migration to be renumbered sequentially in flyway order.
+func flywayVersionMap(migrations []*flywayMigration) map[string]uint {
+	dotted := false
+	for _, migration := range migrations {
+		if len(migration.parts) > 1 && normalizeFlywayVersion(migration.parts) != strconv.FormatUint(migration.parts[0], 10) {
+			dotted = true
+			break
+		}
+	}
+
+	versionMap := make(map[string]uint)
+	next := uint(1)
+
+	for _, migration := range migrations {
+		if _, ok := versionMap[migration.version]; ok {
+			continue
+		}
+
+		if dotted {
+			versionMap[migration.version] = next
+			next++
+		} else {
+			versionMap[migration.version] = uint(migration.parts[0])
+		}
+	}
+
+	return versionMap
+}
+
+func (m *Migrator) flywayImportedFiles(dir string) ([]*importedFile, error) {
+	migrations, err := readFlywayMigrations(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	versionMap := flywayVersionMap(migrations)
+	files := make([]*importedFile, 0, len(migrations))
+
+	for _, migration := range migrations {
+		body, err := os.ReadFile(migration.path)
+		if err != nil {
+			return nil, err
+		}
+
+		direction := "up"
+		if migration.kind == "U" {
+			direction = "down"
+		}
+
+		files = append(files, &importedFile{
+			from: migration.path,
+			to:   m.importedPath(versionMap[migration.version], migration.name, direction),
+			body: body,
+		})
+	}
+
+	repeatables, err := filepath.Glob(filepath.Join(dir, repeatablePrefix+"*.sql"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, repeatable := range repeatables {
+		body, err := os.ReadFile(repeatable)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, &importedFile{
+			from: repeatable,
+			to:   filepath.Join(m.migrationsFilePath, filepath.Base(repeatable)),
+			body: body,
+		})
+	}
+
+	return files, nil
+}
+
+func (m *Migrator) importFlywayHistory(dir string) error {
+	if m.db == nil {
+		return errNoDB
+	}
+
+	migrations, err := readFlywayMigrations(dir)
+	if err != nil {
+		return err
+	}
+	versionMap := flywayVersionMap(migrations)
+
+	var version string
+	err = m.db.QueryRow(fmt.Sprintf(
+		"SELECT version FROM %s WHERE success AND version IS NOT NULL ORDER BY installed_rank DESC LIMIT 1",
+		flywayHistoryTable)).Scan(&version)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		m.logger.Info("flyway history is empty, nothing to import")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	parts, err := parseFlywayVersion(version)
+	if err != nil {
+		return fmt.Errorf("malformed flyway version %q in %s", version, flywayHistoryTable)
+	}
+
+	mapped, ok := versionMap[normalizeFlywayVersion(parts)]
+	if !ok {
+		return fmt.Errorf("flyway version %s is applied but has no migration file in %s", version, dir)
+	}
+
+	m.logger.Info("setting version from flyway history", "flywayVersion", version, "version", mapped)
+	return m.Force(int(mapped))
+}
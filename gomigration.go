@@ -0,0 +1,113 @@
+package migrator
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+const goMigrationIdentifier = "go"
+
+var errGoMigrationExists = errors.New("go migration already registered")
+
+type GoMigrationFunc func(ctx context.Context, tx *sql.Tx) error
+
+type goMigration struct {
+	up   GoMigrationFunc
+	down GoMigrationFunc
+}
+
+// RegisterGoMigration adds a Go migration of this migrator that runs in version order alongside
+// its SQL migration files.
+func (m *Migrator) RegisterGoMigration(version uint, up, down GoMigrationFunc) error {
+	if _, ok := m.goMigrations[version]; ok {
+		return fmt.Errorf("%w: %d", errGoMigrationExists, version)
+	}
+
+	if m.goMigrations == nil {
+		m.goMigrations = make(map[uint]*goMigration)
+	}
+	m.goMigrations[version] = &goMigration{up: up, down: down}
+
+	if m.source == nil {
+		return nil
+	}
+
+	// re-read the migrations for the new one to run
+	if err := m.SetMigrationsPath(m.migrationsFilePath); err != nil {
+		delete(m.goMigrations, version)
+		return err
+	}
+	return nil
+}
+
+func WithGoMigration(version uint, up, down GoMigrationFunc) Option {
+	return func(m *Migrator) {
+		if err := m.RegisterGoMigration(version, up, down); err != nil {
+			m.logger.Error("can't register go migration", "error", err)
+		}
+	}
+}
+
+func goMigrationMarker(version uint, direction source.Direction) []byte {
+	return []byte(fmt.Sprintf("-- migrator:go %d %s\n", version, direction))
+}
+
+func parseGoMigrationMarker(body []byte) (uint, source.Direction, bool) {
+	var (
+		version   uint
+		direction string
+	)
+
+	if !bytes.HasPrefix(body, []byte("-- migrator:go ")) {
+		return 0, "", false
+	}
+
+	if _, err := fmt.Sscanf(string(body), "-- migrator:go %d %s\n", &version, &direction); err != nil {
+		return 0, "", false
+	}
+	return version, source.Direction(direction), true
+}
+
+func (m *Migrator) runGoMigration(version uint, direction source.Direction) error {
+	migration, ok := m.goMigrations[version]
+	if !ok {
+		return fmt.Errorf("go migration %d is not registered", version)
+	}
+
+	fn := migration.up
+	if direction == source.Down {
+		fn = migration.down
+	}
+
+	if fn == nil {
+		return nil
+	}
+
+	if err := m.runInTx(fn); err != nil {
+		return fmt.Errorf("go migration %d %s: %w", version, direction, err)
+	}
+	return nil
+}
+
+func (m *Migrator) runInTx(fn GoMigrationFunc) error {
+	if m.db == nil {
+		return errNoDB
+	}
+
+	ctx := context.Background()
+	tx, err := m.db.BeginTx(ctx, &sql.TxOptions{Isolation: m.isolation})
+	if err != nil {
+		return err
+	}
+
+	if err = fn(ctx, tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,179 @@
+package migrator
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	gooseHistoryTable = "goose_db_version"
+	gooseDirective    = "-- +goose"
+)
+
+var gooseFileRegexp = regexp.MustCompile(`^([0-9]+)_(.+)\.(sql|go)$`)
+
+// splitGooseMigration splits an annotated goose file into its up and down parts, marking both
+// -- migrator:no-transaction when the file is annotated NO TRANSACTION and dropping the other
+// goose directives.
+func splitGooseMigration(body []byte) ([]byte, []byte, error) {
+	var up, down bytes.Buffer
+	var current *bytes.Buffer
+	noTransaction := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(body)+1)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, gooseDirective) {
+			switch strings.ToLower(strings.TrimSpace(strings.TrimPrefix(trimmed, gooseDirective))) {
+			case "up":
+				current = &up
+			case "down":
+				current = &down
+			case "no transaction":
+				noTransaction = true
+			}
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if current == nil {
+		return nil, nil, fmt.Errorf("no %s Up annotation", gooseDirective)
+	}
+
+	upSQL, downSQL := bytes.TrimSpace(up.Bytes()), bytes.TrimSpace(down.Bytes())
+	if noTransaction {
+		upSQL = append([]byte(noTransactionDirective), upSQL...)
+		if len(downSQL) > 0 {
+			downSQL = append([]byte(noTransactionDirective), downSQL...)
+		}
+	}
+	return upSQL, downSQL, nil
+}
+
+func (m *Migrator) gooseImportedFiles(dir string) ([]*importedFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]*importedFile, 0, len(entries)*2)
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		matches := gooseFileRegexp.FindStringSubmatch(e.Name())
+		if matches == nil {
+			continue
+		}
+
+		path := filepath.Join(dir, e.Name())
+
+		if matches[3] == "go" {
+			m.logger.Info("skipping goose Go migration, port it with Register", "file", path)
+			continue
+		}
+
+		version, err := strconv.ParseUint(matches[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed goose migration filename: %s", e.Name())
+		}
+
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		up, down, err := splitGooseMigration(body)
+		if err != nil {
+			return nil, fmt.Errorf("goose migration %s: %w", path, err)
+		}
+
+		files = append(files, &importedFile{
+			from: path,
+			to:   m.importedPath(uint(version), matches[2], "up"),
+			body: append(up, '\n'),
+		})
+
+		if len(down) > 0 {
+			files = append(files, &importedFile{
+				from: path,
+				to:   m.importedPath(uint(version), matches[2], "down"),
+				body: append(down, '\n'),
+			})
+		}
+	}
+
+	return files, nil
+}
+
+func (m *Migrator) importGooseHistory() error {
+	if m.db == nil {
+		return errNoDB
+	}
+
+	rows, err := m.db.Query(fmt.Sprintf("SELECT version_id, is_applied FROM %s ORDER BY id DESC", gooseHistoryTable))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// goose keeps a log of applies and rollbacks, the current version is the newest
+	// applied row that hasn't been rolled back since.
+	rolledBack := make(map[int64]struct{})
+	current := int64(0)
+
+	for rows.Next() {
+		var (
+			version int64
+			applied bool
+		)
+
+		if err = rows.Scan(&version, &applied); err != nil {
+			return err
+		}
+
+		if _, ok := rolledBack[version]; ok {
+			continue
+		}
+
+		if applied {
+			current = version
+			break
+		}
+		rolledBack[version] = struct{}{}
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	if current <= 0 {
+		m.logger.Info("goose history is empty, nothing to import")
+		return nil
+	}
+
+	m.logger.Info("setting version from goose history", "version", current)
+	return m.Force(int(current))
+}
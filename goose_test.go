@@ -0,0 +1,60 @@
+package migrator
+
+import "testing"
+
+func TestSplitGooseMigration(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		up   string
+		down string
+		err  bool
+	}{
+		{
+			name: "up and down",
+			body: "-- +goose Up\nCREATE TABLE t (id INT);\n\n-- +goose Down\nDROP TABLE t;\n",
+			up:   "CREATE TABLE t (id INT);",
+			down: "DROP TABLE t;",
+		},
+		{
+			name: "statement blocks",
+			body: "-- +goose Up\n-- +goose StatementBegin\nCREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql;\n-- +goose StatementEnd\n",
+			up:   "CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql;",
+		},
+		{
+			name: "no transaction",
+			body: "-- +goose NO TRANSACTION\n-- +goose Up\nCREATE INDEX CONCURRENTLY i ON t (id);\n-- +goose Down\nDROP INDEX CONCURRENTLY i;\n",
+			up:   noTransactionDirective + "CREATE INDEX CONCURRENTLY i ON t (id);",
+			down: noTransactionDirective + "DROP INDEX CONCURRENTLY i;",
+		},
+		{
+			name: "no transaction without down",
+			body: "-- +goose Up\n-- +goose NO TRANSACTION\nCREATE INDEX CONCURRENTLY i ON t (id);\n",
+			up:   noTransactionDirective + "CREATE INDEX CONCURRENTLY i ON t (id);",
+		},
+		{
+			name: "no up annotation",
+			body: "CREATE TABLE t (id INT);\n",
+			err:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			up, down, err := splitGooseMigration([]byte(test.body))
+			if test.err {
+				if err == nil {
+					t.Fatal("want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(up) != test.up || string(down) != test.down {
+				t.Errorf("got %q, %q, want %q, %q", up, down, test.up, test.down)
+			}
+		})
+	}
+}
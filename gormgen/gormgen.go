@@ -0,0 +1,136 @@
+package gormgen
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/anyufly/file-migrator"
+	"github.com/anyufly/migrate-sql-result"
+	"gorm.io/gorm"
+	"strings"
+)
+
+var errNoInverse = errors.New("can't derive the down migration, write it by hand")
+
+type recordedResult struct{}
+
+func (recordedResult) LastInsertId() (int64, error) {
+	return 0, nil
+}
+
+func (recordedResult) RowsAffected() (int64, error) {
+	return 0, nil
+}
+
+// recordingConnPool passes queries through to the database so gorm can inspect the
+// live schema, and records every statement gorm would execute instead of running it.
+type recordingConnPool struct {
+	gorm.ConnPool
+	dialector gorm.Dialector
+	sqlList   []string
+}
+
+func (pool *recordingConnPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	sql := strings.TrimSpace(pool.dialector.Explain(query, args...))
+	sql = strings.TrimSuffix(sql, ";")
+	pool.sqlList = append(pool.sqlList, sql)
+	return recordedResult{}, nil
+}
+
+func (pool *recordingConnPool) take() []string {
+	sqlList := pool.sqlList
+	pool.sqlList = nil
+	return sqlList
+}
+
+// Diff returns the statements AutoMigrate would run for models, with their down statements: dropping
+// the tables it creates, and undoing the columns and indexes it adds to existing ones, failing on
+// the statements that can't be undone.
+func Diff(db *gorm.DB, models ...interface{}) (*result.MigrateSQLResult, error) {
+	migrateResult := result.NewMigrateSQLResult()
+	downs := make([]*result.SQLForTable, 0)
+
+	tx := db.Session(&gorm.Session{NewDB: true})
+	pool := &recordingConnPool{ConnPool: tx.Statement.ConnPool, dialector: tx.Dialector}
+	tx.Statement.ConnPool = pool
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: tx}
+		if err := stmt.Parse(model); err != nil {
+			return nil, fmt.Errorf("parse model %T: %w", model, err)
+		}
+		tableName := stmt.Schema.Table
+
+		exists := tx.Migrator().HasTable(model)
+
+		if err := tx.Migrator().AutoMigrate(model); err != nil {
+			return nil, fmt.Errorf("diff model %T: %w", model, err)
+		}
+
+		sqlList := pool.take()
+		for _, sql := range sqlList {
+			migrateResult.AppendUp(result.NewSQLForTable(tableName, sql))
+		}
+
+		if !exists {
+			downs = append(downs, result.NewSQLForTable(tableName, fmt.Sprintf("DROP TABLE %s", tx.Statement.Quote(tableName))))
+			continue
+		}
+
+		for _, sql := range sqlList {
+			down, unknowns := migrator.SuggestDown(migrator.Dialect(tx.Dialector.Name()), sql, ";")
+			if len(unknowns) > 0 {
+				return nil, fmt.Errorf("%w: %s", errNoInverse, sql)
+			}
+			downs = append(downs, result.NewSQLForTable(tableName, strings.TrimSuffix(strings.TrimSpace(down), ";")))
+		}
+	}
+
+	// undone in the reverse order, the tables referencing others being dropped first
+	for i := len(downs) - 1; i >= 0; i-- {
+		migrateResult.AppendDown(downs[i])
+	}
+
+	return migrateResult, nil
+}
+
+func MigrateFunc(db *gorm.DB, models ...interface{}) func() (*result.MigrateSQLResult, error) {
+	return func() (*result.MigrateSQLResult, error) {
+		return Diff(db, models...)
+	}
+}
+
+func Schema(db *gorm.DB, models ...interface{}) (*migrator.Schema, error) {
+	s := &migrator.Schema{}
+	tx := db.Session(&gorm.Session{NewDB: true})
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: tx}
+		if err := stmt.Parse(model); err != nil {
+			return nil, fmt.Errorf("parse model %T: %w", model, err)
+		}
+
+		table := &migrator.Table{Name: stmt.Schema.Table}
+
+		for _, dbName := range stmt.Schema.DBNames {
+			field := stmt.Schema.FieldsByDBName[dbName]
+			table.Columns = append(table.Columns, &migrator.Column{
+				Name:       field.DBName,
+				Type:       tx.Dialector.DataTypeOf(field),
+				Nullable:   !field.NotNull && !field.PrimaryKey,
+				PrimaryKey: field.PrimaryKey,
+			})
+		}
+
+		s.Tables = append(s.Tables, table)
+	}
+
+	return s, nil
+}
+
+func SchemaFunc(db *gorm.DB, models ...interface{}) func() (*migrator.Schema, error) {
+	return func() (*migrator.Schema, error) {
+		return Schema(db, models...)
+	}
+}
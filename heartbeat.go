@@ -0,0 +1,116 @@
+package migrator
+
+import (
+	"sync"
+	"time"
+)
+
+// LockRefresher is implemented by drivers whose lock can expire or be considered stale during a
+// long migration, such as table based locks with a TTL.
+type LockRefresher interface {
+	RefreshLock() error
+}
+
+type lockHeartbeat struct {
+	stop     chan struct{}
+	done     chan struct{}
+	mu       sync.Mutex
+	failures int
+	lastErr  error
+}
+
+// WithLockHeartbeat refreshes the migration lock every interval while it is held, with refresh, or
+// with the RefreshLock of the driver when refresh is nil.
+func WithLockHeartbeat(interval time.Duration, refresh func() error) Option {
+	return func(m *Migrator) {
+		m.SetLockHeartbeat(interval, refresh)
+	}
+}
+
+// SetLockHeartbeat see WithLockHeartbeat, an interval of 0 disables the heartbeat.
+func (m *Migrator) SetLockHeartbeat(interval time.Duration, refresh func() error) {
+	m.lockHeartbeat = interval
+	m.lockRefresh = refresh
+}
+
+func (d *databaseDriver) refreshLock() func() error {
+	if d.migrator.lockRefresh != nil {
+		return d.migrator.lockRefresh
+	}
+
+	if refresher, ok := d.Driver.(LockRefresher); ok {
+		return refresher.RefreshLock
+	}
+	return nil
+}
+
+func (d *databaseDriver) startHeartbeat() {
+	interval := d.migrator.lockHeartbeat
+	if interval <= 0 {
+		return
+	}
+
+	refresh := d.refreshLock()
+	if refresh == nil {
+		d.migrator.logger.Error("lock heartbeat is enabled but the driver can't refresh its lock, set a refresh func")
+		return
+	}
+
+	heartbeat := &lockHeartbeat{stop: make(chan struct{}), done: make(chan struct{})}
+	d.heartbeat = heartbeat
+
+	go func() {
+		defer close(heartbeat.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-heartbeat.stop:
+				return
+			case <-ticker.C:
+				if err := refresh(); err != nil {
+					heartbeat.mu.Lock()
+					heartbeat.failures++
+					heartbeat.lastErr = err
+					heartbeat.mu.Unlock()
+
+					d.migrator.logger.Error("lock heartbeat failed, the lock may be lost", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+func (d *databaseDriver) stopHeartbeat() {
+	heartbeat := d.heartbeat
+	if heartbeat == nil {
+		return
+	}
+	d.heartbeat = nil
+
+	close(heartbeat.stop)
+	<-heartbeat.done
+
+	heartbeat.mu.Lock()
+	defer heartbeat.mu.Unlock()
+	if heartbeat.failures > 0 {
+		d.migrator.logger.Error("lock heartbeat failed during the migration, check no other migration ran concurrently",
+			"failures", heartbeat.failures, "error", heartbeat.lastErr)
+	}
+}
+
+func (d *databaseDriver) Lock() error {
+	if err := d.Driver.Lock(); err != nil {
+		return err
+	}
+
+	d.startHeartbeat()
+	return nil
+}
+
+func (d *databaseDriver) Unlock() error {
+	d.stopHeartbeat()
+	return d.Driver.Unlock()
+}
@@ -0,0 +1,339 @@
+package migrator
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4/database"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	historyTable = "schema_migrations_history"
+
+	historyFormatJSON = "json"
+	historyFormatCSV  = "csv"
+)
+
+const (
+	HistorySQLNone HistorySQLMode = iota
+	HistorySQLText
+	HistorySQLHash
+)
+
+var historyColumns = []string{
+	"version", "direction", "applied_at", "duration_ms", "success", "error", "sql_text", "sql_checksum", "sql_location",
+	"schema_checksum", "labels",
+}
+
+var (
+	errUnknownHistoryFormat = errors.New("history format must be json or csv")
+	errHistoryNotEmpty      = errors.New("history table is not empty, use replace to overwrite it")
+)
+
+// HistorySQLMode selects how much of the executed SQL is kept in the history table.
+type HistorySQLMode int
+
+// SQLStore keeps the SQL of applied migrations outside of the database, e.g. in object storage,
+// and returns where it was put.
+type SQLStore interface {
+	Put(checksum string, body []byte) (location string, err error)
+}
+
+type HistoryEntry struct {
+	Version        uint              `json:"version"`
+	Direction      string            `json:"direction"`
+	AppliedAt      time.Time         `json:"applied_at"`
+	DurationMs     int64             `json:"duration_ms"`
+	Success        bool              `json:"success"`
+	Error          string            `json:"error,omitempty"`
+	SQL            string            `json:"sql,omitempty"`
+	SQLChecksum    string            `json:"sql_checksum,omitempty"`
+	SQLLocation    string            `json:"sql_location,omitempty"`
+	SchemaChecksum string            `json:"schema_checksum,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+}
+
+// EnableHistory records every migration run, with its duration and outcome, in the history table.
+func (m *Migrator) EnableHistory() {
+	m.historyEnabled = true
+}
+
+// RecordHistorySQL also keeps the executed SQL of each run in the history: its full text with
+// HistorySQLText, or only its checksum with HistorySQLHash, in which case the text is handed
+// to store when one is given.
+func (m *Migrator) RecordHistorySQL(mode HistorySQLMode, store SQLStore) {
+	m.historySQLMode = mode
+	m.historySQLStore = store
+}
+
+func (m *Migrator) attachHistorySQL(entry *HistoryEntry, body []byte) {
+	if !m.historyEnabled {
+		return
+	}
+
+	switch m.historySQLMode {
+	case HistorySQLText:
+		entry.SQL = string(body)
+		entry.SQLChecksum = checksumOf(body)
+	case HistorySQLHash:
+		entry.SQLChecksum = checksumOf(body)
+		if m.historySQLStore == nil {
+			return
+		}
+
+		location, err := m.historySQLStore.Put(entry.SQLChecksum, body)
+		if err != nil {
+			m.logger.Error("can't store migration sql", "version", entry.Version, "error", err)
+			return
+		}
+		entry.SQLLocation = location
+	}
+}
+
+func (m *Migrator) ensureHistoryTable() error {
+	_, err := m.db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	version BIGINT NOT NULL,
+	direction VARCHAR(8) NOT NULL,
+	applied_at TIMESTAMP NOT NULL,
+	duration_ms BIGINT NOT NULL,
+	success BOOLEAN NOT NULL,
+	error TEXT,
+	sql_text TEXT,
+	sql_checksum VARCHAR(64),
+	sql_location TEXT,
+	schema_checksum VARCHAR(64),
+	labels TEXT
+)`, historyTable))
+	if err != nil {
+		return err
+	}
+
+	if err = m.ensureHistorySQLColumns(); err != nil {
+		return err
+	}
+	if err = m.ensureHistorySchemaColumn(); err != nil {
+		return err
+	}
+	return m.ensureLabelsColumn(historyTable)
+}
+
+// ensureHistorySQLColumns upgrades history tables created before the executed SQL was recorded.
+func (m *Migrator) ensureHistorySQLColumns() error {
+	rows, err := m.db.Query(fmt.Sprintf("SELECT sql_checksum FROM %s WHERE 1 = 0", historyTable))
+	if err == nil {
+		return rows.Close()
+	}
+
+	for _, column := range []string{"sql_text TEXT", "sql_checksum VARCHAR(64)", "sql_location TEXT"} {
+		if _, err = m.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", historyTable, column)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureHistorySchemaColumn upgrades history tables created before the schema checksum was recorded.
+func (m *Migrator) ensureHistorySchemaColumn() error {
+	rows, err := m.db.Query(fmt.Sprintf("SELECT schema_checksum FROM %s WHERE 1 = 0", historyTable))
+	if err == nil {
+		return rows.Close()
+	}
+
+	_, err = m.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN schema_checksum VARCHAR(64)", historyTable))
+	return err
+}
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func (m *Migrator) insertHistoryRow(db execer, entry *HistoryEntry) error {
+	d := m.dialect
+	placeholders := make([]string, len(historyColumns))
+	for i := range placeholders {
+		placeholders[i] = d.placeholder(i + 1)
+	}
+
+	_, err := db.Exec(fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		historyTable, strings.Join(historyColumns, ", "), strings.Join(placeholders, ", ")),
+		int64(entry.Version), entry.Direction, entry.AppliedAt.UTC(), entry.DurationMs, entry.Success, entry.Error,
+		entry.SQL, entry.SQLChecksum, entry.SQLLocation, entry.SchemaChecksum, encodeLabels(entry.Labels))
+	return err
+}
+
+func (m *Migrator) insertHistory(entry *HistoryEntry) error {
+	if err := m.ensureHistoryTable(); err != nil {
+		return err
+	}
+
+	return m.insertHistoryRow(m.db, entry)
+}
+
+func (m *Migrator) recordHistory(entry *HistoryEntry) {
+	if !m.historyEnabled || m.db == nil {
+		return
+	}
+
+	if err := m.insertHistory(entry); err != nil {
+		m.logger.Error("can't record migration history", "version", entry.Version, "error", err)
+	}
+}
+
+func (m *Migrator) History() ([]*HistoryEntry, error) {
+	if m.db == nil {
+		return nil, errNoDB
+	}
+
+	if err := m.ensureHistoryTable(); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.Query(fmt.Sprintf(
+		"SELECT %s FROM %s ORDER BY applied_at", strings.Join(historyColumns, ", "), historyTable))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]*HistoryEntry, 0)
+	for rows.Next() {
+		var (
+			entry                                                              = &HistoryEntry{}
+			version                                                            int64
+			errText, sqlText, sqlChecksum, sqlLocation, schemaChecksum, labels sql.NullString
+		)
+
+		err = rows.Scan(&version, &entry.Direction, &entry.AppliedAt, &entry.DurationMs, &entry.Success,
+			&errText, &sqlText, &sqlChecksum, &sqlLocation, &schemaChecksum, &labels)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.Version = uint(version)
+		entry.Error = errText.String
+		entry.SQL = sqlText.String
+		entry.SQLChecksum = sqlChecksum.String
+		entry.SQLLocation = sqlLocation.String
+		entry.SchemaChecksum = schemaChecksum.String
+		entry.Labels = decodeLabels(labels.String)
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+func (m *Migrator) ExportHistory(w io.Writer, format string) error {
+	if format != historyFormatJSON && format != historyFormatCSV {
+		return errUnknownHistoryFormat
+	}
+
+	entries, err := m.History()
+	if err != nil {
+		return err
+	}
+
+	if format == historyFormatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	}
+
+	writer := csv.NewWriter(w)
+	if err = writer.Write(historyColumns); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		err = writer.Write([]string{
+			strconv.FormatUint(uint64(entry.Version), 10),
+			entry.Direction,
+			entry.AppliedAt.UTC().Format(time.RFC3339),
+			strconv.FormatInt(entry.DurationMs, 10),
+			strconv.FormatBool(entry.Success),
+			entry.Error,
+			entry.SQL,
+			entry.SQLChecksum,
+			entry.SQLLocation,
+			entry.SchemaChecksum,
+			encodeLabels(entry.Labels),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// stateVersion is the highest version a history left applied.
+func stateVersion(entries []*HistoryEntry) int {
+	version := database.NilVersion
+	for v := range appliedVersions(entries) {
+		if int(v) > version {
+			version = int(v)
+		}
+	}
+	return version
+}
+
+// RestoreHistory seeds the history table and the version from a state exported as json by ExportHistory.
+func (m *Migrator) RestoreHistory(r io.Reader, replace bool) error {
+	if m.db == nil {
+		return errNoDB
+	}
+
+	var entries []*HistoryEntry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].AppliedAt.Before(entries[j].AppliedAt)
+	})
+
+	existing, err := m.History()
+	if err != nil {
+		return err
+	}
+
+	if len(existing) > 0 && !replace {
+		return errHistoryNotEmpty
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(fmt.Sprintf("DELETE FROM %s", historyTable))
+
+	for _, entry := range entries {
+		if err != nil {
+			break
+		}
+
+		err = m.insertHistoryRow(tx, entry)
+	}
+
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	version := stateVersion(entries)
+	m.logger.Info("restored migration history", "entries", len(entries), "version", version)
+
+	return m.Force(version)
+}
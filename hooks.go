@@ -0,0 +1,22 @@
+package migrator
+
+import "fmt"
+
+// OnVersion registers a hook that runs in its own transaction right after the up migration of
+// version is applied, e.g. to warm a cache or backfill data the new schema needs. A failing hook
+// stops the run, the migration itself stays applied.
+func (m *Migrator) OnVersion(version uint, hook GoMigrationFunc) {
+	if m.versionHooks == nil {
+		m.versionHooks = make(map[uint][]GoMigrationFunc)
+	}
+	m.versionHooks[version] = append(m.versionHooks[version], hook)
+}
+
+func (m *Migrator) runVersionHooks(version uint) error {
+	for i, hook := range m.versionHooks[version] {
+		if err := m.runInTx(hook); err != nil {
+			return fmt.Errorf("hook #%d of version %d: %w", i+1, version, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	importFromFlyway = "flyway"
+	importFromGoose  = "goose"
+)
+
+var errUnknownImportSource = errors.New("unknown import source")
+
+type importedFile struct {
+	from string
+	to   string
+	body []byte
+}
+
+func (m *Migrator) writeImportedFiles(files []*importedFile) error {
+	for _, file := range files {
+		if _, err := os.Stat(file.to); err == nil {
+			return fmt.Errorf("can't import %s: %s already exists", file.from, file.to)
+		}
+	}
+
+	for _, file := range files {
+		if err := os.WriteFile(file.to, file.body, 0666); err != nil {
+			return err
+		}
+		m.logger.Info("imported migration", "from", file.from, "to", file.to)
+	}
+
+	return nil
+}
+
+func (m *Migrator) importedPath(version uint, name, direction string) string {
+	return filepath.Join(m.migrationsFilePath, fmt.Sprintf("%d_%s.%s.sql", version, name, direction))
+}
+
+// Import converts the migrations another tool keeps in dir into this package's naming scheme,
+// writing them to the migrations directory.
+func (m *Migrator) Import(from string, dir string) error {
+	var (
+		files []*importedFile
+		err   error
+	)
+
+	switch from {
+	case importFromFlyway:
+		files, err = m.flywayImportedFiles(dir)
+	case importFromGoose:
+		files, err = m.gooseImportedFiles(dir)
+	default:
+		return fmt.Errorf("%w: %s", errUnknownImportSource, from)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return m.writeImportedFiles(files)
+}
+
+// ImportHistory sets the version of the database from the history table of another tool.
+func (m *Migrator) ImportHistory(from string, dir string) error {
+	switch from {
+	case importFromFlyway:
+		return m.importFlywayHistory(dir)
+	case importFromGoose:
+		return m.importGooseHistory()
+	default:
+		return fmt.Errorf("%w: %s", errUnknownImportSource, from)
+	}
+}
@@ -0,0 +1,85 @@
+package migrator
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const includeDirective = "include"
+
+var (
+	errIncludeCycle = errors.New("include cycle")
+	errIncludePath  = errors.New("include needs the path of a SQL file")
+)
+
+// expandIncludes replaces the -- migrator:include PATH lines of the file name of the directory dir
+// by the content of the file PATH, relative to the file including it, so that shared trigger or
+// function definitions are written once. Included files can include other ones, but not cycles.
+func expandIncludes(dir string, name string, body []byte) ([]byte, error) {
+	path, err := filepath.Abs(filepath.Join(dir, name))
+	if err != nil {
+		return nil, err
+	}
+	return expandFileIncludes(path, body, []string{path})
+}
+
+func expandFileIncludes(path string, body []byte, stack []string) ([]byte, error) {
+	if !bytes.Contains(body, []byte(directivePrefix+includeDirective)) {
+		return body, nil
+	}
+
+	var expanded bytes.Buffer
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(body)+1)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		rest, ok := strings.CutPrefix(strings.TrimSpace(line), directivePrefix+includeDirective)
+		if !ok || rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+			expanded.WriteString(line)
+			expanded.WriteString("\n")
+			continue
+		}
+
+		include := strings.TrimSpace(rest)
+		if include == "" {
+			return nil, fmt.Errorf("%w: %s", errIncludePath, path)
+		}
+
+		includePath := filepath.Join(filepath.Dir(path), filepath.FromSlash(include))
+		for _, including := range stack {
+			if including == includePath {
+				return nil, fmt.Errorf("%w: %s", errIncludeCycle, strings.Join(append(stack, includePath), " -> "))
+			}
+		}
+
+		included, err := os.ReadFile(includePath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		if included, err = normalizeEncoding(includePath, included); err != nil {
+			return nil, err
+		}
+
+		if included, err = expandFileIncludes(includePath, included, append(stack[:len(stack):len(stack)], includePath)); err != nil {
+			return nil, err
+		}
+
+		expanded.Write(included)
+		if len(included) > 0 && !bytes.HasSuffix(included, []byte("\n")) {
+			expanded.WriteString("\n")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return expanded.Bytes(), nil
+}
@@ -0,0 +1,112 @@
+package migrator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExpandIncludes(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"shared/trigger.sql":  "CREATE FUNCTION touch() RETURNS trigger;",
+		"shared/nested.sql":   "-- migrator:include trigger.sql\nCREATE TRIGGER t;\n",
+		"shared/cycle_a.sql":  "-- migrator:include cycle_b.sql\n",
+		"shared/cycle_b.sql":  "-- migrator:include cycle_a.sql\n",
+		"shared/utf16.sql":    "\xFF\xFEA\x00;\x00",
+		"shared/invalid.sql":  "A;\n\xFF\n",
+		"shared/no_final.sql": "B;",
+	}
+	for name, body := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want string
+		err  error
+	}{
+		{
+			name: "no include",
+			body: "SELECT 1;",
+			want: "SELECT 1;",
+		},
+		{
+			name: "include",
+			body: "A;\n-- migrator:include shared/trigger.sql\nB;\n",
+			want: "A;\nCREATE FUNCTION touch() RETURNS trigger;\nB;\n",
+		},
+		{
+			name: "nested include relative to the including file",
+			body: "  -- migrator:include shared/nested.sql\n",
+			want: "CREATE FUNCTION touch() RETURNS trigger;\nCREATE TRIGGER t;\n",
+		},
+		{
+			name: "included file without final newline",
+			body: "-- migrator:include shared/no_final.sql\nC;",
+			want: "B;\nC;\n",
+		},
+		{
+			name: "included UTF-16 file",
+			body: "-- migrator:include shared/utf16.sql\n",
+			want: "A;\n",
+		},
+		{
+			name: "other directive with the same prefix",
+			body: "-- migrator:includes shared/trigger.sql\n",
+			want: "-- migrator:includes shared/trigger.sql\n",
+		},
+		{
+			name: "cycle",
+			body: "-- migrator:include shared/cycle_a.sql\n",
+			err:  errIncludeCycle,
+		},
+		{
+			name: "self include",
+			body: "-- migrator:include 1_init.up.sql\n",
+			err:  errIncludeCycle,
+		},
+		{
+			name: "no path",
+			body: "-- migrator:include\n",
+			err:  errIncludePath,
+		},
+		{
+			name: "missing file",
+			body: "-- migrator:include shared/missing.sql\n",
+			err:  os.ErrNotExist,
+		},
+		{
+			name: "invalid UTF-8",
+			body: "-- migrator:include shared/invalid.sql\n",
+			err:  errEncoding,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := expandIncludes(dir, "1_init.up.sql", []byte(test.body))
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("err = %v, want %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
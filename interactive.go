@@ -0,0 +1,152 @@
+package migrator
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var (
+	errNotPending          = errors.New("migration isn't pending")
+	errSkipNeedsOutOfOrder = errors.New("skipping pending migrations before selected ones needs history and out-of-order migrations allowed")
+)
+
+// UpSelected applies the pending migrations of versions, skipping the other pending ones. Skipping
+// a migration older than a selected one moves the version past it, so that it's later applied as an
+// out-of-order migration: it needs history enabled and AllowOutOfOrder.
+func (m *Migrator) UpSelected(versions []uint) error {
+	plan, err := m.UpPlan(0)
+	if err != nil {
+		return err
+	}
+
+	pending := make(map[uint]bool, len(plan))
+	for _, planned := range plan {
+		pending[planned.Version] = true
+	}
+
+	selected := make(map[uint]bool, len(versions))
+	for _, version := range versions {
+		if !pending[version] {
+			return fmt.Errorf("%w: %d", errNotPending, version)
+		}
+		selected[version] = true
+	}
+
+	if len(selected) == 0 {
+		return migrate.ErrNoChange
+	}
+
+	// the pending migrations to run, up to the last selected one, in order
+	run := make([]*PlannedMigration, 0, len(plan))
+	skipped := false
+	for _, planned := range plan {
+		if len(run) == len(selected) {
+			break
+		}
+
+		if selected[planned.Version] {
+			run = append(run, planned)
+		} else {
+			skipped = true
+		}
+	}
+
+	if skipped && (!m.historyEnabled || !m.outOfOrderAllowed) {
+		return errSkipNeedsOutOfOrder
+	}
+
+	return m.audited("up", func() error {
+		if err := m.checkMonotonic(); err != nil {
+			return err
+		}
+
+		if err := m.checkDowns(run); err != nil {
+			return err
+		}
+
+		if err := m.checkLargeTables(run); err != nil {
+			return err
+		}
+
+		m.startProgress(len(run), -1)
+		for _, planned := range run {
+			current, _, err := m.migrate.Version()
+			if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+				return err
+			}
+
+			next, ok := m.source.migrations.First()
+			if err == nil {
+				next, ok = m.source.migrations.Next(current)
+			}
+
+			if !ok || next != planned.Version {
+				prev, _ := m.source.migrations.Prev(planned.Version)
+				m.logger.Info("skipping migrations", "to", planned.Version)
+				if err = m.migrate.Force(int(prev)); err != nil {
+					return err
+				}
+			}
+
+			if err = m.migrate.Steps(1); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// selectPending lets the operator deselect pending migrations of plan on the terminal, returning
+// the selected versions, nil when the operator quits.
+func (builder *migratorCobraCommandBuilder) selectPending(plan []*PlannedMigration) []uint {
+	selected := make([]bool, len(plan))
+	for i := range selected {
+		selected[i] = true
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		for i, planned := range plan {
+			box := "[ ]"
+			if selected[i] {
+				box = "[x]"
+			}
+			fmt.Printf("%3d %s %d %s\n", i+1, box, planned.Version, planned.Identifier)
+		}
+		fmt.Print("Toggle migrations by number (e.g. 2 3), enter to apply the selected ones, q to quit: ")
+
+		if !scanner.Scan() {
+			fmt.Println()
+			return nil
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		switch line {
+		case "":
+			versions := make([]uint, 0, len(plan))
+			for i, planned := range plan {
+				if selected[i] {
+					versions = append(versions, planned.Version)
+				}
+			}
+			return versions
+		case "q":
+			return nil
+		}
+
+		for _, field := range strings.Fields(line) {
+			n, err := strconv.Atoi(field)
+			if err != nil || n < 1 || n > len(plan) {
+				fmt.Printf("no migration %s\n", field)
+				continue
+			}
+			selected[n-1] = !selected[n-1]
+		}
+		fmt.Println()
+	}
+}
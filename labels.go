@@ -0,0 +1,72 @@
+package migrator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var errLabel = errors.New("labels must be key=value")
+
+// WithLabels see SetLabels.
+func WithLabels(labels map[string]string) Option {
+	return func(m *Migrator) {
+		m.SetLabels(labels)
+	}
+}
+
+// SetLabels tags the runs of the migrator, e.g. with who deployed which commit, in the history and
+// audit tables, the reports, the JSON logs and the notifications.
+func (m *Migrator) SetLabels(labels map[string]string) {
+	m.labels = mergeParams(nil, labels)
+}
+
+// ParseLabels reads key=value labels, a later key replacing an earlier one.
+func ParseLabels(pairs []string) (map[string]string, error) {
+	labels := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%w: %s", errLabel, pair)
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// encodeLabels returns labels as the JSON kept in the labels columns, empty when there are none.
+func encodeLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	text, err := json.Marshal(labels)
+	if err != nil {
+		return ""
+	}
+	return string(text)
+}
+
+func decodeLabels(text string) map[string]string {
+	if text == "" {
+		return nil
+	}
+
+	var labels map[string]string
+	if err := json.Unmarshal([]byte(text), &labels); err != nil {
+		return nil
+	}
+	return labels
+}
+
+// ensureLabelsColumn upgrades history and audit tables created before labels were recorded.
+func (m *Migrator) ensureLabelsColumn(table string) error {
+	rows, err := m.db.Query(fmt.Sprintf("SELECT labels FROM %s WHERE 1 = 0", table))
+	if err == nil {
+		return rows.Close()
+	}
+
+	_, err = m.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN labels TEXT", table))
+	return err
+}
@@ -0,0 +1,168 @@
+package migrator
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	LargeTableWarn       = "warn"
+	LargeTableRequireAck = "require-ack"
+
+	postgresTableSizeQuery = `SELECT pg_total_relation_size(c.oid), c.reltuples::bigint FROM pg_catalog.pg_class c WHERE c.oid = to_regclass($1)`
+	mysqlTableSizeQuery    = `SELECT COALESCE(DATA_LENGTH + INDEX_LENGTH, 0), COALESCE(TABLE_ROWS, 0) FROM information_schema.TABLES
+WHERE TABLE_SCHEMA = %s AND TABLE_NAME = ?`
+)
+
+var (
+	errLargeTable       = errors.New("migrations rewrite large tables without being acknowledged")
+	errLargeTableAction = errors.New("large table action must be warn or require-ack")
+)
+
+// LargeTableAlter is a pending ALTER TABLE rewriting or scanning a table larger than the threshold.
+type LargeTableAlter struct {
+	Version   uint
+	Table     string
+	Bytes     int64
+	Rows      int64
+	Statement string
+}
+
+// SetLargeTableCheck looks up the size of the tables the pending ALTER TABLE statements rewrite
+// before up and apply, when threshold is above 0 bytes. Larger tables are logged with the warn
+// action, and fail the run until AcknowledgeLargeTables is called with the require-ack action.
+func (m *Migrator) SetLargeTableCheck(threshold int64, action string) error {
+	if action != LargeTableWarn && action != LargeTableRequireAck {
+		return fmt.Errorf("%w: %s", errLargeTableAction, action)
+	}
+
+	m.largeTableLimit = threshold
+	m.largeTableAction = action
+	return nil
+}
+
+func WithLargeTableCheck(threshold int64, action string) Option {
+	return func(m *Migrator) {
+		m.largeTableLimit = threshold
+		m.largeTableAction = action
+	}
+}
+
+// AcknowledgeLargeTables lets the migrations rewriting large tables run, see SetLargeTableCheck.
+func (m *Migrator) AcknowledgeLargeTables() {
+	m.largeTableAcked = true
+}
+
+// rewrittenTable returns the table an ALTER TABLE statement rewrites or scans while holding its
+// lock: a type change, a new NOT NULL or validated constraint, or a column with a default on
+// postgres, any ALTER TABLE but the ALGORITHM=INSTANT ones on mysql.
+func rewrittenTable(d Dialect, statement string) (string, bool) {
+	match := alterTableLockRegexp.FindStringSubmatch(strings.TrimSpace(stripComments(statement)))
+	if match == nil {
+		return "", false
+	}
+	actions := strings.ToUpper(strings.Join(strings.Fields(match[2]), " "))
+
+	switch d {
+	case DialectPostgres:
+		rewrites := strings.Contains(actions, " TYPE ") || strings.Contains(actions, "SET NOT NULL") ||
+			(strings.Contains(actions, "ADD COLUMN") && strings.Contains(actions, "DEFAULT")) ||
+			((strings.Contains(actions, "ADD CONSTRAINT") || strings.Contains(actions, "FOREIGN KEY")) && !strings.Contains(actions, "NOT VALID"))
+		return match[1], rewrites
+	case DialectMySQL:
+		instant := strings.Contains(strings.ReplaceAll(actions, " ", ""), "ALGORITHM=INSTANT")
+		return match[1], !instant
+	default:
+		return "", false
+	}
+}
+
+func unquoteIdentifier(identifier string) string {
+	return strings.Trim(identifier, "\"`")
+}
+
+// tableSize returns the size in bytes and the estimated row count of table, false when it
+// doesn't exist yet.
+func (m *Migrator) tableSize(table string) (int64, int64, bool, error) {
+	var bytes, rows int64
+	var err error
+
+	switch m.dialect {
+	case DialectPostgres:
+		err = m.db.QueryRow(postgresTableSizeQuery, table).Scan(&bytes, &rows)
+	case DialectMySQL:
+		schema, name, qualified := strings.Cut(table, ".")
+		if qualified {
+			err = m.db.QueryRow(fmt.Sprintf(mysqlTableSizeQuery, "?"), unquoteIdentifier(schema), unquoteIdentifier(name)).Scan(&bytes, &rows)
+		} else {
+			err = m.db.QueryRow(fmt.Sprintf(mysqlTableSizeQuery, "DATABASE()"), unquoteIdentifier(table)).Scan(&bytes, &rows)
+		}
+	default:
+		return 0, 0, false, nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, false, nil
+	}
+	return bytes, rows, err == nil, err
+}
+
+// LargeTableAlters returns the statements of plan rewriting tables larger than the threshold of
+// SetLargeTableCheck.
+func (m *Migrator) LargeTableAlters(plan []*PlannedMigration) ([]*LargeTableAlter, error) {
+	if m.db == nil {
+		return nil, errNoDB
+	}
+
+	alters := make([]*LargeTableAlter, 0)
+	for _, planned := range plan {
+		statements, err := m.plannedStatements(planned)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, statement := range statements {
+			table, ok := rewrittenTable(m.dialect, statement)
+			if !ok {
+				continue
+			}
+
+			bytes, rows, exists, err := m.tableSize(table)
+			if err != nil {
+				return nil, fmt.Errorf("can't get the size of table %s: %w", table, err)
+			}
+
+			if exists && bytes >= m.largeTableLimit {
+				alters = append(alters, &LargeTableAlter{Version: planned.Version, Table: table, Bytes: bytes, Rows: rows,
+					Statement: strings.TrimSpace(stripComments(statement))})
+			}
+		}
+	}
+
+	return alters, nil
+}
+
+// checkLargeTables logs the pending statements rewriting large tables, failing with
+// errLargeTable when they need an acknowledgement.
+func (m *Migrator) checkLargeTables(plan []*PlannedMigration) error {
+	if m.largeTableLimit <= 0 {
+		return nil
+	}
+
+	alters, err := m.LargeTableAlters(plan)
+	if err != nil {
+		return err
+	}
+
+	for _, alter := range alters {
+		m.logger.Error("migration rewrites a large table", "version", alter.Version, "table", alter.Table,
+			"size", fmt.Sprintf("%d MB", alter.Bytes>>20), "rows", alter.Rows)
+	}
+
+	if len(alters) > 0 && m.largeTableAction == LargeTableRequireAck && !m.largeTableAcked {
+		return errLargeTable
+	}
+	return nil
+}
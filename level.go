@@ -0,0 +1,62 @@
+package migrator
+
+import (
+	"fmt"
+	"strings"
+)
+
+type LogLevel int
+
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
+
+var logLevelNames = map[string]LogLevel{
+	"debug": LogLevelDebug,
+	"info":  LogLevelInfo,
+	"warn":  LogLevelWarn,
+	"error": LogLevelError,
+}
+
+func ParseLogLevel(level string) (LogLevel, error) {
+	if l, ok := logLevelNames[strings.ToLower(level)]; ok {
+		return l, nil
+	}
+	return LogLevelInfo, fmt.Errorf("log level must be debug, info, warn or error, got %s", level)
+}
+
+// leveledLogger drops the messages of a Logger below its level. The Logger interface has no
+// debug or warn methods, so debug turns on the verbose output of golang-migrate and warn keeps
+// errors only.
+type leveledLogger struct {
+	Logger
+	level LogLevel
+}
+
+func (l *leveledLogger) Printf(format string, v ...interface{}) {
+	if l.level <= LogLevelInfo {
+		l.Logger.Printf(format, v...)
+	}
+}
+
+func (l *leveledLogger) Verbose() bool {
+	return l.level == LogLevelDebug || l.Logger.Verbose()
+}
+
+func (l *leveledLogger) Info(msg string, keyAndValues ...interface{}) {
+	if l.level <= LogLevelInfo {
+		l.Logger.Info(msg, keyAndValues...)
+	}
+}
+
+func (m *Migrator) SetLogLevel(level LogLevel) {
+	logger := m.logger
+	if leveled, ok := logger.(*leveledLogger); ok {
+		logger = leveled.Logger
+	}
+
+	m.SetLogger(&leveledLogger{Logger: logger, level: level})
+}
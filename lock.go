@@ -0,0 +1,106 @@
+package migrator
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4/database"
+	"time"
+)
+
+var errNotLocked = errors.New("migration lock is not held")
+
+type LockStatus struct {
+	LockID      string     `json:"lock_id"`
+	Held        bool       `json:"held"`
+	PID         int64      `json:"pid,omitempty"`
+	User        string     `json:"user,omitempty"`
+	Application string     `json:"application,omitempty"`
+	ClientAddr  string     `json:"client_addr,omitempty"`
+	SessionFrom *time.Time `json:"session_from,omitempty"`
+}
+
+const postgresLockQuery = `SELECT a.pid, COALESCE(a.usename, ''), COALESCE(a.application_name, ''),
+	COALESCE(host(a.client_addr), ''), a.backend_start
+FROM pg_locks l JOIN pg_stat_activity a ON a.pid = l.pid
+WHERE l.locktype = 'advisory' AND l.granted AND l.classid = 0 AND l.objid = $1::bigint AND l.objsubid = 1`
+
+const mysqlLockQuery = `SELECT p.ID, COALESCE(p.USER, ''), COALESCE(p.HOST, '')
+FROM information_schema.PROCESSLIST p WHERE p.ID = IS_USED_LOCK(?)`
+
+// lockID computes the advisory lock id the postgres and mysql drivers of golang-migrate take.
+func (m *Migrator) lockID() (string, error) {
+	switch m.dialect {
+	case DialectPostgres:
+		var databaseName, schemaName string
+		if err := m.db.QueryRow("SELECT current_database(), current_schema()").Scan(&databaseName, &schemaName); err != nil {
+			return "", err
+		}
+		return database.GenerateAdvisoryLockId(databaseName, schemaName, m.migrationsTable)
+	case DialectMySQL:
+		var databaseName string
+		if err := m.db.QueryRow("SELECT DATABASE()").Scan(&databaseName); err != nil {
+			return "", err
+		}
+		return database.GenerateAdvisoryLockId(fmt.Sprintf("%s:%s", databaseName, m.migrationsTable))
+	default:
+		return "", errUnsupportedDialect
+	}
+}
+
+// LockStatus reports whether the migration lock is held, and by which session.
+func (m *Migrator) LockStatus() (*LockStatus, error) {
+	if m.db == nil {
+		return nil, errNoDB
+	}
+
+	lockID, err := m.lockID()
+	if err != nil {
+		return nil, err
+	}
+
+	status := &LockStatus{LockID: lockID}
+	switch m.dialect {
+	case DialectPostgres:
+		var sessionFrom time.Time
+		err = m.db.QueryRow(postgresLockQuery, lockID).Scan(&status.PID, &status.User, &status.Application, &status.ClientAddr, &sessionFrom)
+		status.SessionFrom = &sessionFrom
+	case DialectMySQL:
+		err = m.db.QueryRow(mysqlLockQuery, lockID).Scan(&status.PID, &status.User, &status.ClientAddr)
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return &LockStatus{LockID: lockID}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	status.Held = true
+	return status, nil
+}
+
+// ForceUnlock clears a stale migration lock by terminating the session holding it, which rolls back
+// whatever that session was doing. Only use it once sure no migration is running.
+func (m *Migrator) ForceUnlock() (*LockStatus, error) {
+	status, err := m.LockStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	if !status.Held {
+		return status, errNotLocked
+	}
+
+	switch m.dialect {
+	case DialectPostgres:
+		var terminated bool
+		if err = m.db.QueryRow("SELECT pg_terminate_backend($1)", status.PID).Scan(&terminated); err == nil && !terminated {
+			err = fmt.Errorf("can't terminate session %d", status.PID)
+		}
+	case DialectMySQL:
+		_, err = m.db.Exec(fmt.Sprintf("KILL %d", status.PID))
+	}
+	return status, err
+}
@@ -0,0 +1,219 @@
+package migrator
+
+import (
+	"fmt"
+	"github.com/golang-migrate/migrate/v4/source"
+	"regexp"
+	"strings"
+)
+
+const (
+	LockAccessExclusive      = "ACCESS EXCLUSIVE"
+	LockExclusive            = "EXCLUSIVE"
+	LockShareRowExclusive    = "SHARE ROW EXCLUSIVE"
+	LockShare                = "SHARE"
+	LockShareUpdateExclusive = "SHARE UPDATE EXCLUSIVE"
+	LockRowExclusive         = "ROW EXCLUSIVE"
+)
+
+// lockBlocks tells what each postgres table lock keeps other sessions from doing while it is held.
+var lockBlocks = map[string]string{
+	LockAccessExclusive:      "blocks reads and writes",
+	LockExclusive:            "blocks writes, allows reads",
+	LockShareRowExclusive:    "blocks writes",
+	LockShare:                "blocks writes",
+	LockShareUpdateExclusive: "blocks other schema changes and vacuum, allows reads and writes",
+	LockRowExclusive:         "blocks schema changes only",
+}
+
+const identPattern = `((?:"[^"]+"|` + "`[^`]+`" + `|[\w$]+)(?:\.(?:"[^"]+"|` + "`[^`]+`" + `|[\w$]+))?)`
+
+var (
+	createIndexConcurrentlyRegexp = regexp.MustCompile(`(?is)^CREATE\s+(?:UNIQUE\s+)?INDEX\s+CONCURRENTLY\b.*?\bON\s+(?:ONLY\s+)?` + identPattern)
+	createIndexRegexp             = regexp.MustCompile(`(?is)^CREATE\s+(?:UNIQUE\s+)?INDEX\b.*?\bON\s+(?:ONLY\s+)?` + identPattern)
+	dropIndexConcurrentlyRegexp   = regexp.MustCompile(`(?is)^DROP\s+INDEX\s+CONCURRENTLY\s+(?:IF\s+EXISTS\s+)?` + identPattern)
+	dropIndexRegexp               = regexp.MustCompile(`(?is)^DROP\s+INDEX\s+(?:IF\s+EXISTS\s+)?` + identPattern)
+	alterTableLockRegexp          = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?` + identPattern + `\s+(.*)$`)
+	dropTableRegexp               = regexp.MustCompile(`(?is)^DROP\s+TABLE\s+(?:IF\s+EXISTS\s+)?` + identPattern)
+	truncateRegexp                = regexp.MustCompile(`(?is)^TRUNCATE\s+(?:TABLE\s+)?(?:ONLY\s+)?` + identPattern)
+	createTableRegexp             = regexp.MustCompile(`(?is)^CREATE\s+(?:UNLOGGED\s+|TEMP(?:ORARY)?\s+)?TABLE\b`)
+	referencesRegexp              = regexp.MustCompile(`(?is)\bREFERENCES\s+` + identPattern)
+	writeRegexp                   = regexp.MustCompile(`(?is)^(?:INSERT\s+INTO|UPDATE|DELETE\s+FROM)\s+(?:ONLY\s+)?` + identPattern)
+	reindexRegexp                 = regexp.MustCompile(`(?is)^REINDEX\s+(?:\(.*?\)\s+)?(INDEX|TABLE)\s+(CONCURRENTLY\s+)?` + identPattern)
+	vacuumFullRegexp              = regexp.MustCompile(`(?is)^VACUUM\s+(?:\([^)]*\bFULL\b[^)]*\)|FULL\b)`)
+	clusterRegexp                 = regexp.MustCompile(`(?is)^CLUSTER\s+(?:VERBOSE\s+)?` + identPattern)
+	refreshRegexp                 = regexp.MustCompile(`(?is)^REFRESH\s+MATERIALIZED\s+VIEW\s+(CONCURRENTLY\s+)?` + identPattern)
+	lockTableRegexp               = regexp.MustCompile(`(?is)^LOCK\s+(?:TABLE\s+)?(?:ONLY\s+)?` + identPattern + `(?:.*?\bIN\s+(.+?)\s+MODE)?`)
+)
+
+// LockImpact is the table lock a statement of a migration takes, on postgres.
+type LockImpact struct {
+	Version   uint     `json:"version"`
+	Direction string   `json:"direction"`
+	Statement string   `json:"statement"`
+	Lock      string   `json:"lock"`
+	Tables    []string `json:"tables"`
+	Blocks    string   `json:"blocks"`
+	Note      string   `json:"note,omitempty"`
+}
+
+// alterTableLock returns the lock and a note for the actions of an ALTER TABLE.
+func alterTableLock(actions string) (string, string) {
+	upper := strings.ToUpper(strings.Join(strings.Fields(actions), " "))
+
+	switch {
+	case strings.Contains(upper, "VALIDATE CONSTRAINT"):
+		return LockShareUpdateExclusive, "scans the table without blocking writes"
+	case strings.Contains(upper, "SET STATISTICS"):
+		return LockShareUpdateExclusive, ""
+	case strings.Contains(upper, "FOREIGN KEY") || strings.Contains(upper, " REFERENCES "):
+		if strings.Contains(upper, "NOT VALID") {
+			return LockShareRowExclusive, ""
+		}
+		return LockShareRowExclusive, "checks every row while holding the lock, consider NOT VALID then VALIDATE CONSTRAINT"
+	}
+
+	note := ""
+	switch {
+	case strings.Contains(upper, " TYPE "):
+		note = "may rewrite the table while holding the lock"
+	case strings.Contains(upper, "SET NOT NULL"):
+		note = "scans the table while holding the lock"
+	case strings.Contains(upper, "ADD CONSTRAINT") && !strings.Contains(upper, "NOT VALID"):
+		note = "checks every row while holding the lock, consider NOT VALID then VALIDATE CONSTRAINT"
+	case strings.Contains(upper, "ADD COLUMN") && strings.Contains(upper, "DEFAULT"):
+		note = "rewrites the table when the default is volatile"
+	}
+	return LockAccessExclusive, note
+}
+
+func referencedTables(statement string) []string {
+	tables := make([]string, 0)
+	for _, match := range referencesRegexp.FindAllStringSubmatch(statement, -1) {
+		tables = append(tables, match[1])
+	}
+	return tables
+}
+
+// analyzeLock returns the table lock statement takes on postgres, nil when it takes none worth
+// reporting, such as the creation of a table nothing references.
+func analyzeLock(statement string) *LockImpact {
+	statement = strings.TrimSpace(stripComments(statement))
+
+	impact := func(lock string, note string, tables ...string) *LockImpact {
+		return &LockImpact{Statement: statement, Lock: lock, Tables: tables, Blocks: lockBlocks[lock], Note: note}
+	}
+
+	if match := createIndexConcurrentlyRegexp.FindStringSubmatch(statement); match != nil {
+		return impact(LockShareUpdateExclusive, "", match[1])
+	}
+	if match := createIndexRegexp.FindStringSubmatch(statement); match != nil {
+		return impact(LockShare, "consider CREATE INDEX CONCURRENTLY", match[1])
+	}
+	if match := dropIndexConcurrentlyRegexp.FindStringSubmatch(statement); match != nil {
+		return impact(LockShareUpdateExclusive, "", match[1])
+	}
+	if match := dropIndexRegexp.FindStringSubmatch(statement); match != nil {
+		return impact(LockAccessExclusive, "locks the table of the index, consider DROP INDEX CONCURRENTLY", match[1])
+	}
+	if match := alterTableLockRegexp.FindStringSubmatch(statement); match != nil {
+		lock, note := alterTableLock(match[2])
+		tables := append([]string{match[1]}, referencedTables(match[2])...)
+		return impact(lock, note, tables...)
+	}
+	if match := dropTableRegexp.FindStringSubmatch(statement); match != nil {
+		return impact(LockAccessExclusive, "", match[1])
+	}
+	if match := truncateRegexp.FindStringSubmatch(statement); match != nil {
+		return impact(LockAccessExclusive, "", match[1])
+	}
+	if createTableRegexp.MatchString(statement) {
+		if tables := referencedTables(statement); len(tables) > 0 {
+			return impact(LockShareRowExclusive, "locks the referenced tables", tables...)
+		}
+		return nil
+	}
+	if match := writeRegexp.FindStringSubmatch(statement); match != nil {
+		return impact(LockRowExclusive, "", match[1])
+	}
+	if match := reindexRegexp.FindStringSubmatch(statement); match != nil {
+		switch {
+		case match[2] != "":
+			return impact(LockShareUpdateExclusive, "", match[3])
+		case strings.EqualFold(match[1], "INDEX"):
+			return impact(LockAccessExclusive, "blocks reads using the index, consider REINDEX CONCURRENTLY", match[3])
+		default:
+			return impact(LockShare, "consider REINDEX CONCURRENTLY", match[3])
+		}
+	}
+	if vacuumFullRegexp.MatchString(statement) {
+		return impact(LockAccessExclusive, "rewrites the vacuumed tables")
+	}
+	if match := clusterRegexp.FindStringSubmatch(statement); match != nil {
+		return impact(LockAccessExclusive, "rewrites the table", match[1])
+	}
+	if match := refreshRegexp.FindStringSubmatch(statement); match != nil {
+		if match[1] != "" {
+			return impact(LockExclusive, "", match[2])
+		}
+		return impact(LockAccessExclusive, "consider REFRESH MATERIALIZED VIEW CONCURRENTLY", match[2])
+	}
+	if match := lockTableRegexp.FindStringSubmatch(statement); match != nil {
+		lock := strings.ToUpper(strings.Join(strings.Fields(match[2]), " "))
+		if lock == "" {
+			lock = LockAccessExclusive
+		}
+		return impact(lock, "", match[1])
+	}
+	return nil
+}
+
+// plannedStatements returns the statements of the SQL migration planned, none for Go migrations.
+func (m *Migrator) plannedStatements(planned *PlannedMigration) ([]string, error) {
+	if planned.Missing {
+		return nil, nil
+	}
+
+	var migration *source.Migration
+	var ok bool
+	if planned.Direction == directionDown {
+		migration, ok = m.source.migrations.Down(planned.Version)
+	} else {
+		migration, ok = m.source.migrations.Up(planned.Version)
+	}
+	if !ok || migration.Raw == "" {
+		return nil, nil
+	}
+
+	body, err := m.source.readFile(migration)
+	if err != nil {
+		return nil, err
+	}
+	return splitStatements(string(body), m.statementTerminator()), nil
+}
+
+// AnalyzeLocks returns the table locks taken by the statements of the SQL migrations of plan, so
+// operators know which tables a run blocks and how. Only postgres is supported.
+func (m *Migrator) AnalyzeLocks(plan []*PlannedMigration) ([]*LockImpact, error) {
+	if m.dialect != DialectPostgres {
+		return nil, fmt.Errorf("%w: lock analysis only supports postgres", errUnsupportedDialect)
+	}
+
+	impacts := make([]*LockImpact, 0)
+	for _, planned := range plan {
+		statements, err := m.plannedStatements(planned)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, statement := range statements {
+			if impact := analyzeLock(statement); impact != nil {
+				impact.Version = planned.Version
+				impact.Direction = planned.Direction
+				impacts = append(impacts, impact)
+			}
+		}
+	}
+
+	return impacts, nil
+}
@@ -0,0 +1,54 @@
+package logrusadapter
+
+import (
+	"fmt"
+	migrator "github.com/anyufly/file-migrator"
+	"github.com/sirupsen/logrus"
+	"strings"
+)
+
+type logger struct {
+	logger  logrus.FieldLogger
+	verbose bool
+}
+
+// New adapts a logrus logger, or entry, to migrator.Logger.
+func New(l logrus.FieldLogger) migrator.Logger {
+	return &logger{logger: l}
+}
+
+func fields(keyAndValues []interface{}) logrus.Fields {
+	f := make(logrus.Fields, len(keyAndValues)/2)
+	for i := 0; i+1 < len(keyAndValues); i += 2 {
+		f[fmt.Sprint(keyAndValues[i])] = keyAndValues[i+1]
+	}
+
+	if len(keyAndValues)%2 == 1 {
+		f["!BADKEY"] = keyAndValues[len(keyAndValues)-1]
+	}
+	return f
+}
+
+func (l *logger) Printf(format string, v ...interface{}) {
+	l.logger.Info(strings.TrimSuffix(fmt.Sprintf(format, v...), "\n"))
+}
+
+func (l *logger) Verbose() bool {
+	return l.verbose
+}
+
+func (l *logger) SetVerbose(b bool) {
+	l.verbose = b
+}
+
+func (l *logger) Info(msg string, keyAndValues ...interface{}) {
+	l.logger.WithFields(fields(keyAndValues)).Info(msg)
+}
+
+func (l *logger) Error(msg string, keyAndValues ...interface{}) {
+	l.logger.WithFields(fields(keyAndValues)).Error(msg)
+}
+
+func (l *logger) Fatal(msg string, keyAndValues ...interface{}) {
+	l.logger.WithFields(fields(keyAndValues)).Fatal(msg)
+}
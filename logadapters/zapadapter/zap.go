@@ -0,0 +1,42 @@
+package zapadapter
+
+import (
+	"fmt"
+	migrator "github.com/anyufly/file-migrator"
+	"go.uber.org/zap"
+	"strings"
+)
+
+type logger struct {
+	logger  *zap.SugaredLogger
+	verbose bool
+}
+
+// New adapts a zap logger to migrator.Logger.
+func New(l *zap.Logger) migrator.Logger {
+	return &logger{logger: l.Sugar()}
+}
+
+func (l *logger) Printf(format string, v ...interface{}) {
+	l.logger.Info(strings.TrimSuffix(fmt.Sprintf(format, v...), "\n"))
+}
+
+func (l *logger) Verbose() bool {
+	return l.verbose
+}
+
+func (l *logger) SetVerbose(b bool) {
+	l.verbose = b
+}
+
+func (l *logger) Info(msg string, keyAndValues ...interface{}) {
+	l.logger.Infow(msg, keyAndValues...)
+}
+
+func (l *logger) Error(msg string, keyAndValues ...interface{}) {
+	l.logger.Errorw(msg, keyAndValues...)
+}
+
+func (l *logger) Fatal(msg string, keyAndValues ...interface{}) {
+	l.logger.Fatalw(msg, keyAndValues...)
+}
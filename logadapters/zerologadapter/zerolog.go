@@ -0,0 +1,42 @@
+package zerologadapter
+
+import (
+	"fmt"
+	migrator "github.com/anyufly/file-migrator"
+	"github.com/rs/zerolog"
+	"strings"
+)
+
+type logger struct {
+	logger  zerolog.Logger
+	verbose bool
+}
+
+// New adapts a zerolog logger to migrator.Logger.
+func New(l zerolog.Logger) migrator.Logger {
+	return &logger{logger: l}
+}
+
+func (l *logger) Printf(format string, v ...interface{}) {
+	l.logger.Info().Msg(strings.TrimSuffix(fmt.Sprintf(format, v...), "\n"))
+}
+
+func (l *logger) Verbose() bool {
+	return l.verbose
+}
+
+func (l *logger) SetVerbose(b bool) {
+	l.verbose = b
+}
+
+func (l *logger) Info(msg string, keyAndValues ...interface{}) {
+	l.logger.Info().Fields(keyAndValues).Msg(msg)
+}
+
+func (l *logger) Error(msg string, keyAndValues ...interface{}) {
+	l.logger.Error().Fields(keyAndValues).Msg(msg)
+}
+
+func (l *logger) Fatal(msg string, keyAndValues ...interface{}) {
+	l.logger.Fatal().Fields(keyAndValues).Msg(msg)
+}
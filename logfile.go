@@ -0,0 +1,139 @@
+package migrator
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// rotatingFile appends to a log file, moving it to path.1, path.2, ... once it grows past maxBytes.
+type rotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxBytes   int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func openRotatingFile(path string, maxBytes int64, maxBackups int) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxBytes: maxBytes, maxBackups: maxBackups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+
+	if r.maxBackups <= 0 {
+		if err := os.Remove(r.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return r.open()
+	}
+
+	for i := r.maxBackups - 1; i > 0; i-- {
+		if err := os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if err := os.Rename(r.path, r.path+".1"); err != nil {
+		return err
+	}
+	return r.open()
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.maxBytes > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxBytes {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.file.Close()
+}
+
+// teeLogger sends every message to both loggers.
+type teeLogger struct {
+	Logger
+	file Logger
+}
+
+func (t *teeLogger) Printf(format string, v ...interface{}) {
+	t.file.Printf(format, v...)
+	t.Logger.Printf(format, v...)
+}
+
+func (t *teeLogger) SetVerbose(b bool) {
+	t.file.SetVerbose(b)
+	t.Logger.SetVerbose(b)
+}
+
+func (t *teeLogger) Info(msg string, keyAndValues ...interface{}) {
+	t.file.Info(msg, keyAndValues...)
+	t.Logger.Info(msg, keyAndValues...)
+}
+
+func (t *teeLogger) Error(msg string, keyAndValues ...interface{}) {
+	t.file.Error(msg, keyAndValues...)
+	t.Logger.Error(msg, keyAndValues...)
+}
+
+func (t *teeLogger) Fatal(msg string, keyAndValues ...interface{}) {
+	t.file.Error(msg, keyAndValues...)
+	t.Logger.Fatal(msg, keyAndValues...)
+}
+
+// SetLogFile also writes the log to path, rotating it once it grows past maxBytes (never when 0)
+// and keeping maxBackups rotated files.
+func (m *Migrator) SetLogFile(path string, maxBytes int64, maxBackups int) error {
+	file, err := openRotatingFile(path, maxBytes, maxBackups)
+	if err != nil {
+		return err
+	}
+
+	if m.logFile != nil {
+		_ = m.logFile.Close()
+	}
+	m.logFile = file
+
+	fileLogger := newFormatLogger(file, m.logFormat)
+	fileLogger.SetVerbose(m.logger.Verbose())
+
+	m.SetLogger(&teeLogger{Logger: m.logger, file: fileLogger})
+	return nil
+}
@@ -0,0 +1,71 @@
+package migrator
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+func newFormatLogger(w io.Writer, format string) Logger {
+	if format == LogFormatJSON {
+		return NewSlogLogger(slog.New(slog.NewJSONHandler(w, nil)).With("logger", "migrator"))
+	}
+	return newMigrateLogger(w)
+}
+
+// SetLogFormat replaces the logger with one writing text or JSON lines to stdout. In JSON mode every
+// migration and command is also logged as an event with its version, direction, duration and error.
+func (m *Migrator) SetLogFormat(format string) error {
+	if format != LogFormatText && format != LogFormatJSON {
+		return fmt.Errorf("log format must be text or json, got %s", format)
+	}
+
+	logger := newFormatLogger(os.Stdout, format)
+	logger.SetVerbose(m.logger.Verbose())
+
+	m.logFormat = format
+	m.SetLogger(logger)
+	return nil
+}
+
+func (m *Migrator) logMigration(entry *HistoryEntry) {
+	if m.logFormat != LogFormatJSON {
+		return
+	}
+
+	fields := []interface{}{
+		"command", m.command, "version", entry.Version, "direction", entry.Direction, "duration_ms", entry.DurationMs,
+	}
+
+	if !entry.Success {
+		m.logger.Error("migration failed", append(fields, "error", entry.Error)...)
+		return
+	}
+	m.logger.Info("migration applied", fields...)
+}
+
+func (m *Migrator) logCommand(record *AuditRecord) {
+	if m.logFormat != LogFormatJSON {
+		return
+	}
+
+	fields := []interface{}{
+		"command", record.Command, "from_version", record.FromVersion, "version", record.ToVersion,
+		"duration_ms", record.FinishedAt.Sub(record.StartedAt).Milliseconds(),
+	}
+	if len(record.Labels) > 0 {
+		fields = append(fields, "labels", record.Labels)
+	}
+
+	if !record.Success {
+		m.logger.Error("command failed", append(fields, "error", record.Error)...)
+		return
+	}
+	m.logger.Info("command finished", fields...)
+}
@@ -2,8 +2,11 @@ package migrator
 
 import (
 	"fmt"
-	"github.com/anyufly/logger/loggers"
 	"github.com/golang-migrate/migrate/v4"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
 )
 
 type Logger interface {
@@ -14,35 +17,43 @@ type Logger interface {
 	Fatal(msg string, keyAndValues ...interface{})
 }
 
-var defaultLogger = &migrateLogger{
-	logger: loggers.Logger.Name("migrator"),
+var defaultLogger = newMigrateLogger(os.Stdout)
+
+func newMigrateLogger(w io.Writer) Logger {
+	return NewSlogLogger(slog.New(slog.NewTextHandler(w, nil)).With("logger", "migrator"))
+}
+
+// NewSlogLogger adapts a log/slog logger to the Logger interface, Fatal logs at error level and exits.
+func NewSlogLogger(logger *slog.Logger) Logger {
+	return &slogLogger{logger: logger}
 }
 
-type migrateLogger struct {
-	logger  *loggers.CommonLogger
+type slogLogger struct {
+	logger  *slog.Logger
 	verbose bool
 }
 
-func (m *migrateLogger) Printf(format string, v ...interface{}) {
-	m.logger.Info(fmt.Sprintf(format, v...))
+func (s *slogLogger) Printf(format string, v ...interface{}) {
+	s.logger.Info(strings.TrimSuffix(fmt.Sprintf(format, v...), "\n"))
 }
 
-func (m *migrateLogger) Verbose() bool {
-	return m.verbose
+func (s *slogLogger) Verbose() bool {
+	return s.verbose
 }
 
-func (m *migrateLogger) SetVerbose(b bool) {
-	m.verbose = b
+func (s *slogLogger) SetVerbose(b bool) {
+	s.verbose = b
 }
 
-func (m *migrateLogger) Error(msg string, keyAndValues ...interface{}) {
-	m.logger.Sugar().Errorw(msg, keyAndValues...)
+func (s *slogLogger) Error(msg string, keyAndValues ...interface{}) {
+	s.logger.Error(msg, keyAndValues...)
 }
 
-func (m *migrateLogger) Fatal(msg string, keyAndValues ...interface{}) {
-	m.logger.Sugar().Fatalw(msg, keyAndValues...)
+func (s *slogLogger) Fatal(msg string, keyAndValues ...interface{}) {
+	s.logger.Error(msg, keyAndValues...)
+	os.Exit(1)
 }
 
-func (m *migrateLogger) Info(msg string, keyAndValues ...interface{}) {
-	m.logger.Sugar().Infow(msg, keyAndValues...)
+func (s *slogLogger) Info(msg string, keyAndValues ...interface{}) {
+	s.logger.Info(msg, keyAndValues...)
 }
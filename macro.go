@@ -0,0 +1,116 @@
+package migrator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+var (
+	macroRegexp     = regexp.MustCompile(`@([A-Za-z_][A-Za-z0-9_]*)@`)
+	macroNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	errMacroDialect = errors.New("macro isn't defined for the dialect")
+	errMacroName    = errors.New("macro names must be letters, digits and underscores")
+)
+
+// builtinMacros keep column definitions portable across the dialects, e.g. id @uuid_pk@.
+var builtinMacros = map[string]map[Dialect]string{
+	"uuid_pk": {
+		DialectPostgres: "UUID PRIMARY KEY DEFAULT gen_random_uuid()",
+		DialectMySQL:    "BINARY(16) PRIMARY KEY DEFAULT (UUID_TO_BIN(UUID()))",
+		DialectSQLite:   "TEXT PRIMARY KEY",
+	},
+	"id_pk": {
+		DialectPostgres: "BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY",
+		DialectMySQL:    "BIGINT AUTO_INCREMENT PRIMARY KEY",
+		DialectSQLite:   "INTEGER PRIMARY KEY AUTOINCREMENT",
+	},
+	"timestamp": {
+		DialectPostgres: "TIMESTAMPTZ",
+		DialectMySQL:    "DATETIME(6)",
+		DialectSQLite:   "TIMESTAMP",
+	},
+	"json": {
+		DialectPostgres: "JSONB",
+		DialectMySQL:    "JSON",
+		DialectSQLite:   "TEXT",
+	},
+	"bool": {
+		DialectPostgres: "BOOLEAN",
+		DialectMySQL:    "TINYINT(1)",
+		DialectSQLite:   "INTEGER",
+	},
+}
+
+// DefineMacro makes @name@ expand to the expansion of the dialect of the database in the SQL
+// migrations, handwritten or generated, overriding the built-in macro of the same name: uuid_pk,
+// id_pk, timestamp, json or bool.
+func (m *Migrator) DefineMacro(name string, expansions map[Dialect]string) error {
+	if !macroNameRegexp.MatchString(name) {
+		return fmt.Errorf("%w: %s", errMacroName, name)
+	}
+
+	if m.macros == nil {
+		m.macros = make(map[string]map[Dialect]string)
+	}
+	m.macros[name] = expansions
+	return nil
+}
+
+func WithMacro(name string, expansions map[Dialect]string) Option {
+	return func(m *Migrator) {
+		if err := m.DefineMacro(name, expansions); err != nil {
+			m.logger.Error("can't define macro", "error", err)
+		}
+	}
+}
+
+// LoadMacros defines the macros of the JSON file path, mapping their names to their expansion by
+// dialect, e.g. {"money": {"postgres": "NUMERIC(12,2)", "mysql": "DECIMAL(12,2)"}}.
+func (m *Migrator) LoadMacros(path string) error {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	macros := make(map[string]map[Dialect]string)
+	if err = json.Unmarshal(body, &macros); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	for name, expansions := range macros {
+		if err = m.DefineMacro(name, expansions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expandMacros replaces the defined @name@ of body by their expansion for the dialect of the
+// database, leaving the other @...@ as they are.
+func (m *Migrator) expandMacros(body []byte) ([]byte, error) {
+	var err error
+
+	expanded := macroRegexp.ReplaceAllFunc(body, func(match []byte) []byte {
+		name := string(match[1 : len(match)-1])
+
+		expansions, ok := m.macros[name]
+		if !ok {
+			if expansions, ok = builtinMacros[name]; !ok {
+				return match
+			}
+		}
+
+		expansion, ok := expansions[m.dialect]
+		if !ok && err == nil {
+			err = fmt.Errorf("%w: @%s@ on %s", errMacroDialect, name, m.dialect)
+		}
+		return []byte(expansion)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expanded, nil
+}
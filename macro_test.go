@@ -0,0 +1,136 @@
+package migrator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExpandMacros(t *testing.T) {
+	tests := []struct {
+		name     string
+		database string
+		macros   map[string]map[Dialect]string
+		body     string
+		want     string
+		err      error
+	}{
+		{
+			name:     "builtin on postgres",
+			database: "postgres",
+			body:     "CREATE TABLE t (id @id_pk@, data @json@);",
+			want:     "CREATE TABLE t (id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY, data JSONB);",
+		},
+		{
+			name:     "builtin on mysql",
+			database: "mysql",
+			body:     "created_at @timestamp@, active @bool@",
+			want:     "created_at DATETIME(6), active TINYINT(1)",
+		},
+		{
+			name:     "builtin on sqlite",
+			database: "sqlite3",
+			body:     "id @uuid_pk@",
+			want:     "id TEXT PRIMARY KEY",
+		},
+		{
+			name:     "defined",
+			database: "postgres",
+			macros:   map[string]map[Dialect]string{"money": {DialectPostgres: "NUMERIC(12,2)"}},
+			body:     "price @money@",
+			want:     "price NUMERIC(12,2)",
+		},
+		{
+			name:     "defined overrides builtin",
+			database: "postgres",
+			macros:   map[string]map[Dialect]string{"json": {DialectPostgres: "JSON"}},
+			body:     "data @json@",
+			want:     "data JSON",
+		},
+		{
+			name:     "unknown macros and emails are left as they are",
+			database: "postgres",
+			body:     "SELECT '@unknown@', 'user@example.com';",
+			want:     "SELECT '@unknown@', 'user@example.com';",
+		},
+		{
+			name:     "not defined for the dialect",
+			database: "sqlite3",
+			macros:   map[string]map[Dialect]string{"money": {DialectPostgres: "NUMERIC(12,2)"}},
+			body:     "price @money@",
+			err:      errMacroDialect,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := newMigrator(test.database, nil)
+			for name, expansions := range test.macros {
+				if err := m.DefineMacro(name, expansions); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := m.expandMacros([]byte(test.body))
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("err = %v, want %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDefineMacroName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{name: "money", valid: true},
+		{name: "_id2", valid: true},
+		{name: "2id", valid: false},
+		{name: "", valid: false},
+		{name: "a-b", valid: false},
+		{name: "a@b", valid: false},
+	}
+
+	for _, test := range tests {
+		err := newMigrator("postgres", nil).DefineMacro(test.name, map[Dialect]string{DialectPostgres: "TEXT"})
+		if test.valid && err != nil {
+			t.Errorf("DefineMacro(%q) = %v, want nil", test.name, err)
+		}
+		if !test.valid && !errors.Is(err, errMacroName) {
+			t.Errorf("DefineMacro(%q) = %v, want %v", test.name, err, errMacroName)
+		}
+	}
+}
+
+func TestLoadMacros(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "macros.json")
+	body := `{"money": {"postgres": "NUMERIC(12,2)", "mysql": "DECIMAL(12,2)"}}`
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := newMigrator("mysql", nil)
+	if err := m.LoadMacros(path); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := m.expandMacros([]byte("price @money@"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "price DECIMAL(12,2)"; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,180 @@
+package migrator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4/source"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const manifestFile = "MANIFEST.json"
+
+var errManifestMismatch = errors.New("migrations don't match the manifest")
+
+type ManifestEntry struct {
+	File     string `json:"file"`
+	Version  uint   `json:"version,omitempty"`
+	Checksum string `json:"checksum"`
+}
+
+type Manifest struct {
+	ToolVersion string           `json:"tool_version"`
+	CreatedAt   time.Time        `json:"created_at"`
+	Files       []*ManifestEntry `json:"files"`
+}
+
+// manifestedFiles lists the files of a migrations directory a manifest covers: versioned, repeatable
+// and callback files.
+func manifestedFiles(fsys fs.FS) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.HasPrefix(name, manifestFile) {
+			continue
+		}
+
+		if _, err := source.DefaultParse(name); err == nil || strings.HasPrefix(name, repeatablePrefix) || isCallbackFile(name) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// buildManifest lists the files of fsys, the directory dir, with the checksums of their bodies once
+// their includes are expanded, so that changing an included file changes them too.
+func buildManifest(fsys fs.FS, dir string) (*Manifest, error) {
+	names, err := manifestedFiles(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	toolVersion, _ := buildInfo()
+	manifest := &Manifest{ToolVersion: toolVersion, CreatedAt: time.Now().UTC(), Files: make([]*ManifestEntry, 0, len(names))}
+
+	for _, name := range names {
+		body, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if body, err = expandIncludes(dir, name, body); err != nil {
+			return nil, err
+		}
+
+		entry := &ManifestEntry{File: name, Checksum: checksumOf(body)}
+		if migration, err := source.DefaultParse(name); err == nil {
+			entry.Version = migration.Version
+		}
+		manifest.Files = append(manifest.Files, entry)
+	}
+
+	return manifest, nil
+}
+
+func readManifest(fsys fs.FS) (*Manifest, []byte, error) {
+	body, err := fs.ReadFile(fsys, manifestFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	manifest := &Manifest{}
+	if err = json.Unmarshal(body, manifest); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", manifestFile, err)
+	}
+	return manifest, body, nil
+}
+
+// verify checks that fsys, the directory dir, holds exactly the files of the manifest, with the
+// same content.
+func (manifest *Manifest) verify(fsys fs.FS, dir string) error {
+	actual, err := buildManifest(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	expected := make(map[string]string, len(manifest.Files))
+	for _, entry := range manifest.Files {
+		expected[entry.File] = entry.Checksum
+	}
+
+	problems := make([]string, 0)
+	for _, entry := range actual.Files {
+		checksum, ok := expected[entry.File]
+		switch {
+		case !ok:
+			problems = append(problems, entry.File+" is not in the manifest")
+		case checksum != entry.Checksum:
+			problems = append(problems, entry.File+" changed")
+		}
+		delete(expected, entry.File)
+	}
+
+	for file := range expected {
+		problems = append(problems, file+" is missing")
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("%w: %s", errManifestMismatch, strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// WriteManifest writes the MANIFEST.json of the migrations directory. Once it exists, up, down, goto
+// and rollback refuse to run migrations that don't match it.
+func (m *Migrator) WriteManifest() error {
+	manifest, err := buildManifest(m.source.fsys, m.source.path)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(m.migrationsFilePath, manifestFile), append(body, '\n'), 0666)
+}
+
+// VerifyManifest checks the migrations against their manifest, and the signature of the manifest
+// when signed migrations are required.
+func (m *Migrator) VerifyManifest() error {
+	manifest, body, err := readManifest(m.source.fsys)
+	if err != nil {
+		return err
+	}
+
+	if m.requireSigned {
+		if m.manifestVerifier == nil {
+			return errNoVerifier
+		}
+
+		if err = verifyManifestSignature(m.source.fsys, body, m.manifestVerifier); err != nil {
+			return err
+		}
+	}
+
+	return manifest.verify(m.source.fsys, m.source.path)
+}
+
+// checkManifest verifies the migrations against their manifest, when there is one or signed
+// migrations are required.
+func (m *Migrator) checkManifest() error {
+	if _, err := fs.Stat(m.source.fsys, manifestFile); errors.Is(err, fs.ErrNotExist) && !m.requireSigned {
+		return nil
+	}
+	return m.VerifyManifest()
+}
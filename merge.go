@@ -0,0 +1,189 @@
+package migrator
+
+import (
+	"fmt"
+	"github.com/anyufly/migrate-sql-result"
+	"sort"
+	"strings"
+)
+
+func sortedTableNames(sqlMaps ...map[string][]string) []string {
+	seen := make(map[string]struct{})
+	tableNames := make([]string, 0)
+
+	for _, sqlMap := range sqlMaps {
+		for tableName := range sqlMap {
+			if _, ok := seen[tableName]; !ok {
+				seen[tableName] = struct{}{}
+				tableNames = append(tableNames, tableName)
+			}
+		}
+	}
+
+	sort.Strings(tableNames)
+	return tableNames
+}
+
+// resultTables returns the tables of the up or down statements of migrateResult, the tables the
+// up statements reference by foreign keys coming before the tables referencing them, and after them
+// for down. migrate-sql-result only hands out maps, so the tables are otherwise sorted by name.
+func resultTables(migrateResult *result.MigrateSQLResult, direction string) []string {
+	up, down := migrateResult.Up(), migrateResult.Down()
+
+	tableNames := sortedTableNames(up, down)
+	known := make(map[string]struct{}, len(tableNames))
+	for _, tableName := range tableNames {
+		known[tableName] = struct{}{}
+	}
+
+	// a table is marked before its references are visited, so that a cycle of references, added
+	// by ALTER TABLE once both tables exist, ends there
+	visited := make(map[string]bool, len(tableNames))
+	ordered := make([]string, 0, len(tableNames))
+
+	var visit func(tableName string)
+	visit = func(tableName string) {
+		if visited[tableName] {
+			return
+		}
+		visited[tableName] = true
+
+		for _, statement := range up[tableName] {
+			for _, referenced := range referencedTables(statement) {
+				if referenced, ok := resultTable(known, referenced); ok {
+					visit(referenced)
+				}
+			}
+		}
+		ordered = append(ordered, tableName)
+	}
+
+	for _, tableName := range tableNames {
+		visit(tableName)
+	}
+
+	sqlMap := up
+	if direction == directionDown {
+		sqlMap = down
+		for i, j := 0, len(ordered)-1; i < j; i, j = i+1, j-1 {
+			ordered[i], ordered[j] = ordered[j], ordered[i]
+		}
+	}
+
+	tables := make([]string, 0, len(sqlMap))
+	for _, tableName := range ordered {
+		if _, ok := sqlMap[tableName]; ok {
+			tables = append(tables, tableName)
+		}
+	}
+	return tables
+}
+
+// resultTable returns the table of known a REFERENCES clause names, with or without its schema.
+func resultTable(known map[string]struct{}, identifier string) (string, bool) {
+	tableName := unquoteIdentifier(identifier)
+	if _, ok := known[tableName]; ok {
+		return tableName, true
+	}
+
+	if i := strings.LastIndex(identifier, "."); i >= 0 {
+		tableName = unquoteIdentifier(identifier[i+1:])
+		_, ok := known[tableName]
+		return tableName, ok
+	}
+	return "", false
+}
+
+// appendTables appends the tables of more missing from tableNames, keeping their order.
+func appendTables(tableNames []string, more []string) []string {
+	seen := make(map[string]struct{}, len(tableNames))
+	for _, tableName := range tableNames {
+		seen[tableName] = struct{}{}
+	}
+
+	for _, tableName := range more {
+		if _, ok := seen[tableName]; !ok {
+			seen[tableName] = struct{}{}
+			tableNames = append(tableNames, tableName)
+		}
+	}
+	return tableNames
+}
+
+func dedupeSQL(sqlList []string) []string {
+	seen := make(map[string]struct{}, len(sqlList))
+	deduped := make([]string, 0, len(sqlList))
+
+	for _, sql := range sqlList {
+		if _, ok := seen[sql]; ok {
+			continue
+		}
+		seen[sql] = struct{}{}
+		deduped = append(deduped, sql)
+	}
+
+	return deduped
+}
+
+func equalSQL(a, b []string) bool {
+	a, b = dedupeSQL(a), dedupeSQL(b)
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeMigrateResults merges the results of the migrate funcs, in the order the funcs were added and
+// resultTables orders the tables of each result, the down statements being merged in the reverse order.
+func mergeMigrateResults(migrateResults []*result.MigrateSQLResult) (*result.MigrateSQLResult, error) {
+	merged := result.NewMigrateSQLResult()
+
+	type tableChange struct {
+		owner int
+		up    []string
+		down  []string
+	}
+
+	changes := make(map[string]*tableChange)
+	mergedTables := make([]string, 0)
+
+	for i, migrateResult := range migrateResults {
+		if migrateResult == nil {
+			continue
+		}
+
+		up, down := migrateResult.Up(), migrateResult.Down()
+
+		tableNames := appendTables(resultTables(migrateResult, directionUp), resultTables(migrateResult, directionDown))
+		for _, tableName := range tableNames {
+			if change, ok := changes[tableName]; ok {
+				if !equalSQL(change.up, up[tableName]) || !equalSQL(change.down, down[tableName]) {
+					return nil, fmt.Errorf("migrate func #%d and #%d both change table %s", change.owner, i, tableName)
+				}
+				continue
+			}
+
+			changes[tableName] = &tableChange{owner: i, up: up[tableName], down: down[tableName]}
+			mergedTables = append(mergedTables, tableName)
+
+			for _, sql := range dedupeSQL(up[tableName]) {
+				merged.AppendUp(result.NewSQLForTable(tableName, sql))
+			}
+		}
+	}
+
+	for i := len(mergedTables) - 1; i >= 0; i-- {
+		tableName := mergedTables[i]
+		for _, sql := range dedupeSQL(changes[tableName].down) {
+			merged.AppendDown(result.NewSQLForTable(tableName, sql))
+		}
+	}
+
+	return merged, nil
+}
@@ -0,0 +1,150 @@
+package migrator
+
+import (
+	"github.com/anyufly/migrate-sql-result"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testResult returns a result with the up and down statements of tables, in their order.
+func testResult(tables ...string) *result.MigrateSQLResult {
+	migrateResult := result.NewMigrateSQLResult()
+	for _, table := range tables {
+		migrateResult.AppendUp(result.NewSQLForTable(table, "CREATE TABLE "+table))
+		migrateResult.AppendDown(result.NewSQLForTable(table, "DROP TABLE "+table))
+	}
+	return migrateResult
+}
+
+func TestResultTables(t *testing.T) {
+	tests := []struct {
+		name     string
+		up       map[string]string
+		down     []string
+		wantUp   []string
+		wantDown []string
+	}{
+		{
+			name:     "sorted by name",
+			up:       map[string]string{"users": "CREATE TABLE users (id INT)", "accounts": "CREATE TABLE accounts (id INT)"},
+			down:     []string{"users", "accounts"},
+			wantUp:   []string{"accounts", "users"},
+			wantDown: []string{"users", "accounts"},
+		},
+		{
+			name: "referenced tables first",
+			up: map[string]string{
+				"users":    "CREATE TABLE users (id INT)",
+				"accounts": "CREATE TABLE accounts (id INT, user_id INT REFERENCES users (id))",
+				"orders":   `CREATE TABLE "orders" (id INT, account_id INT REFERENCES "public"."accounts" (id))`,
+			},
+			down:     []string{"users", "accounts", "orders"},
+			wantUp:   []string{"users", "accounts", "orders"},
+			wantDown: []string{"orders", "accounts", "users"},
+		},
+		{
+			name: "reference cycle",
+			up: map[string]string{
+				"a": "ALTER TABLE a ADD CONSTRAINT fk_b FOREIGN KEY (b_id) REFERENCES b (id)",
+				"b": "ALTER TABLE b ADD CONSTRAINT fk_a FOREIGN KEY (a_id) REFERENCES a (id)",
+			},
+			wantUp:   []string{"b", "a"},
+			wantDown: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			migrateResult := result.NewMigrateSQLResult()
+			for table, sql := range test.up {
+				migrateResult.AppendUp(result.NewSQLForTable(table, sql))
+			}
+			for _, table := range test.down {
+				migrateResult.AppendDown(result.NewSQLForTable(table, "DROP TABLE "+table))
+			}
+
+			if got := resultTables(migrateResult, directionUp); !reflect.DeepEqual(got, test.wantUp) {
+				t.Errorf("up tables = %q, want %q", got, test.wantUp)
+			}
+			if got := resultTables(migrateResult, directionDown); !reflect.DeepEqual(got, test.wantDown) {
+				t.Errorf("down tables = %q, want %q", got, test.wantDown)
+			}
+		})
+	}
+}
+
+func TestMergeMigrateResults(t *testing.T) {
+	referencing := result.NewMigrateSQLResult()
+	referencing.AppendUp(result.NewSQLForTable("accounts", "CREATE TABLE accounts (user_id INT REFERENCES users (id))"))
+	referencing.AppendDown(result.NewSQLForTable("accounts", "DROP TABLE accounts"))
+
+	conflicting := result.NewMigrateSQLResult()
+	conflicting.AppendUp(result.NewSQLForTable("users", "CREATE TABLE users (id INT)"))
+
+	tests := []struct {
+		name     string
+		results  []*result.MigrateSQLResult
+		up       []string
+		down     []string
+		conflict string
+	}{
+		{
+			name:    "referenced tables first",
+			results: []*result.MigrateSQLResult{referencing, testResult("users")},
+			up:      []string{"users", "accounts"},
+			down:    []string{"accounts", "users"},
+		},
+		{
+			name:    "sorted tables",
+			results: []*result.MigrateSQLResult{testResult("users", "orders"), testResult("accounts")},
+			up:      []string{"accounts", "orders", "users"},
+			down:    []string{"users", "orders", "accounts"},
+		},
+		{
+			name:    "nil results are skipped",
+			results: []*result.MigrateSQLResult{nil, testResult("b"), nil, testResult("a")},
+			up:      []string{"a", "b"},
+			down:    []string{"b", "a"},
+		},
+		{
+			name:    "same change of a table",
+			results: []*result.MigrateSQLResult{testResult("users", "orders"), testResult("users")},
+			up:      []string{"orders", "users"},
+			down:    []string{"users", "orders"},
+		},
+		{
+			name:     "different changes of a table",
+			results:  []*result.MigrateSQLResult{testResult("orders", "users"), conflicting},
+			conflict: "migrate func #0 and #1 both change table users",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			merged, err := mergeMigrateResults(test.results)
+			if test.conflict != "" {
+				if err == nil || !strings.Contains(err.Error(), test.conflict) {
+					t.Fatalf("err = %v, want %q", err, test.conflict)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := resultTables(merged, directionUp); !reflect.DeepEqual(got, test.up) {
+				t.Errorf("up tables = %q, want %q", got, test.up)
+			}
+			if got := resultTables(merged, directionDown); !reflect.DeepEqual(got, test.down) {
+				t.Errorf("down tables = %q, want %q", got, test.down)
+			}
+
+			for _, table := range test.up {
+				if up := merged.Up()[table]; len(up) != 1 || !strings.HasPrefix(up[0], "CREATE TABLE "+table) {
+					t.Errorf("up statements of %s = %q", table, up)
+				}
+			}
+		})
+	}
+}
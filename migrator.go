@@ -2,17 +2,19 @@ package migrator
 
 import (
 	"bytes"
+	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/anyufly/migrate-sql-result"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/spf13/cobra"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -21,15 +23,81 @@ const defaultTimeFormat = "20060102150405"
 var (
 	errInvalidSequenceWidth     = errors.New("digits must be positive")
 	errIncompatibleSeqAndFormat = errors.New("the seq and format options are mutually exclusive")
+	errUncapturedChanges        = errors.New("models have changes not captured by committed migrations")
+
+	ErrMigrateFunc = errors.New("migrate func failed")
 )
 
 type migrateFunc func() (*result.MigrateSQLResult, error)
 
 type Migrator struct {
 	migrate            *migrate.Migrate
+	source             *migrationSource
+	driver             database.Driver
 	migrationsFilePath string
-	migrateFunc        migrateFunc
+	databaseName       string
+	databaseURL        string
+	migrationsTable    string
+	driverParams       map[string]string
+	sessionParams      map[string]string
+	isolation          sql.IsolationLevel
+	prefetchMigrations uint
+	lockTimeout        time.Duration
+	lockHeartbeat      time.Duration
+	lockRefresh        func() error
+	migrateFuncs       []migrateFunc
 	logger             Logger
+	logFile            io.Closer
+	logFormat          string
+	command            string
+	progressFunc       func(ProgressUpdate)
+	progress           *progress
+	versionHooks       map[uint][]GoMigrationFunc
+	terminator         string
+	splitStatements    bool
+	savepoints         bool
+	statementTracking  bool
+	oscArgs            []string
+	concurrentIndexes  bool
+	bundleDir          string
+	manifestVerifier   SignatureVerifier
+	requireSigned      bool
+	approvalVerifier   SignatureVerifier
+	closeOnce          sync.Once
+	closeSourceErr     error
+	closeDatabaseErr   error
+	dialect            Dialect
+	db                 *sql.DB
+	ownsDB             bool
+	pool               ConnectionPool
+	connectTimeout     time.Duration
+	keepAlive          time.Duration
+	schemaFunc         schemaFunc
+	ignoredTables      map[string]struct{}
+	dropExtraTables    bool
+	seedsPath          string
+	historyEnabled     bool
+	historySQLMode     HistorySQLMode
+	historySQLStore    SQLStore
+	auditEnabled       bool
+	outOfOrderAllowed  bool
+	historyWarned      bool
+	reportPath         string
+	readOnly           bool
+	largeTableLimit    int64
+	largeTableAction   string
+	largeTableAcked    bool
+	waitInterval       time.Duration
+	notifyChannel      string
+	listener           Listener
+	modules            []*moduleMigrator
+	labels             map[string]string
+	downCheck          string
+	templateValues     map[string]string
+	templateEnv        map[string]struct{}
+	profile            string
+	macros             map[string]map[Dialect]string
+	goMigrations       map[uint]*goMigration
 }
 
 func checkAndMakeMigrationsFilePath(migrationsFilePath string) error {
@@ -50,31 +118,53 @@ func checkAndMakeMigrationsFilePath(migrationsFilePath string) error {
 	}
 }
 
-func New(driver database.Driver, databaseName, migrationsFilePath string, migrateFunc migrateFunc) (*Migrator, error) {
-	m, err := migrate.NewWithDatabaseInstance(
-		fmt.Sprintf("file://%s", migrationsFilePath),
-		databaseName,
-		driver,
-	)
+func New(driver database.Driver, databaseName, migrationsFilePath string, migrateFunc migrateFunc, opts ...Option) (*Migrator, error) {
+	migrator := newMigrator(databaseName, opts)
+	if migrator.migrationsTable != defaultMigrationsTable {
+		return nil, errMigrationsTableNeedsURL
+	}
 
-	if err != nil {
+	migrator.driver = &databaseDriver{Driver: driver, migrator: migrator}
+
+	if err := migrator.SetMigrationsPath(migrationsFilePath); err != nil {
 		return nil, err
 	}
 
-	m.Log = defaultLogger
+	migrator.AddMigrateFunc(migrateFunc)
+
+	return migrator, nil
+}
 
-	err = checkAndMakeMigrationsFilePath(migrationsFilePath)
+// SetMigrationsPath points the Migrator at another migrations directory, re-reading its migrations.
+func (m *Migrator) SetMigrationsPath(migrationsFilePath string) error {
+	err := checkAndMakeMigrationsFilePath(migrationsFilePath)
 
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return &Migrator{
-		migrate:            m,
-		migrationsFilePath: migrationsFilePath,
-		migrateFunc:        migrateFunc,
-		logger:             defaultLogger,
-	}, nil
+	sourceDriver, err := newMigrationSource(os.DirFS(migrationsFilePath), migrationsFilePath, m.goMigrations)
+
+	if err != nil {
+		return err
+	}
+	sourceDriver.render = m.renderMigration
+
+	mi, err := migrate.NewWithInstance("file", sourceDriver, m.databaseName, m.driver)
+
+	if err != nil {
+		return err
+	}
+
+	mi.Log = m.logger
+	mi.PrefetchMigrations = m.prefetchMigrations
+	mi.LockTimeout = m.lockTimeout
+
+	m.migrate = mi
+	m.source = sourceDriver
+	m.migrationsFilePath = migrationsFilePath
+
+	return nil
 }
 
 func nextSeqVersion(migrationsFilePath, ext string, seqDigits int) (string, error) {
@@ -88,6 +178,14 @@ func nextSeqVersion(migrationsFilePath, ext string, seqDigits int) (string, erro
 		return "", errInvalidSequenceWidth
 	}
 
+	versioned := matches[:0]
+	for _, match := range matches {
+		if name := filepath.Base(match); !strings.HasPrefix(name, repeatablePrefix) && !isCallbackFile(name) {
+			versioned = append(versioned, match)
+		}
+	}
+	matches = versioned
+
 	nextSeq := uint64(1)
 
 	if len(matches) > 0 {
@@ -145,6 +243,28 @@ func timeVersion(timeZoneName string, format string) (version string, err error)
 	return
 }
 
+func (m *Migrator) AddMigrateFunc(migrateFuncs ...migrateFunc) {
+	for _, fn := range migrateFuncs {
+		if fn != nil {
+			m.migrateFuncs = append(m.migrateFuncs, fn)
+		}
+	}
+}
+
+func (m *Migrator) runMigrateFuncs() (*result.MigrateSQLResult, error) {
+	migrateResults := make([]*result.MigrateSQLResult, 0, len(m.migrateFuncs))
+
+	for _, fn := range m.migrateFuncs {
+		migrateResult, err := fn()
+		if err != nil {
+			return nil, err
+		}
+		migrateResults = append(migrateResults, migrateResult)
+	}
+
+	return mergeMigrateResults(migrateResults)
+}
+
 func (m *Migrator) SetLogger(logger Logger) {
 	m.migrate.Log = logger
 	m.logger = logger
@@ -197,89 +317,325 @@ func (m *Migrator) upAndDownFilePath(
 	return up, down, nil
 }
 
-func (m *Migrator) MakeMigrate(timeZoneName string, format string, name string, ext string, seq bool, seqDigits int) error {
-	migrateResult, err := m.migrateFunc()
+type generatedMigration struct {
+	upFile   string
+	downFile string
+	upSQL    []byte
+	downSQL  []byte
+}
+
+// renderSQL renders the statements of sqlMap table by table, in the order of tableNames.
+func renderSQL(sqlMap map[string][]string, tableNames []string, terminator string) []byte {
+	var buffer bytes.Buffer
+
+	if terminator != defaultTerminator {
+		buffer.WriteString(fmt.Sprintf("DELIMITER %s\n", terminator))
+	}
+
+	for _, tableName := range tableNames {
+		buffer.WriteString(fmt.Sprintf("-- %s\n", tableName))
+		renderStatements(&buffer, sqlMap[tableName], terminator)
+	}
+
+	if terminator != defaultTerminator {
+		buffer.WriteString(fmt.Sprintf("DELIMITER %s\n", defaultTerminator))
+	}
+
+	return buffer.Bytes()
+}
+
+func (m *Migrator) generateMigrate(migrateFunc migrateFunc,
+	timeZoneName string, format string, name string, ext string, seq bool, seqDigits int) (*generatedMigration, error) {
+
+	migrateResult, err := migrateFunc()
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if migrateResult.Empty() {
+		return nil, nil
+	}
+
+	up, down, err := m.upAndDownFilePath(timeZoneName, format, name, ext, seq, seqDigits)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &generatedMigration{
+		upFile:   up,
+		downFile: down,
+		upSQL:    m.renderMigrationSQL(migrateResult.Up(), resultTables(migrateResult, directionUp)),
+		downSQL:  m.renderMigrationSQL(migrateResult.Down(), resultTables(migrateResult, directionDown)),
+	}, nil
+}
+
+func (m *Migrator) writeMigrate(generated *generatedMigration) error {
+	if generated == nil {
 		m.logger.Info("no change")
 		return nil
 	}
 
-	up, down, err := m.upAndDownFilePath(timeZoneName, format, name, ext, seq, seqDigits)
+	err := os.WriteFile(generated.upFile, bytes.TrimPrefix(generated.upSQL, utf8BOM), 0666)
+	if err != nil {
+		return err
+	}
 
+	err = os.WriteFile(generated.downFile, bytes.TrimPrefix(generated.downSQL, utf8BOM), 0666)
 	if err != nil {
 		return err
 	}
+	return nil
+}
 
-	var upBuffer, downBuffer bytes.Buffer
+func (m *Migrator) printMigrate(w io.Writer, generated *generatedMigration) error {
+	if generated == nil {
+		m.logger.Info("no change")
+		return nil
+	}
 
-	for tableName, sqlList := range migrateResult.Up() {
-		upBuffer.WriteString(fmt.Sprintf("-- %s\n", tableName))
+	_, err := fmt.Fprintf(w, "-- ==> %s\n%s\n-- ==> %s\n%s", generated.upFile, generated.upSQL, generated.downFile, generated.downSQL)
+	return err
+}
 
-		for _, sql := range sqlList {
-			upBuffer.WriteString(fmt.Sprintf("%s;\n", sql))
-		}
+func (m *Migrator) MakeMigrate(timeZoneName string, format string, name string, ext string, seq bool, seqDigits int) error {
+	generated, err := m.generateMigrate(m.runMigrateFuncs, timeZoneName, format, name, ext, seq, seqDigits)
 
+	if err != nil {
+		return err
 	}
 
-	for tableName, sqlList := range migrateResult.Down() {
-		downBuffer.WriteString(fmt.Sprintf("-- %s\n", tableName))
+	return m.writeMigrate(generated)
+}
 
-		for _, sql := range sqlList {
-			downBuffer.WriteString(fmt.Sprintf("%s;\n", sql))
+// Generate is MakeMigrate reporting whether a migration was written. Errors of the migrate funcs
+// wrap ErrMigrateFunc.
+func (m *Migrator) Generate(timeZoneName string, format string, name string, ext string, seq bool, seqDigits int) (bool, error) {
+	run := func() (*result.MigrateSQLResult, error) {
+		migrateResult, err := m.runMigrateFuncs()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrMigrateFunc, err)
 		}
+		return migrateResult, nil
 	}
 
-	err = os.WriteFile(up, upBuffer.Bytes(), 0666)
+	generated, err := m.generateMigrate(run, timeZoneName, format, name, ext, seq, seqDigits)
+
+	if err != nil || generated == nil {
+		return false, err
+	}
+
+	if err = m.writeMigrate(generated); err != nil {
+		return false, err
+	}
+
+	m.logger.Info("generated migration", "up", generated.upFile, "down", generated.downFile)
+	return true, nil
+}
+
+func (m *Migrator) MakeMigrateDryRun(
+	w io.Writer, timeZoneName string, format string, name string, ext string, seq bool, seqDigits int) error {
+
+	generated, err := m.generateMigrate(m.runMigrateFuncs, timeZoneName, format, name, ext, seq, seqDigits)
+
 	if err != nil {
 		return err
 	}
 
-	err = os.WriteFile(down, downBuffer.Bytes(), 0666)
+	return m.printMigrate(w, generated)
+}
+
+func (m *Migrator) MakeMigrateFromDB(timeZoneName string, format string, name string, ext string, seq bool, seqDigits int) error {
+	generated, err := m.generateMigrate(m.DiffDB, timeZoneName, format, name, ext, seq, seqDigits)
+
 	if err != nil {
 		return err
 	}
-	return nil
+
+	return m.writeMigrate(generated)
 }
 
-func (m *Migrator) Up(n int) error {
-	if n <= 0 {
-		return m.migrate.Up()
+func (m *Migrator) MakeMigrateFromDBDryRun(
+	w io.Writer, timeZoneName string, format string, name string, ext string, seq bool, seqDigits int) error {
+
+	generated, err := m.generateMigrate(m.DiffDB, timeZoneName, format, name, ext, seq, seqDigits)
+
+	if err != nil {
+		return err
 	}
-	return m.migrate.Steps(n)
+
+	return m.printMigrate(w, generated)
+}
+
+func (m *Migrator) GenerateCheck() error {
+	migrateResult, err := m.runMigrateFuncs()
+
+	if err != nil {
+		return err
+	}
+
+	if migrateResult.Empty() {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", errUncapturedChanges, strings.Join(sortedTableNames(migrateResult.Up()), ", "))
+}
+
+func (m *Migrator) Up(n int) error {
+	return m.audited("up", func() error {
+		if err := m.checkMonotonic(); err != nil {
+			return err
+		}
+
+		// the repeatable migrations only follow a full up
+		if n <= 0 {
+			if err := m.checkRepeatable(); err != nil {
+				return err
+			}
+		}
+
+		if m.largeTableLimit > 0 || m.downCheck != "" {
+			plan, err := m.UpPlan(n)
+			if err != nil {
+				return err
+			}
+			if err = m.checkDowns(plan); err != nil {
+				return err
+			}
+			if err = m.checkLargeTables(plan); err != nil {
+				return err
+			}
+		}
+
+		m.startProgress(n, -1)
+
+		var err error
+		if n <= 0 {
+			err = m.migrate.Up()
+		} else {
+			err = m.migrate.Steps(n)
+		}
+
+		if (err != nil && err != migrate.ErrNoChange) || n > 0 {
+			return err
+		}
+
+		if _, repeatableErr := m.ApplyRepeatable(); repeatableErr != nil {
+			return repeatableErr
+		}
+		return err
+	})
 }
 
 func (m *Migrator) Down(n int) error {
-	if n <= 0 {
-		return m.migrate.Down()
+	return m.audited("down", func() error {
+		if err := m.checkPrivileges("down"); err != nil {
+			return err
+		}
+
+		if n <= 0 {
+			m.startProgress(-1, -1)
+			return m.migrate.Down()
+		}
+
+		m.startProgress(-n, -1)
+		return m.migrate.Steps(-n)
+	})
+}
+
+func ignoreNoChange(err error) error {
+	if err == migrate.ErrNoChange {
+		return nil
+	}
+	return err
+}
+
+// TestReversibility applies all up migrations, then all down migrations, then all up migrations
+// again to the disposable database at scratchURL, never to the migrated database. Its database/sql
+// driver must be registered, as golang-migrate drivers do.
+func (m *Migrator) TestReversibility(scratchURL string) error {
+	if scratchURL == "" {
+		return errNoScratchURL
 	}
-	return m.migrate.Steps(-n)
+
+	if m.databaseURL != "" && scratchURL == m.databaseURL {
+		return errShadowIsTarget
+	}
+
+	return m.audited("test", func() error {
+		if err := m.checkManifest(); err != nil {
+			return err
+		}
+
+		scratch, err := m.newShadow(scratchURL)
+		if err != nil {
+			return fmt.Errorf("can't open scratch database: %w", err)
+		}
+		defer func() {
+			_, _ = scratch.Close()
+			_ = scratch.db.Close()
+		}()
+
+		if err = ignoreNoChange(scratch.Up(0)); err != nil {
+			return fmt.Errorf("applying all up migrations: %w", err)
+		}
+
+		if err = ignoreNoChange(scratch.Down(0)); err != nil {
+			return fmt.Errorf("applying all down migrations: %w", err)
+		}
+
+		if err = ignoreNoChange(scratch.Up(0)); err != nil {
+			return fmt.Errorf("re-applying all up migrations: %w", err)
+		}
+
+		return nil
+	})
 }
 
 func (m *Migrator) Drop() error {
-	return m.migrate.Drop()
+	return m.audited("drop", func() error {
+		if err := m.checkPrivileges("drop"); err != nil {
+			return err
+		}
+		return m.migrate.Drop()
+	})
 }
 
 func (m *Migrator) Force(version int) error {
-	return m.migrate.Force(version)
+	return m.audited("force", func() error {
+		return m.migrate.Force(version)
+	})
+}
+
+// ResetVersion clears the version record, as if no migration had been applied, such as after the
+// schema was dropped by hand.
+func (m *Migrator) ResetVersion() error {
+	return m.audited("reset-version", func() error {
+		return m.migrate.Force(database.NilVersion)
+	})
 }
 
 func (m *Migrator) Goto(version uint) error {
-	return m.migrate.Migrate(version)
+	return m.audited("goto", func() error {
+		m.startProgress(0, int(version))
+		return m.migrate.Migrate(version)
+	})
 }
 
 func (m *Migrator) Version() (version uint, dirty bool, err error) {
 	return m.migrate.Version()
 }
 
-func (m *Migrator) CobraCommand() *cobra.Command {
-	return (&migratorCobraCommandBuilder{migrator: m}).Build()
+func (m *Migrator) CobraCommand(opts ...CommandOption) *cobra.Command {
+	builder := &migratorCobraCommandBuilder{migrator: m}
+	for _, opt := range opts {
+		opt(builder)
+	}
+	return builder.Build()
 }
 
+// Deprecated: use CloseContext, which bounds the time spent closing and joins the errors.
 func (m *Migrator) Close() (source error, database error) {
-	return m.migrate.Close()
+	return m.close()
 }
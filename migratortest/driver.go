@@ -0,0 +1,113 @@
+// Package migratortest provides helpers for testing code built on the migrator package
+// without a real database.
+package migratortest
+
+import (
+	"github.com/golang-migrate/migrate/v4/database"
+	"io"
+	"sync"
+)
+
+type Driver struct {
+	mu       sync.Mutex
+	locked   bool
+	version  int
+	dirty    bool
+	executed []string
+	versions []int
+}
+
+var _ database.Driver = (*Driver)(nil)
+
+func NewDriver() *Driver {
+	return &Driver{version: database.NilVersion}
+}
+
+func (d *Driver) Open(url string) (database.Driver, error) {
+	return NewDriver(), nil
+}
+
+func (d *Driver) Close() error {
+	return nil
+}
+
+func (d *Driver) Lock() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.locked {
+		return database.ErrLocked
+	}
+	d.locked = true
+	return nil
+}
+
+func (d *Driver) Unlock() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.locked {
+		return database.ErrNotLocked
+	}
+	d.locked = false
+	return nil
+}
+
+func (d *Driver) Run(migration io.Reader) error {
+	body, err := io.ReadAll(migration)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.executed = append(d.executed, string(body))
+	return nil
+}
+
+func (d *Driver) SetVersion(version int, dirty bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !dirty {
+		d.versions = append(d.versions, version)
+	}
+	d.version = version
+	d.dirty = dirty
+	return nil
+}
+
+func (d *Driver) Version() (int, bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.version, d.dirty, nil
+}
+
+func (d *Driver) Drop() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.version = database.NilVersion
+	d.dirty = false
+	d.executed = nil
+	d.versions = nil
+	return nil
+}
+
+// Executed returns the body of every migration run so far, in order.
+func (d *Driver) Executed() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]string(nil), d.executed...)
+}
+
+// AppliedVersions returns every version the driver was cleanly set to, in order.
+func (d *Driver) AppliedVersions() []int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]int(nil), d.versions...)
+}
@@ -0,0 +1,59 @@
+package migratortest
+
+import (
+	"bytes"
+	"github.com/anyufly/file-migrator"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+// fileHeaderRegexp matches the file headers of dry runs, extensions such as .sql.tmpl included.
+var fileHeaderRegexp = regexp.MustCompile(`(?m)^-- ==> .*\.(up|down)\.[^\s]+$`)
+
+// GeneratedSQL returns the migration the Migrator would create, with the timestamped
+// filenames replaced by their direction so the output is stable across runs.
+func GeneratedSQL(m *migrator.Migrator) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	if err := m.MakeMigrateDryRun(&buffer, "", "", "golden", "", false, 0); err != nil {
+		return nil, err
+	}
+
+	return fileHeaderRegexp.ReplaceAll(buffer.Bytes(), []byte("-- ==> $1")), nil
+}
+
+// AssertGolden compares the migration the Migrator would create against goldenPath, or rewrites
+// the golden file when update is set, typically from a -update flag of the test package:
+//
+//	var update = flag.Bool("update", false, "update the golden files")
+//
+//	migratortest.AssertGolden(t, m, "testdata/users.golden", *update)
+func AssertGolden(t testing.TB, m *migrator.Migrator, goldenPath string, update bool) {
+	t.Helper()
+
+	got, err := GeneratedSQL(m)
+	if err != nil {
+		t.Fatalf("generate migration: %v", err)
+	}
+
+	if update {
+		if err = os.MkdirAll(filepath.Dir(goldenPath), 0777); err != nil {
+			t.Fatalf("create golden dir: %v", err)
+		}
+		if err = os.WriteFile(goldenPath, got, 0666); err != nil {
+			t.Fatalf("update golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("read golden file (run with -update to create it): %v", err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("generated migration differs from %s (run with -update to accept)\n--- got:\n%s\n--- want:\n%s", goldenPath, got, want)
+	}
+}
@@ -0,0 +1,323 @@
+package migrator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+)
+
+var (
+	errModuleName     = errors.New("module name can't be empty")
+	errModuleExists   = errors.New("module already exists")
+	errUnknownModule  = errors.New("unknown module")
+	errModulesNeedURL = errors.New("modules can only be used by a migrator created with NewFromURL")
+	errAllModules     = errors.New("--all-modules is only supported by up and version")
+	errModuleCycle    = errors.New("modules require each other")
+	errRequirement    = errors.New("module requirement not met")
+)
+
+// Module is a set of migrations kept in its own directory, whose version is kept in its own
+// table, schema_migrations_<name> when MigrationsTable is empty, so that the teams of a monorepo
+// migrate the same database independently. Requires lists the versions other modules must be at
+// before its migrations run.
+type Module struct {
+	Name            string
+	Path            string
+	MigrationsTable string
+	Requires        []ModuleRequirement
+}
+
+// ModuleRequirement is the minimum version of a module another module depends on.
+type ModuleRequirement struct {
+	Module  string
+	Version uint
+}
+
+type moduleMigrator struct {
+	Module
+	migrator *Migrator
+}
+
+// ModuleStatus is the Status of a module.
+type ModuleStatus struct {
+	Module string `json:"module"`
+	*VersionStatus
+}
+
+func WithModules(modules ...Module) Option {
+	return func(m *Migrator) {
+		for _, module := range modules {
+			if err := m.AddModule(module); err != nil {
+				m.logger.Error("can't add module", "module", module.Name, "error", err)
+			}
+		}
+	}
+}
+
+// AddModule configures a module migrating the database of the migrator, see Module.
+func (m *Migrator) AddModule(module Module) error {
+	if module.Name == "" {
+		return errModuleName
+	}
+
+	for _, configured := range m.modules {
+		if configured.Name == module.Name {
+			return fmt.Errorf("%w: %s", errModuleExists, module.Name)
+		}
+	}
+
+	if module.MigrationsTable == "" {
+		module.MigrationsTable = defaultMigrationsTable + "_" + module.Name
+	}
+
+	m.modules = append(m.modules, &moduleMigrator{Module: module})
+	if m.ignoredTables != nil {
+		m.ignoredTables[module.MigrationsTable] = struct{}{}
+	}
+	return nil
+}
+
+// Modules returns the configured modules, in the order they were added.
+func (m *Migrator) Modules() []Module {
+	modules := make([]Module, 0, len(m.modules))
+	for _, module := range m.modules {
+		modules = append(modules, module.Module)
+	}
+	return modules
+}
+
+// Module returns the migrator of the module name, opened on first use with the url, logger and
+// settings of m at that time, and closed along with m.
+func (m *Migrator) Module(name string) (*Migrator, error) {
+	var found *moduleMigrator
+	for _, module := range m.modules {
+		if module.Name == name {
+			found = module
+			break
+		}
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("%w: %s", errUnknownModule, name)
+	}
+
+	if found.migrator != nil {
+		return found.migrator, nil
+	}
+
+	if m.databaseURL == "" {
+		return nil, errModulesNeedURL
+	}
+
+	migrator, err := NewFromURL(m.databaseURL, found.Path, nil, WithLogger(m.logger),
+		WithPrefetchMigrations(m.prefetchMigrations), WithLockTimeout(m.lockTimeout),
+		WithMigrationsTable(found.MigrationsTable), WithDriverParams(m.driverParams),
+		withRunSettingsOf(m), withPolicySettingsOf(m))
+	if err != nil {
+		return nil, fmt.Errorf("module %s: %w", name, err)
+	}
+
+	migrator.db = m.db
+	for table := range m.ignoredTables {
+		migrator.ignoredTables[table] = struct{}{}
+	}
+
+	found.migrator = migrator
+	return migrator, nil
+}
+
+// ModulesStatus returns the Status of every module, in the order they were added.
+func (m *Migrator) ModulesStatus() ([]*ModuleStatus, error) {
+	statuses := make([]*ModuleStatus, 0, len(m.modules))
+	for _, module := range m.modules {
+		migrator, err := m.Module(module.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		status, err := migrator.Status()
+		if err != nil {
+			return nil, fmt.Errorf("module %s: %w", module.Name, err)
+		}
+		statuses = append(statuses, &ModuleStatus{Module: module.Name, VersionStatus: status})
+	}
+	return statuses, nil
+}
+
+// orderedModules returns the modules ordered so that each comes after the modules it requires,
+// keeping the order they were added otherwise.
+func (m *Migrator) orderedModules() ([]*moduleMigrator, error) {
+	byName := make(map[string]*moduleMigrator, len(m.modules))
+	for _, module := range m.modules {
+		byName[module.Name] = module
+	}
+
+	ordered := make([]*moduleMigrator, 0, len(m.modules))
+	visited := make(map[string]bool, len(m.modules))
+
+	var visit func(module *moduleMigrator, path []string) error
+	visit = func(module *moduleMigrator, path []string) error {
+		done, ok := visited[module.Name]
+		if ok {
+			if !done {
+				return fmt.Errorf("%w: %s", errModuleCycle, strings.Join(append(path, module.Name), " -> "))
+			}
+			return nil
+		}
+
+		visited[module.Name] = false
+		for _, requirement := range module.Requires {
+			required, ok := byName[requirement.Module]
+			if !ok {
+				return fmt.Errorf("%w: %s requires %s", errUnknownModule, module.Name, requirement.Module)
+			}
+
+			if err := visit(required, append(path, module.Name)); err != nil {
+				return err
+			}
+		}
+
+		visited[module.Name] = true
+		ordered = append(ordered, module)
+		return nil
+	}
+
+	for _, module := range m.modules {
+		if err := visit(module, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// checkRequirements fails when a module required by module is below the required version, or
+// when its migrations don't reach it.
+func (m *Migrator) checkRequirements(module *moduleMigrator, applied bool) error {
+	for _, requirement := range module.Requires {
+		required, err := m.Module(requirement.Module)
+		if err != nil {
+			return err
+		}
+
+		status, _, err := required.status()
+		if err != nil {
+			return fmt.Errorf("module %s: %w", requirement.Module, err)
+		}
+
+		reached := status.LatestAvailable
+		if applied {
+			reached = status.Current
+		}
+
+		if reached == nil || *reached < requirement.Version {
+			return fmt.Errorf("%w: %s requires %s >= %d", errRequirement, module.Name, requirement.Module, requirement.Version)
+		}
+	}
+	return nil
+}
+
+// UpAllModules applies the pending migrations of every module, each after the modules it requires
+// and in the order they were added otherwise, stopping at the first failure. The requirements are
+// checked against the migrations of the modules before anything runs, then against the applied
+// versions before each module.
+func (m *Migrator) UpAllModules() error {
+	modules, err := m.orderedModules()
+	if err != nil {
+		return err
+	}
+
+	for _, module := range modules {
+		if err = m.checkRequirements(module, false); err != nil {
+			return err
+		}
+	}
+
+	for _, module := range modules {
+		if err = m.checkRequirements(module, true); err != nil {
+			return err
+		}
+
+		migrator, err := m.Module(module.Name)
+		if err != nil {
+			return err
+		}
+
+		m.logger.Info("migrating module", "module", module.Name)
+		if err = migrator.Up(-1); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			return fmt.Errorf("module %s: %w", module.Name, err)
+		}
+	}
+	return nil
+}
+
+// closeModules closes the migrators opened by Module.
+func (m *Migrator) closeModules() error {
+	var errs []error
+	for _, module := range m.modules {
+		if module.migrator == nil {
+			continue
+		}
+
+		sourceErr, databaseErr := module.migrator.close()
+		if err := errors.Join(sourceErr, databaseErr); err != nil {
+			errs = append(errs, fmt.Errorf("module %s: %w", module.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// selectModule makes the commands run on the migrator of --module, keeping the root migrator to
+// close it, and rejects --all-modules for the commands not supporting it.
+func (builder *migratorCobraCommandBuilder) selectModule(cmd *cobra.Command) error {
+	if builder.allModulesPtr {
+		if builder.modulePtr != "" {
+			return errors.New("--module and --all-modules can't be used together")
+		}
+		if cmd.Parent() != builder.migrateCommand || (cmd.Name() != "up" && cmd.Name() != "version") {
+			return errAllModules
+		}
+		return nil
+	}
+
+	if builder.modulePtr == "" {
+		return nil
+	}
+
+	migrator, err := builder.migrator.Module(builder.modulePtr)
+	if err != nil {
+		return err
+	}
+
+	builder.rootMigrator = builder.migrator
+	builder.migrator = migrator
+	return nil
+}
+
+func (builder *migratorCobraCommandBuilder) printModulesStatus() {
+	statuses, err := builder.migrator.ModulesStatus()
+	if err != nil {
+		builder.migrator.logger.Fatal(err.Error())
+	}
+
+	if builder.versionJSONPtr {
+		if err = json.NewEncoder(os.Stdout).Encode(statuses); err != nil {
+			builder.migrator.logger.Fatal(err.Error())
+		}
+		return
+	}
+
+	for _, status := range statuses {
+		version := "none"
+		if status.Current != nil {
+			version = fmt.Sprint(*status.Current)
+			if status.Dirty {
+				version += " (dirty)"
+			}
+		}
+		fmt.Printf("%s\t%s\t%d pending\n", status.Module, version, status.PendingCount)
+	}
+}
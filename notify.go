@@ -0,0 +1,109 @@
+package migrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const defaultNotifyChannel = "file_migrator"
+
+var errNoListener = errors.New("no listener, call SetListener first")
+
+// MigrationEvent is notified on the postgres channel of EnableNotifications after each migration.
+type MigrationEvent struct {
+	Version   uint              `json:"version"`
+	Direction string            `json:"direction"`
+	AppliedAt time.Time         `json:"applied_at"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// Listener delivers the payloads notified on a postgres channel until ctx is done, such as the
+// one of the pqlistener package.
+type Listener interface {
+	Listen(ctx context.Context, channel string) (<-chan string, error)
+}
+
+// WithNotifications see EnableNotifications.
+func WithNotifications(channel string) Option {
+	return func(m *Migrator) {
+		m.EnableNotifications(channel)
+	}
+}
+
+// EnableNotifications sends a MigrationEvent with NOTIFY on channel, file_migrator when empty,
+// after each applied migration. It needs SetDB and postgres.
+func (m *Migrator) EnableNotifications(channel string) {
+	if channel == "" {
+		channel = defaultNotifyChannel
+	}
+	m.notifyChannel = channel
+}
+
+func WithListener(listener Listener) Option {
+	return func(m *Migrator) {
+		m.listener = listener
+	}
+}
+
+// SetListener sets how Subscribe and WaitForVersion receive the notifications of EnableNotifications.
+func (m *Migrator) SetListener(listener Listener) {
+	m.listener = listener
+}
+
+func (m *Migrator) channel() string {
+	if m.notifyChannel == "" {
+		return defaultNotifyChannel
+	}
+	return m.notifyChannel
+}
+
+func (m *Migrator) notifyMigration(entry *HistoryEntry) {
+	if !entry.Success || m.notifyChannel == "" || m.db == nil || m.dialect != DialectPostgres {
+		return
+	}
+
+	payload, err := json.Marshal(&MigrationEvent{Version: entry.Version, Direction: entry.Direction,
+		AppliedAt: entry.AppliedAt, Labels: entry.Labels})
+	if err == nil {
+		_, err = m.db.Exec("SELECT pg_notify($1, $2)", m.notifyChannel, string(payload))
+	}
+	if err != nil {
+		m.logger.Error("can't notify the migration", "version", entry.Version, "channel", m.notifyChannel, "error", err)
+	}
+}
+
+// Subscribe delivers the migrations applied by any migrator notifying on the channel of
+// EnableNotifications, until ctx is done or the listener fails.
+func (m *Migrator) Subscribe(ctx context.Context) (<-chan *MigrationEvent, error) {
+	if m.listener == nil {
+		return nil, errNoListener
+	}
+
+	payloads, err := m.listener.Listen(ctx, m.channel())
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan *MigrationEvent)
+	go func() {
+		defer close(events)
+
+		for payload := range payloads {
+			event := &MigrationEvent{}
+			if err := json.Unmarshal([]byte(payload), event); err != nil {
+				m.logger.Error("can't read migration event", "payload", payload, "error", err)
+				continue
+			}
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
@@ -0,0 +1,247 @@
+package migrator
+
+import (
+	"errors"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"net/url"
+	"time"
+)
+
+const defaultMigrationsTable = "schema_migrations"
+
+var (
+	errMigrationsTableNeedsURL = errors.New("the migrations table can only be changed for a migrator created with NewFromURL")
+	errDriverParamsNeedURL     = errors.New("driver params can only be set for a migrator created with NewFromURL")
+)
+
+type Option func(*Migrator)
+
+func WithLogger(logger Logger) Option {
+	return func(m *Migrator) {
+		m.logger = logger
+	}
+}
+
+func WithPrefetchMigrations(n uint) Option {
+	return func(m *Migrator) {
+		m.prefetchMigrations = n
+	}
+}
+
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(m *Migrator) {
+		m.lockTimeout = timeout
+	}
+}
+
+// WithMigrationsTable sets the table the version is kept in, it's only supported by NewFromURL.
+func WithMigrationsTable(table string) Option {
+	return func(m *Migrator) {
+		m.migrationsTable = table
+	}
+}
+
+// WithDriverParams adds query parameters, such as x-statement-timeout or x-multi-statement, to the
+// url the driver is opened with, it's only supported by NewFromURL.
+func WithDriverParams(params map[string]string) Option {
+	return func(m *Migrator) {
+		for key, value := range params {
+			m.driverParams[key] = value
+		}
+	}
+}
+
+// withRunSettingsOf makes a migrator opened by m, for a shadow database or a module, connect and
+// run and render its migrations the way m does.
+func withRunSettingsOf(m *Migrator) Option {
+	return func(to *Migrator) {
+		to.logFormat = m.logFormat
+		to.sessionParams = mergeParams(nil, m.sessionParams)
+		to.isolation = m.isolation
+		to.connectTimeout = m.connectTimeout
+		to.keepAlive = m.keepAlive
+		to.pool = m.pool
+		to.terminator = m.terminator
+		to.splitStatements = m.splitStatements
+		to.savepoints = m.savepoints
+		to.oscArgs = m.oscArgs
+		to.concurrentIndexes = m.concurrentIndexes
+		to.templateValues = m.templateValues
+		to.templateEnv = m.templateEnv
+		to.profile = m.profile
+		to.macros = m.macros
+	}
+}
+
+// withPolicySettingsOf makes a module migrator check, lock and record its runs the way m does.
+func withPolicySettingsOf(m *Migrator) Option {
+	return func(to *Migrator) {
+		to.lockHeartbeat = m.lockHeartbeat
+		to.statementTracking = m.statementTracking
+		to.manifestVerifier = m.manifestVerifier
+		to.requireSigned = m.requireSigned
+		to.approvalVerifier = m.approvalVerifier
+		to.historyEnabled = m.historyEnabled
+		to.historySQLMode = m.historySQLMode
+		to.historySQLStore = m.historySQLStore
+		to.auditEnabled = m.auditEnabled
+		to.outOfOrderAllowed = m.outOfOrderAllowed
+		to.readOnly = m.readOnly
+		to.largeTableLimit = m.largeTableLimit
+		to.largeTableAction = m.largeTableAction
+		to.largeTableAcked = m.largeTableAcked
+		to.labels = m.labels
+		to.downCheck = m.downCheck
+	}
+}
+
+func newMigrator(databaseName string, opts []Option) *Migrator {
+	migrator := &Migrator{
+		databaseName:       databaseName,
+		logger:             defaultLogger,
+		dialect:            dialectOf(databaseName),
+		prefetchMigrations: migrate.DefaultPrefetchMigrations,
+		lockTimeout:        migrate.DefaultLockTimeout,
+		migrationsTable:    defaultMigrationsTable,
+		driverParams:       make(map[string]string),
+	}
+
+	for _, opt := range opts {
+		opt(migrator)
+	}
+
+	migrator.ignoredTables = map[string]struct{}{
+		migrator.migrationsTable: {}, repeatableTable: {}, seedTable: {}, historyTable: {}, auditTable: {}, tagTable: {}, statementsTable: {},
+	}
+	for _, module := range migrator.modules {
+		migrator.ignoredTables[module.MigrationsTable] = struct{}{}
+	}
+	return migrator
+}
+
+// NewFromURL opens the database driver registered for the scheme of databaseURL, which the
+// Migrator then owns. golang-migrate drivers register themselves when their package is imported.
+func NewFromURL(databaseURL, migrationsFilePath string, migrateFunc migrateFunc, opts ...Option) (*Migrator, error) {
+	u, err := url.Parse(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator := newMigrator(u.Scheme, opts)
+	migrator.databaseURL = databaseURL
+
+	driver, err := database.Open(migrator.driverURL())
+	if err != nil {
+		return nil, err
+	}
+	migrator.driver = &databaseDriver{Driver: driver, migrator: migrator}
+
+	if err = migrator.SetMigrationsPath(migrationsFilePath); err != nil {
+		_ = driver.Close()
+		return nil, err
+	}
+
+	migrator.AddMigrateFunc(migrateFunc)
+
+	return migrator, nil
+}
+
+// driverURL returns the url golang-migrate opens the database driver with.
+func (m *Migrator) driverURL() string {
+	if m.keepAlive > 0 && m.dialect == DialectMySQL {
+		return keepAliveDSN(m.paramsURL(), m.keepAlive)
+	}
+	return m.paramsURL()
+}
+
+// paramsURL returns the url of the database with the driver and session params of the migrator.
+func (m *Migrator) paramsURL() string {
+	if m.migrationsTable == defaultMigrationsTable && len(m.driverParams) == 0 && len(m.sessionParams) == 0 {
+		return m.databaseURL
+	}
+
+	u, err := url.Parse(m.databaseURL)
+	if err != nil {
+		return m.databaseURL
+	}
+
+	query := u.Query()
+	for key, value := range m.driverParams {
+		query.Set(key, value)
+	}
+
+	if m.migrationsTable != defaultMigrationsTable {
+		query.Set("x-migrations-table", m.migrationsTable)
+	}
+	m.addSessionParams(query)
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// reopenDriver replaces the driver opened by NewFromURL with one opened with the current url.
+func (m *Migrator) reopenDriver() error {
+	driver, err := database.Open(m.driverURL())
+	if err != nil {
+		return err
+	}
+
+	wrapped := m.driver.(*databaseDriver)
+	if err = wrapped.Driver.Close(); err != nil {
+		m.logger.Error("can't close database driver", "error", err)
+	}
+	wrapped.Driver = driver
+
+	return m.SetMigrationsPath(m.migrationsFilePath)
+}
+
+func (m *Migrator) SetPrefetchMigrations(n uint) {
+	m.prefetchMigrations = n
+	m.migrate.PrefetchMigrations = n
+}
+
+func (m *Migrator) SetLockTimeout(timeout time.Duration) {
+	m.lockTimeout = timeout
+	m.migrate.LockTimeout = timeout
+}
+
+// SetMigrationsTable reopens the database driver so that the version is kept in table.
+func (m *Migrator) SetMigrationsTable(table string) error {
+	if m.databaseURL == "" {
+		return errMigrationsTableNeedsURL
+	}
+
+	if table == m.migrationsTable {
+		return nil
+	}
+
+	previous := m.migrationsTable
+	m.migrationsTable = table
+
+	if err := m.reopenDriver(); err != nil {
+		m.migrationsTable = previous
+		return err
+	}
+
+	delete(m.ignoredTables, previous)
+	m.ignoredTables[table] = struct{}{}
+
+	return nil
+}
+
+// SetDriverParams reopens the database driver with params added to its url.
+func (m *Migrator) SetDriverParams(params map[string]string) error {
+	if m.databaseURL == "" {
+		return errDriverParamsNeedURL
+	}
+
+	previous := m.driverParams
+	m.driverParams = mergeParams(previous, params)
+
+	if err := m.reopenDriver(); err != nil {
+		m.driverParams = previous
+		return err
+	}
+	return nil
+}
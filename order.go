@@ -0,0 +1,176 @@
+package migrator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var errOutOfOrder = errors.New("found migrations older than the applied version that were never applied")
+
+// AllowOutOfOrder makes Up apply migrations older than the applied version instead of failing on them.
+func (m *Migrator) AllowOutOfOrder() {
+	m.outOfOrderAllowed = true
+}
+
+// appliedVersions replays the successful entries of a history to find the versions it left applied.
+func appliedVersions(entries []*HistoryEntry) map[uint]struct{} {
+	applied := make(map[uint]struct{})
+
+	for _, entry := range entries {
+		if !entry.Success {
+			continue
+		}
+
+		if entry.Direction == directionUp {
+			applied[entry.Version] = struct{}{}
+		} else {
+			delete(applied, entry.Version)
+		}
+	}
+	return applied
+}
+
+// recordedHistory returns the history of the database, nil when none was ever recorded, without
+// creating its table.
+func (m *Migrator) recordedHistory() ([]*HistoryEntry, error) {
+	if m.db == nil {
+		return nil, nil
+	}
+
+	if !m.historyEnabled {
+		rows, err := m.db.Query(fmt.Sprintf("SELECT version FROM %s WHERE 1 = 0", historyTable))
+		if err != nil {
+			return nil, nil
+		}
+		if err = rows.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return m.History()
+}
+
+// outOfOrderVersions returns the migrations of the source below the applied version that were never
+// applied, as golang-migrate only moves forward from the applied version and would skip them. The
+// history tells which of them were applied, versions older than the history itself being assumed
+// applied before it was recorded. Without history, the migrations below the applied version can't
+// be told apart, which is logged instead.
+func (m *Migrator) outOfOrderVersions() ([]uint, error) {
+	current, _, err := m.migrate.Version()
+	if err != nil {
+		return nil, nil
+	}
+
+	candidates := make([]uint, 0)
+	for _, version := range m.source.versions() {
+		if _, ok := m.source.migrations.Up(version); ok && version < current {
+			candidates = append(candidates, version)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	entries, err := m.recordedHistory()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 {
+		if !m.historyWarned {
+			m.historyWarned = true
+			m.logger.Error("can't tell the migrations below the applied version that were never applied without history, enable it to catch them",
+				"version", current, "migrations", len(candidates))
+		}
+		return nil, nil
+	}
+
+	oldest := entries[0].Version
+	for _, entry := range entries {
+		if entry.Version < oldest {
+			oldest = entry.Version
+		}
+	}
+
+	applied := appliedVersions(entries)
+	versions := make([]uint, 0)
+	for _, version := range candidates {
+		if _, ok := applied[version]; !ok && version > oldest {
+			versions = append(versions, version)
+		}
+	}
+
+	return versions, nil
+}
+
+func (m *Migrator) migrationName(version uint) string {
+	migration, ok := m.source.migrations.Up(version)
+	if !ok {
+		return fmt.Sprintf("%d", version)
+	}
+
+	if migration.Raw == "" {
+		return fmt.Sprintf("go migration %d (%s)", version, migration.Identifier)
+	}
+	return migration.Raw
+}
+
+func (m *Migrator) checkMonotonic() error {
+	versions, err := m.outOfOrderVersions()
+	if err != nil || len(versions) == 0 {
+		return err
+	}
+
+	if m.outOfOrderAllowed {
+		return m.applyOutOfOrder(versions)
+	}
+
+	names := make([]string, len(versions))
+	for i, version := range versions {
+		names[i] = m.migrationName(version)
+	}
+
+	return fmt.Errorf("%w, rebase them or allow out-of-order migrations: %s", errOutOfOrder, strings.Join(names, ", "))
+}
+
+// applyOutOfOrder runs the given up migrations without moving the version, which is already past them.
+func (m *Migrator) applyOutOfOrder(versions []uint) (err error) {
+	driver := m.driver.(*databaseDriver)
+
+	if err = driver.Lock(); err != nil {
+		return err
+	}
+
+	defer func() {
+		if e := driver.Unlock(); e != nil && err == nil {
+			err = e
+		}
+	}()
+
+	for _, version := range versions {
+		r, _, err := m.source.ReadUp(version)
+		if err != nil {
+			return err
+		}
+
+		body, err := io.ReadAll(r)
+		_ = r.Close()
+		if err != nil {
+			return err
+		}
+
+		m.logger.Info("applying out-of-order migration", "migration", m.migrationName(version))
+
+		driver.running = &runningMigration{version: version, direction: directionUp, startedAt: time.Now()}
+		if err = driver.Run(bytes.NewReader(body)); err != nil {
+			return err
+		}
+		driver.finish(nil)
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package migrator
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+var (
+	errNoUser   = errors.New("the database url has no user to set the password of")
+	errNoScheme = errors.New("the database url has no scheme")
+)
+
+// URLWithPassword returns databaseURL with the password of its user replaced by password, so it
+// can be kept out of the url given in process arguments or environment listings.
+func URLWithPassword(databaseURL, password string) (string, error) {
+	scheme, rest, ok := strings.Cut(databaseURL, "://")
+	if !ok {
+		return "", errNoScheme
+	}
+
+	// not url.Parse, which rejects the user:password@tcp(host:port)/db urls of mysql
+	userinfo, address, ok := strings.Cut(rest, "@")
+	if !ok || strings.Contains(userinfo, "/") {
+		return "", errNoUser
+	}
+
+	username, _, _ := strings.Cut(userinfo, ":")
+	username, err := url.PathUnescape(username)
+	if err != nil {
+		return "", err
+	}
+
+	if username == "" {
+		return "", errNoUser
+	}
+	return scheme + "://" + url.UserPassword(username, password).String() + "@" + address, nil
+}
+
+// urlWithoutPassword returns databaseURL without the password of its user nor its query, which may
+// hold credentials too.
+func urlWithoutPassword(databaseURL string) string {
+	databaseURL, _, _ = strings.Cut(databaseURL, "?")
+	scheme, rest, ok := strings.Cut(databaseURL, "://")
+	if !ok {
+		return databaseURL
+	}
+
+	// the userinfo ends at the last @ before the path, passwords of mysql urls being unescaped
+	path := strings.Index(rest, "/")
+	if path < 0 {
+		path = len(rest)
+	}
+	if at := strings.LastIndex(rest[:path], "@"); at >= 0 {
+		username, _, _ := strings.Cut(rest[:at], ":")
+		rest = username + rest[at:]
+	}
+	return scheme + "://" + rest
+}
+
+// ReadPassword reads a password from r, such as a Docker or Kubernetes secret, without its
+// trailing newline.
+func ReadPassword(r io.Reader) (string, error) {
+	password, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(password), "\r\n"), nil
+}
+
+// ReadPasswordFile reads the password of the file at path, see ReadPassword.
+func ReadPasswordFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	return ReadPassword(f)
+}
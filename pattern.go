@@ -0,0 +1,286 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	PatternAddColumn    = "add-column"
+	PatternAddIndex     = "add-index"
+	PatternRenameColumn = "rename-column"
+)
+
+var (
+	errUnknownPattern   = errors.New("pattern must be add-column, add-index or rename-column")
+	errPatternTable     = errors.New("the pattern needs a table")
+	errPatternColumns   = errors.New("the pattern needs at least one column")
+	errPatternColumn    = errors.New("columns must be NAME:TYPE")
+	errPatternDefault   = errors.New("adding a NOT NULL column needs a default to backfill the existing rows")
+	errPatternRenameTo  = errors.New("rename-column needs one column and the name to rename it to")
+	errPatternUnique    = errors.New("only add-index can be unique")
+	errPatternRenameOne = errors.New("rename-column only renames one column")
+)
+
+// PatternParams are the parameters of the built-in migration patterns: add-column adds Columns to
+// Table, backfilling Default when they are NOT NULL; add-index indexes Columns of Table without
+// blocking writes; rename-column adds the column RenameTo and backfills it from the only column of
+// Columns, the old column being dropped by a later migration once the application reads the new one.
+type PatternParams struct {
+	Table    string
+	Columns  []*Column
+	Default  string
+	Unique   bool
+	RenameTo string
+}
+
+// ParseColumnSpec reads the NAME:TYPE columns of the patterns, nullable unless notNull, the type
+// being optional for add-index.
+func ParseColumnSpec(spec string, notNull bool) (*Column, error) {
+	name, columnType, _ := strings.Cut(spec, ":")
+	if name == "" {
+		return nil, fmt.Errorf("%w: %s", errPatternColumn, spec)
+	}
+	return &Column{Name: name, Type: columnType, Nullable: !notNull}, nil
+}
+
+func columnNames(columns []*Column) []string {
+	names := make([]string, 0, len(columns))
+	for _, column := range columns {
+		names = append(names, column.Name)
+	}
+	return names
+}
+
+// patternName is the name of the migration of pattern when none is given, e.g. add_age_to_users.
+func patternName(pattern string, params PatternParams) string {
+	columns := strings.Join(columnNames(params.Columns), "_")
+
+	switch pattern {
+	case PatternAddColumn:
+		return fmt.Sprintf("add_%s_to_%s", columns, params.Table)
+	case PatternAddIndex:
+		return fmt.Sprintf("add_index_%s_%s", params.Table, columns)
+	default:
+		return fmt.Sprintf("rename_%s_%s_to_%s", params.Table, columns, params.RenameTo)
+	}
+}
+
+// indexName follows the postgres naming, <table>_<columns>_idx, or _key for unique indexes.
+func indexName(table string, columns []*Column, unique bool) string {
+	suffix := "idx"
+	if unique {
+		suffix = "key"
+	}
+	return fmt.Sprintf("%s_%s_%s", table, strings.Join(columnNames(columns), "_"), suffix)
+}
+
+func addColumnSQL(d Dialect, table string, column *Column, defaultValue string) ([]string, []string, error) {
+	quotedTable, quotedColumn := d.quote(table), d.quote(column.Name)
+	down := []string{fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", quotedTable, quotedColumn)}
+
+	definition := fmt.Sprintf("%s %s", quotedColumn, column.Type)
+	if defaultValue != "" {
+		definition += " DEFAULT " + defaultValue
+	}
+
+	if column.Nullable {
+		return []string{fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", quotedTable, definition)}, down, nil
+	}
+
+	if defaultValue == "" {
+		return nil, nil, fmt.Errorf("%w: %s", errPatternDefault, column.Name)
+	}
+
+	if d != DialectPostgres {
+		// adding a NOT NULL column with a default is instant on mysql 8, and the only way on sqlite
+		return []string{fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s NOT NULL", quotedTable, definition)}, down, nil
+	}
+
+	// the default fills the existing rows without rewriting the table, and the validated check
+	// constraint spares SET NOT NULL the scan of the table under an exclusive lock
+	constraint := d.quote(fmt.Sprintf("%s_%s_not_null", table, column.Name))
+	return []string{
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", quotedTable, definition),
+		fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s CHECK (%s IS NOT NULL) NOT VALID", quotedTable, constraint, quotedColumn),
+		fmt.Sprintf("ALTER TABLE %s VALIDATE CONSTRAINT %s", quotedTable, constraint),
+		fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL", quotedTable, quotedColumn),
+		fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", quotedTable, constraint),
+	}, down, nil
+}
+
+func addIndexSQL(d Dialect, table string, columns []*Column, unique bool) ([]string, []string) {
+	name := indexName(table, columns, unique)
+
+	quotedColumns := make([]string, 0, len(columns))
+	for _, column := range columns {
+		quotedColumns = append(quotedColumns, d.quote(column.Name))
+	}
+
+	create := "CREATE INDEX"
+	if unique {
+		create = "CREATE UNIQUE INDEX"
+	}
+
+	switch d {
+	case DialectPostgres:
+		return []string{fmt.Sprintf("%s CONCURRENTLY %s ON %s (%s)", create, d.quote(name), d.quote(table), strings.Join(quotedColumns, ", "))},
+			[]string{fmt.Sprintf("DROP INDEX CONCURRENTLY %s", d.quote(name))}
+	case DialectMySQL:
+		return []string{fmt.Sprintf("%s %s ON %s (%s) ALGORITHM=INPLACE LOCK=NONE", create, d.quote(name), d.quote(table), strings.Join(quotedColumns, ", "))},
+			[]string{fmt.Sprintf("DROP INDEX %s ON %s", d.quote(name), d.quote(table))}
+	default:
+		return []string{fmt.Sprintf("%s %s ON %s (%s)", create, d.quote(name), d.quote(table), strings.Join(quotedColumns, ", "))},
+			[]string{fmt.Sprintf("DROP INDEX %s", d.quote(name))}
+	}
+}
+
+// renameColumnSQL expands rather than renames in place, so that the running application keeps
+// reading the old column until it's deployed reading the new one.
+func renameColumnSQL(d Dialect, table string, column *Column, renameTo string) ([]string, []string) {
+	quotedTable, from, to := d.quote(table), d.quote(column.Name), d.quote(renameTo)
+	return []string{
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", quotedTable, to, column.Type),
+			fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s IS NULL", quotedTable, to, from, to),
+		},
+		[]string{fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", quotedTable, to)}
+}
+
+// patternSQL returns the up and down statements of pattern on the dialect d, and whether they must
+// run outside of a transaction.
+func patternSQL(d Dialect, pattern string, params PatternParams) ([]string, []string, bool, error) {
+	if d == DialectUnknown {
+		return nil, nil, false, errUnsupportedDialect
+	}
+
+	if params.Table == "" {
+		return nil, nil, false, errPatternTable
+	}
+
+	if len(params.Columns) == 0 {
+		return nil, nil, false, errPatternColumns
+	}
+
+	if params.Unique && pattern != PatternAddIndex {
+		return nil, nil, false, errPatternUnique
+	}
+
+	needsType := func(column *Column) error {
+		if column.Type == "" {
+			return fmt.Errorf("%w: %s", errPatternColumn, column.Name)
+		}
+		return nil
+	}
+
+	switch pattern {
+	case PatternAddColumn:
+		var up, down []string
+		notNull := false
+		for _, column := range params.Columns {
+			if err := needsType(column); err != nil {
+				return nil, nil, false, err
+			}
+
+			columnUp, columnDown, err := addColumnSQL(d, params.Table, column, params.Default)
+			if err != nil {
+				return nil, nil, false, err
+			}
+			up = append(up, columnUp...)
+			down = append(columnDown, down...)
+			notNull = notNull || !column.Nullable
+		}
+		// the constraint is only validated without blocking writes outside of the transaction adding it
+		return up, down, d == DialectPostgres && notNull, nil
+	case PatternAddIndex:
+		up, down := addIndexSQL(d, params.Table, params.Columns, params.Unique)
+		return up, down, d == DialectPostgres, nil
+	case PatternRenameColumn:
+		if params.RenameTo == "" {
+			return nil, nil, false, errPatternRenameTo
+		}
+		if len(params.Columns) > 1 {
+			return nil, nil, false, errPatternRenameOne
+		}
+		if err := needsType(params.Columns[0]); err != nil {
+			return nil, nil, false, err
+		}
+
+		up, down := renameColumnSQL(d, params.Table, params.Columns[0], params.RenameTo)
+		return up, down, false, nil
+	default:
+		return nil, nil, false, fmt.Errorf("%w: %s", errUnknownPattern, pattern)
+	}
+}
+
+func (m *Migrator) generatePattern(pattern string, params PatternParams,
+	timeZoneName string, format string, name string, ext string, seq bool, seqDigits int) (*generatedMigration, error) {
+
+	up, down, noTransaction, err := patternSQL(m.dialect, pattern, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		name = patternName(pattern, params)
+	}
+
+	return m.generateStatements(params.Table, up, down, noTransaction, timeZoneName, format, name, ext, seq, seqDigits)
+}
+
+// generateStatements renders the up and down statements of a migration changing table, marked
+// -- migrator:no-transaction when they must run one by one.
+func (m *Migrator) generateStatements(table string, up []string, down []string, noTransaction bool,
+	timeZoneName string, format string, name string, ext string, seq bool, seqDigits int) (*generatedMigration, error) {
+
+	upFile, downFile, err := m.upAndDownFilePath(timeZoneName, format, name, ext, seq, seqDigits)
+	if err != nil {
+		return nil, err
+	}
+
+	generated := &generatedMigration{
+		upFile:   upFile,
+		downFile: downFile,
+		upSQL:    renderSQL(map[string][]string{table: up}, []string{table}, m.statementTerminator()),
+		downSQL:  renderSQL(map[string][]string{table: down}, []string{table}, m.statementTerminator()),
+	}
+
+	if noTransaction {
+		generated.upSQL = append([]byte(noTransactionDirective), generated.upSQL...)
+		generated.downSQL = append([]byte(noTransactionDirective), generated.downSQL...)
+	}
+	return generated, nil
+}
+
+// MakePatternMigrate creates the migration of the built-in pattern, see PatternParams, for the
+// dialect of the migrator, named after the pattern when name is empty.
+func (m *Migrator) MakePatternMigrate(pattern string, params PatternParams,
+	timeZoneName string, format string, name string, ext string, seq bool, seqDigits int) error {
+
+	generated, err := m.generatePattern(pattern, params, timeZoneName, format, name, ext, seq, seqDigits)
+	if err != nil {
+		return err
+	}
+
+	if err = m.writeMigrate(generated); err != nil {
+		return err
+	}
+
+	if pattern == PatternRenameColumn {
+		m.logger.Info("drop the old column in a later migration, once the application reads and writes the new one",
+			"table", params.Table, "column", params.Columns[0].Name)
+	}
+	return nil
+}
+
+func (m *Migrator) MakePatternMigrateDryRun(w io.Writer, pattern string, params PatternParams,
+	timeZoneName string, format string, name string, ext string, seq bool, seqDigits int) error {
+
+	generated, err := m.generatePattern(pattern, params, timeZoneName, format, name, ext, seq, seqDigits)
+	if err != nil {
+		return err
+	}
+
+	return m.printMigrate(w, generated)
+}
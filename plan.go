@@ -0,0 +1,302 @@
+package migrator
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4"
+	"io/fs"
+	"os"
+	"time"
+)
+
+var (
+	errPlanStale     = errors.New("plan is stale")
+	errPlanAhead     = errors.New("plan target is ahead of the latest migration")
+	errPlanNoVersion = errors.New("plan target version doesn't exist")
+)
+
+type Plan struct {
+	ToolVersion string              `json:"tool_version"`
+	CreatedAt   time.Time           `json:"created_at"`
+	CreatedBy   string              `json:"created_by"`
+	Database    string              `json:"database"`
+	FromVersion *uint               `json:"from_version"`
+	ToVersion   *uint               `json:"to_version"`
+	Migrations  []*PlannedMigration `json:"migrations"`
+	Files       []*ManifestEntry    `json:"files"`
+	EstimatedMs *int64              `json:"estimated_ms,omitempty"`
+
+	checksum string
+	approval *signedApproval
+}
+
+// planUp lists the up migrations that take the database from its current version to target.
+func (m *Migrator) planUp(target uint) ([]*PlannedMigration, error) {
+	plan := make([]*PlannedMigration, 0)
+
+	current, dirty, err := m.migrate.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return nil, err
+	}
+
+	if dirty {
+		return nil, migrate.ErrDirty{Version: int(current)}
+	}
+
+	var version uint
+	var ok bool
+	if errors.Is(err, migrate.ErrNilVersion) {
+		version, ok = m.source.migrations.First()
+	} else {
+		version, ok = m.source.migrations.Next(current)
+	}
+
+	for ; ok && version <= target; version, ok = m.source.migrations.Next(version) {
+		migration, found := m.source.migrations.Up(version)
+		if !found {
+			return nil, fmt.Errorf("no up migration found for version %d", version)
+		}
+		plan = append(plan, &PlannedMigration{Version: version, Identifier: migration.Identifier, Direction: directionUp})
+	}
+
+	return plan, nil
+}
+
+// UpPlan lists the next n pending up migrations, all of them when n is 0 or less.
+func (m *Migrator) UpPlan(n int) ([]*PlannedMigration, error) {
+	plan, err := m.planUp(^uint(0))
+	if err != nil {
+		return nil, err
+	}
+
+	if n > 0 && n < len(plan) {
+		plan = plan[:n]
+	}
+	return plan, nil
+}
+
+// Plan captures the migrations that take the database to target, the latest version when target
+// is negative, and the checksums of the migrations directory, for ApplyPlan to run later.
+func (m *Migrator) Plan(target int) (*Plan, error) {
+	status, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	if status.Dirty {
+		return nil, migrate.ErrDirty{Version: int(*status.Current)}
+	}
+
+	manifest, err := buildManifest(m.source.fsys, m.source.path)
+	if err != nil {
+		return nil, err
+	}
+
+	database, err := m.planDatabase()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{
+		ToolVersion: manifest.ToolVersion,
+		CreatedAt:   manifest.CreatedAt,
+		CreatedBy:   operatorName(),
+		Database:    database,
+		FromVersion: status.Current,
+		Files:       manifest.Files,
+	}
+
+	switch {
+	case target < 0:
+		plan.ToVersion = status.LatestAvailable
+	case target > 0:
+		version := uint(target)
+		if _, ok := m.source.migrations.Up(version); !ok {
+			return nil, fmt.Errorf("%w: %d", errPlanNoVersion, version)
+		}
+		plan.ToVersion = &version
+	}
+
+	if plan.ToVersion == nil {
+		if status.Current != nil {
+			plan.Migrations, err = m.planDown(0)
+		}
+	} else if status.Current == nil || *plan.ToVersion >= *status.Current {
+		plan.Migrations, err = m.planUp(*plan.ToVersion)
+	} else {
+		plan.Migrations, err = m.planDown(*plan.ToVersion)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	checksums := make(map[string]string, len(plan.Files))
+	for _, entry := range plan.Files {
+		checksums[entry.File] = entry.Checksum
+	}
+	for _, planned := range plan.Migrations {
+		migration, ok := m.source.migrations.Up(planned.Version)
+		if planned.Direction == directionDown && !planned.Missing {
+			migration, ok = m.source.migrations.Down(planned.Version)
+		}
+
+		if ok {
+			planned.Checksum = checksums[migration.Raw]
+		}
+	}
+
+	if m.historyEnabled && len(plan.Migrations) > 0 && plan.Migrations[0].Direction == directionUp {
+		versions := make([]uint, len(plan.Migrations))
+		for i, planned := range plan.Migrations {
+			versions[i] = planned.Version
+		}
+
+		if estimate, ok, err := m.EstimateDuration(versions); err == nil && ok {
+			ms := estimate.Milliseconds()
+			plan.EstimatedMs = &ms
+		}
+	}
+
+	return plan, nil
+}
+
+// WritePlan writes plan as JSON to path, see ReadPlan.
+func WritePlan(path string, plan *Plan) error {
+	body, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(body, '\n'), 0666)
+}
+
+// ReadPlan reads a plan written by WritePlan, along with its approval when there is one.
+func ReadPlan(path string) (*Plan, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{checksum: checksumOf(body)}
+	if err = json.Unmarshal(body, plan); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	plan.approval, err = readApproval(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func sameVersion(a *uint, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func formatVersion(version *uint) string {
+	if version == nil {
+		return "none"
+	}
+	return fmt.Sprint(*version)
+}
+
+// planDatabase names the database a plan is made for: the scheme and the database the server
+// reports, which a tunnel doesn't change, or the url without its password when there's no handle.
+func (m *Migrator) planDatabase() (string, error) {
+	if m.db == nil {
+		if m.databaseURL == "" {
+			return m.databaseName, nil
+		}
+		return urlWithoutPassword(m.databaseURL), nil
+	}
+
+	var query string
+	switch m.dialect {
+	case DialectPostgres:
+		query = "SELECT current_database()"
+	case DialectMySQL:
+		query = "SELECT DATABASE()"
+	case DialectSQLite:
+		query = "SELECT file FROM pragma_database_list WHERE name = 'main'"
+	default:
+		return m.databaseName, nil
+	}
+
+	var name sql.NullString
+	if err := m.db.QueryRow(query).Scan(&name); err != nil {
+		return "", err
+	}
+	return m.databaseName + "://" + name.String, nil
+}
+
+// checkPlan makes sure neither the migrations nor the database changed since plan was made.
+func (m *Migrator) checkPlan(plan *Plan) error {
+	database, err := m.planDatabase()
+	if err != nil {
+		return err
+	}
+
+	if plan.Database != database {
+		return fmt.Errorf("%w: it was made for database %s, not %s", errPlanStale, plan.Database, database)
+	}
+
+	if err := (&Manifest{Files: plan.Files}).verify(m.source.fsys, m.source.path); err != nil {
+		return fmt.Errorf("%w: %w", errPlanStale, err)
+	}
+
+	status, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	if status.Dirty || !sameVersion(status.Current, plan.FromVersion) {
+		return fmt.Errorf("%w: database is at version %s, the plan was made at version %s",
+			errPlanStale, formatVersion(status.Current), formatVersion(plan.FromVersion))
+	}
+
+	if plan.ToVersion != nil && status.LatestAvailable != nil && *plan.ToVersion > *status.LatestAvailable {
+		return errPlanAhead
+	}
+	return nil
+}
+
+// ApplyPlan runs the migrations of a plan read by ReadPlan, refusing to when the migrations or the
+// version of the database changed since it was made, or when it isn't approved and RequireApproval is set.
+func (m *Migrator) ApplyPlan(plan *Plan) error {
+	return m.audited("apply", func() error {
+		if m.approvalVerifier != nil {
+			if err := m.checkApproval(plan); err != nil {
+				return err
+			}
+		}
+
+		if err := m.checkPlan(plan); err != nil {
+			return err
+		}
+
+		if len(plan.Migrations) == 0 {
+			return migrate.ErrNoChange
+		}
+
+		if err := m.checkDowns(plan.Migrations); err != nil {
+			return err
+		}
+
+		if err := m.checkLargeTables(plan.Migrations); err != nil {
+			return err
+		}
+
+		if plan.ToVersion == nil {
+			m.startProgress(-len(plan.Migrations), -1)
+			return m.migrate.Down()
+		}
+
+		m.startProgress(0, int(*plan.ToVersion))
+		return m.migrate.Migrate(*plan.ToVersion)
+	})
+}
@@ -0,0 +1,57 @@
+package pqlistener
+
+import (
+	"context"
+	"github.com/lib/pq"
+	"time"
+)
+
+// Listener implements migrator.Listener with a lib/pq listener connection, which reconnects when
+// the connection is lost.
+type Listener struct {
+	dsn                  string
+	minReconnectInterval time.Duration
+	maxReconnectInterval time.Duration
+}
+
+// New returns a Listener connecting to the postgres database of dsn.
+func New(dsn string) *Listener {
+	return &Listener{dsn: dsn, minReconnectInterval: 10 * time.Second, maxReconnectInterval: time.Minute}
+}
+
+func (l *Listener) Listen(ctx context.Context, channel string) (<-chan string, error) {
+	listener := pq.NewListener(l.dsn, l.minReconnectInterval, l.maxReconnectInterval, nil)
+	if err := listener.Listen(channel); err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+
+	payloads := make(chan string)
+	go func() {
+		defer close(payloads)
+		defer listener.Close()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case notification, ok := <-listener.Notify:
+				if !ok {
+					return
+				}
+				// a nil notification tells the connection was re-established
+				if notification == nil {
+					continue
+				}
+
+				select {
+				case payloads <- notification.Extra:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return payloads, nil
+}
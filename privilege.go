@@ -0,0 +1,86 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var errNotDroppable = errors.New("the connected role can't drop everything")
+
+const postgresForeignObjectsQuery = `SELECT c.relname, pg_get_userbyid(c.relowner)
+FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = current_schema() AND c.relkind IN ('r', 'p', 'v', 'm', 'S', 'f')
+	AND NOT pg_has_role(current_user, c.relowner, 'USAGE')
+ORDER BY c.relname`
+
+const mysqlDropPrivilegeQuery = `SELECT COUNT(*) FROM (
+	SELECT PRIVILEGE_TYPE FROM information_schema.USER_PRIVILEGES WHERE PRIVILEGE_TYPE = 'DROP'
+	UNION ALL
+	SELECT PRIVILEGE_TYPE FROM information_schema.SCHEMA_PRIVILEGES WHERE PRIVILEGE_TYPE = 'DROP' AND TABLE_SCHEMA = DATABASE()
+) p`
+
+// PrivilegeProblems lists what would keep the connected role from dropping the objects of the
+// database: objects owned by roles it isn't a member of on postgres, a missing DROP privilege on
+// mysql. It returns nothing when there is no database handle or the dialect isn't supported.
+func (m *Migrator) PrivilegeProblems() ([]string, error) {
+	if m.db == nil {
+		return nil, nil
+	}
+
+	problems := make([]string, 0)
+	switch m.dialect {
+	case DialectPostgres:
+		rows, err := m.db.Query(postgresForeignObjectsQuery)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var name, owner string
+			if err = rows.Scan(&name, &owner); err != nil {
+				return nil, err
+			}
+			problems = append(problems, fmt.Sprintf("%s is owned by %s", name, owner))
+		}
+
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+	case DialectMySQL:
+		var grants int
+		if err := m.db.QueryRow(mysqlDropPrivilegeQuery).Scan(&grants); err != nil {
+			return nil, err
+		}
+
+		if grants == 0 {
+			problems = append(problems, "the DROP privilege is missing")
+		}
+	}
+
+	return problems, nil
+}
+
+// checkPrivileges fails drop, and warns about down, when the connected role can't drop everything,
+// rather than leaving the schema half dropped.
+func (m *Migrator) checkPrivileges(command string) error {
+	problems, err := m.PrivilegeProblems()
+	if err != nil {
+		m.logger.Error("can't check privileges", "error", err)
+		return nil
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	if command == "drop" {
+		return fmt.Errorf("%w: %s", errNotDroppable, strings.Join(problems, ", "))
+	}
+
+	for _, problem := range problems {
+		m.logger.Error("down migrations may fail", "problem", problem)
+	}
+	return nil
+}
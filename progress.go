@@ -0,0 +1,107 @@
+package migrator
+
+import (
+	"errors"
+	"time"
+)
+
+type ProgressUpdate struct {
+	Version   uint
+	Direction string
+	Index     int
+	Total     int
+	Elapsed   time.Duration
+	Err       error
+}
+
+// WithProgress calls fn after each migration run by Up, Down, Goto and RollbackToTag, with its
+// position among the migrations of the command and the time elapsed since the command started.
+func WithProgress(fn func(ProgressUpdate)) Option {
+	return func(m *Migrator) {
+		m.progressFunc = fn
+	}
+}
+
+type progress struct {
+	index     int
+	total     int
+	startedAt time.Time
+}
+
+// stepsBetween counts the up migrations of the source after from up to to, or the reverse when going down.
+func (m *Migrator) stepsBetween(from int, to int) int {
+	low, high := from, to
+	if low > high {
+		low, high = high, low
+	}
+
+	steps := 0
+	for _, version := range m.source.versions() {
+		if _, ok := m.source.migrations.Up(version); ok && int(version) > low && int(version) <= high {
+			steps++
+		}
+	}
+	return steps
+}
+
+func (m *Migrator) latestVersion() int {
+	latest := -1
+	for _, version := range m.source.versions() {
+		latest = int(version)
+	}
+	return latest
+}
+
+// startProgress counts the migrations a command will run, steps being negative when going down
+// and 0 meaning all of them.
+func (m *Migrator) startProgress(steps int, target int) {
+	if m.progressFunc == nil {
+		return
+	}
+
+	current := m.currentVersion()
+
+	var total int
+	switch {
+	case target >= 0:
+		total = m.stepsBetween(current, target)
+	case steps >= 0:
+		total = m.stepsBetween(current, m.latestVersion())
+	default:
+		total = m.stepsBetween(current, -1)
+	}
+
+	if steps != 0 && abs(steps) < total {
+		total = abs(steps)
+	}
+
+	m.progress = &progress{total: total, startedAt: time.Now()}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func (m *Migrator) reportProgress(entry *HistoryEntry) {
+	if m.progressFunc == nil || m.progress == nil {
+		return
+	}
+
+	m.progress.index++
+
+	update := ProgressUpdate{
+		Version:   entry.Version,
+		Direction: entry.Direction,
+		Index:     m.progress.index,
+		Total:     m.progress.total,
+		Elapsed:   time.Since(m.progress.startedAt),
+	}
+	if !entry.Success {
+		update.Err = errors.New(entry.Error)
+	}
+
+	m.progressFunc(update)
+}
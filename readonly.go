@@ -0,0 +1,57 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+var errReadOnly = errors.New("the migrator is read-only")
+
+// readOnlyCommands are the commands, by path below the migrate command, which don't change the
+// database nor the migrations and are allowed with --read-only.
+var readOnlyCommands = map[string]struct{}{
+	"version":         {},
+	"show":            {},
+	"plan":            {},
+	"history export":  {},
+	"conflicts":       {},
+	"manifest verify": {},
+	"lock-status":     {},
+	"doctor":          {},
+	"ping":            {},
+	"diagnose":        {},
+	"shadow-verify":   {},
+	"schemadiff":      {},
+	"verify":          {},
+	"script":          {},
+	"wait":            {},
+	"completion":      {},
+	"help":            {},
+}
+
+// WithReadOnly makes every command changing the database fail with an error.
+func WithReadOnly() Option {
+	return func(m *Migrator) {
+		m.readOnly = true
+	}
+}
+
+// SetReadOnly see WithReadOnly.
+func (m *Migrator) SetReadOnly() {
+	m.readOnly = true
+}
+
+// checkReadOnly fails commands of the cobra command other than the read-only ones and dry runs.
+func (builder *migratorCobraCommandBuilder) checkReadOnly(cmd *cobra.Command) error {
+	command := strings.TrimPrefix(cmd.CommandPath(), builder.migrateCommand.CommandPath()+" ")
+	if _, ok := readOnlyCommands[command]; ok {
+		return nil
+	}
+
+	if dryRun := cmd.Flags().Lookup("dry-run"); dryRun != nil && dryRun.Value.String() == "true" {
+		return nil
+	}
+	return fmt.Errorf("%s isn't allowed with --read-only", command)
+}
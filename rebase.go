@@ -0,0 +1,123 @@
+package migrator
+
+import (
+	"fmt"
+	"github.com/golang-migrate/migrate/v4/source"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type MigrationRename struct {
+	From string
+	To   string
+}
+
+func renumberedName(name string, version uint) string {
+	prefix, rest, _ := strings.Cut(name, "_")
+	return fmt.Sprintf("%0*d_%s", len(prefix), version, rest)
+}
+
+// RebasePlan lists the renames that move the migrations added on the current git branch, and not
+// applied to the database yet, after every migration of base and of the database, keeping their order.
+func (m *Migrator) RebasePlan(base string) ([]*MigrationRename, error) {
+	added, merged, err := m.branchMigrations(base)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, _, err := m.migrate.Version()
+	if err != nil {
+		applied = 0
+	}
+
+	floor := applied
+	for _, migration := range merged {
+		if migration.Version > floor {
+			floor = migration.Version
+		}
+	}
+
+	pending := make(map[uint][]string)
+	for name, migration := range added {
+		if migration.Version <= applied {
+			continue
+		}
+		pending[migration.Version] = append(pending[migration.Version], name)
+	}
+
+	versions := make([]uint, 0, len(pending))
+	needed := false
+	for version := range pending {
+		versions = append(versions, version)
+		if version <= floor {
+			needed = true
+		}
+	}
+
+	renames := make([]*MigrationRename, 0)
+	if !needed {
+		return renames, nil
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i] < versions[j]
+	})
+
+	for i, version := range versions {
+		names := pending[version]
+		sort.Strings(names)
+
+		for _, name := range names {
+			renames = append(renames, &MigrationRename{
+				From: filepath.Join(m.migrationsFilePath, name),
+				To:   filepath.Join(m.migrationsFilePath, renumberedName(name, floor+uint(i)+1)),
+			})
+		}
+	}
+
+	return renames, nil
+}
+
+// Rebase applies the renames of RebasePlan, after checking that none of them overwrites a file.
+func (m *Migrator) Rebase(base string) ([]*MigrationRename, error) {
+	renames, err := m.RebasePlan(base)
+	if err != nil {
+		return nil, err
+	}
+
+	renamed := make(map[string]struct{}, len(renames))
+	for _, rename := range renames {
+		renamed[rename.From] = struct{}{}
+	}
+
+	for _, rename := range renames {
+		if _, ok := renamed[rename.To]; ok {
+			continue
+		}
+
+		if _, err = os.Lstat(rename.To); err == nil {
+			return nil, fmt.Errorf("can't rename %s, %s already exists", rename.From, rename.To)
+		}
+
+		if _, err = source.DefaultParse(filepath.Base(rename.To)); err != nil {
+			return nil, err
+		}
+	}
+
+	// go through temporary names so that renames within the rebased set can't clash
+	for _, rename := range renames {
+		if err = os.Rename(rename.From, rename.From+".rebase"); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, rename := range renames {
+		if err = os.Rename(rename.From+".rebase", rename.To); err != nil {
+			return nil, err
+		}
+	}
+
+	return renames, nil
+}
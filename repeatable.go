@@ -0,0 +1,188 @@
+package migrator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4/source"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	repeatablePrefix = "R__"
+	repeatableTable  = "schema_repeatable_migrations"
+)
+
+var errRepeatableNeedsDB = errors.New("repeatable migrations (R__ files) need a database handle, call SetDB or OpenDB first")
+
+type repeatableMigration struct {
+	name     string
+	path     string
+	body     []byte
+	checksum string
+}
+
+func checksumOf(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+func (m *Migrator) repeatableMigrations() ([]*repeatableMigration, error) {
+	matches, err := filepath.Glob(filepath.Join(m.migrationsFilePath, repeatablePrefix+"*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+
+	migrations := make([]*repeatableMigration, 0, len(matches))
+
+	for _, match := range matches {
+		body, err := os.ReadFile(match)
+		if err != nil {
+			return nil, err
+		}
+
+		name := strings.TrimPrefix(filepath.Base(match), repeatablePrefix)
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+
+		// the checksum is the one of the rendered body, so that a changed include or template value
+		// applies the migration again
+		migration := &source.Migration{Identifier: name, Direction: source.Up, Raw: filepath.Base(match)}
+		if body, err = m.renderMigration(migration, body); err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, &repeatableMigration{
+			name:     name,
+			path:     match,
+			body:     body,
+			checksum: checksumOf(body),
+		})
+	}
+
+	return migrations, nil
+}
+
+func (m *Migrator) ensureRepeatableTable() error {
+	_, err := m.db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	name VARCHAR(255) NOT NULL PRIMARY KEY,
+	checksum VARCHAR(64) NOT NULL,
+	applied_at TIMESTAMP NOT NULL
+)`, repeatableTable))
+	return err
+}
+
+func (m *Migrator) appliedRepeatableChecksums() (map[string]string, error) {
+	rows, err := m.db.Query(fmt.Sprintf("SELECT name, checksum FROM %s", repeatableTable))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checksums := make(map[string]string)
+	for rows.Next() {
+		var name, checksum string
+		if err = rows.Scan(&name, &checksum); err != nil {
+			return nil, err
+		}
+		checksums[name] = checksum
+	}
+
+	return checksums, rows.Err()
+}
+
+func (m *Migrator) recordRepeatable(migration *repeatableMigration) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	d := m.dialect
+	_, err = tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE name = %s", repeatableTable, d.placeholder(1)), migration.name)
+	if err == nil {
+		_, err = tx.Exec(fmt.Sprintf("INSERT INTO %s (name, checksum, applied_at) VALUES (%s, %s, %s)",
+			repeatableTable, d.placeholder(1), d.placeholder(2), d.placeholder(3)),
+			migration.name, migration.checksum, time.Now().UTC())
+	}
+
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkRepeatable fails when there are repeatable migrations to apply without a database handle to
+// record them with, before any migration runs.
+func (m *Migrator) checkRepeatable() error {
+	if m.db != nil {
+		return nil
+	}
+
+	migrations, err := m.repeatableMigrations()
+	if err != nil {
+		return err
+	}
+
+	if len(migrations) > 0 {
+		return errRepeatableNeedsDB
+	}
+	return nil
+}
+
+// ApplyRepeatable re-applies every R__ migration whose rendered body changed since it was last
+// applied, and returns the number of migrations applied.
+func (m *Migrator) ApplyRepeatable() (int, error) {
+	migrations, err := m.repeatableMigrations()
+	if err != nil || len(migrations) == 0 {
+		return 0, err
+	}
+
+	if m.db == nil {
+		return 0, errRepeatableNeedsDB
+	}
+
+	if err = m.ensureRepeatableTable(); err != nil {
+		return 0, err
+	}
+
+	if err = m.driver.Lock(); err != nil {
+		return 0, err
+	}
+	defer func() {
+		if e := m.driver.Unlock(); e != nil {
+			m.logger.Error("can't release lock after applying repeatable migrations", "error", e)
+		}
+	}()
+
+	checksums, err := m.appliedRepeatableChecksums()
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, migration := range migrations {
+		if checksums[migration.name] == migration.checksum {
+			continue
+		}
+
+		m.logger.Info("applying repeatable migration", "name", migration.name)
+
+		if err = m.driver.Run(bytes.NewReader(migration.body)); err != nil {
+			return applied, fmt.Errorf("repeatable migration %s: %w", migration.path, err)
+		}
+
+		if err = m.recordRepeatable(migration); err != nil {
+			return applied, err
+		}
+		applied++
+	}
+
+	return applied, nil
+}
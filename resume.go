@@ -0,0 +1,195 @@
+package migrator
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const statementsTable = "schema_migrations_statements"
+
+var (
+	errNotDirty       = errors.New("database is not dirty, there is nothing to resume")
+	errNoProgress     = errors.New("no statement progress was recorded for the dirty version")
+	errResumeChecksum = errors.New("migration changed since it failed, resuming it could skip other statements")
+)
+
+type StatementProgress struct {
+	Version   uint      `json:"version"`
+	Checksum  string    `json:"checksum"`
+	Committed int       `json:"committed"`
+	Total     int       `json:"total"`
+	Error     string    `json:"error"`
+	FailedAt  time.Time `json:"failed_at"`
+}
+
+// EnableStatementTracking records how many statements of a failed up migration were committed,
+// when it runs with savepoints or split statements, so that Resume can run the rest of it.
+func (m *Migrator) EnableStatementTracking() {
+	m.statementTracking = true
+}
+
+func (m *Migrator) ensureStatementsTable() error {
+	_, err := m.db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	version BIGINT NOT NULL PRIMARY KEY,
+	checksum VARCHAR(64) NOT NULL,
+	committed INTEGER NOT NULL,
+	total INTEGER NOT NULL,
+	error TEXT,
+	failed_at TIMESTAMP NOT NULL
+)`, statementsTable))
+	return err
+}
+
+func (m *Migrator) deleteStatementProgress(version uint) error {
+	_, err := m.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE version = %s", statementsTable, m.dialect.placeholder(1)), int64(version))
+	return err
+}
+
+func (m *Migrator) saveStatementProgress(progress *StatementProgress) error {
+	if err := m.ensureStatementsTable(); err != nil {
+		return err
+	}
+
+	if err := m.deleteStatementProgress(progress.Version); err != nil {
+		return err
+	}
+
+	d := m.dialect
+	_, err := m.db.Exec(fmt.Sprintf("INSERT INTO %s (version, checksum, committed, total, error, failed_at) VALUES (%s, %s, %s, %s, %s, %s)",
+		statementsTable, d.placeholder(1), d.placeholder(2), d.placeholder(3), d.placeholder(4), d.placeholder(5), d.placeholder(6)),
+		int64(progress.Version), progress.Checksum, progress.Committed, progress.Total, progress.Error, progress.FailedAt)
+	return err
+}
+
+// StatementProgress returns the progress recorded for the failed migration version, nil when none was.
+func (m *Migrator) StatementProgress(version uint) (*StatementProgress, error) {
+	if m.db == nil {
+		return nil, errNoDB
+	}
+
+	if err := m.ensureStatementsTable(); err != nil {
+		return nil, err
+	}
+
+	var (
+		progress = &StatementProgress{Version: version}
+		errText  sql.NullString
+	)
+
+	err := m.db.QueryRow(fmt.Sprintf("SELECT checksum, committed, total, error, failed_at FROM %s WHERE version = %s",
+		statementsTable, m.dialect.placeholder(1)), int64(version)).
+		Scan(&progress.Checksum, &progress.Committed, &progress.Total, &errText, &progress.FailedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	progress.Error = errText.String
+	return progress, nil
+}
+
+// recordStatementProgress keeps the progress of an up migration that failed with a StatementError.
+func (d *databaseDriver) recordStatementProgress(body []byte, err error) {
+	m := d.migrator
+	if !m.statementTracking || d.running == nil || d.running.direction != directionUp {
+		return
+	}
+
+	var statementErr *StatementError
+	if !errors.As(err, &statementErr) {
+		return
+	}
+
+	if m.db == nil {
+		m.logger.Error("can't record statement progress", "error", errNoDB)
+		return
+	}
+
+	progress := &StatementProgress{
+		Version:   d.running.version,
+		Checksum:  checksumOf(body),
+		Committed: statementErr.Committed,
+		Total:     statementErr.Total,
+		Error:     statementErr.Err.Error(),
+		FailedAt:  time.Now().UTC(),
+	}
+
+	if saveErr := m.saveStatementProgress(progress); saveErr != nil {
+		m.logger.Error("can't record statement progress", "version", progress.Version, "error", saveErr)
+	}
+}
+
+// Resume runs the statements of the dirty up migration that weren't committed when it failed,
+// once its file is checked to be the one that failed, and marks its version clean, holding the
+// migration lock.
+func (m *Migrator) Resume() error {
+	return m.audited("resume", func() error {
+		if err := m.driver.Lock(); err != nil {
+			return err
+		}
+		defer func() {
+			if e := m.driver.Unlock(); e != nil {
+				m.logger.Error("can't release lock after resuming migration", "error", e)
+			}
+		}()
+
+		version, dirty, err := m.migrate.Version()
+		if err != nil {
+			return err
+		}
+
+		if !dirty {
+			return errNotDirty
+		}
+
+		progress, err := m.StatementProgress(version)
+		if err != nil {
+			return err
+		}
+
+		if progress == nil {
+			return fmt.Errorf("%w: %d", errNoProgress, version)
+		}
+
+		reader, _, err := m.source.ReadUp(version)
+		if err != nil {
+			return err
+		}
+		body, err := io.ReadAll(reader)
+		_ = reader.Close()
+		if err != nil {
+			return err
+		}
+
+		if checksumOf(body) != progress.Checksum {
+			return errResumeChecksum
+		}
+
+		d := m.driver.(*databaseDriver)
+		d.running = &runningMigration{version: version, direction: directionUp, startedAt: time.Now(), body: body}
+		statements := d.statements(body)
+		m.logger.Info("resuming migration", "version", version, "from_statement", progress.Committed+1, "statements", len(statements))
+
+		if m.savepoints {
+			err = d.runSavepoints(statements, progress.Committed)
+		} else {
+			err = d.runStatementsFrom(statements, progress.Committed)
+		}
+
+		if err != nil {
+			d.recordStatementProgress(body, err)
+			d.finish(err)
+			return err
+		}
+
+		if err = d.SetVersion(int(version), false); err != nil {
+			return err
+		}
+		return m.deleteStatementProgress(version)
+	})
+}
@@ -0,0 +1,122 @@
+package migrator
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	RiskAdditive    = "additive"
+	RiskLocking     = "locking"
+	RiskRewriting   = "rewriting"
+	RiskDestructive = "destructive"
+
+	ansiRed     = "\033[31m"
+	ansiYellow  = "\033[33m"
+	ansiMagenta = "\033[35m"
+)
+
+// riskLevels orders the risks from the least to the most dangerous.
+var riskLevels = map[string]int{RiskAdditive: 0, RiskLocking: 1, RiskRewriting: 2, RiskDestructive: 3}
+
+var riskColors = map[string]string{RiskAdditive: ansiString, RiskLocking: ansiMagenta, RiskRewriting: ansiYellow, RiskDestructive: ansiRed}
+
+var (
+	destructiveRegexp = regexp.MustCompile(`(?is)^(?:DROP\s+(?:TABLE|SCHEMA|DATABASE|VIEW|MATERIALIZED\s+VIEW|TYPE|FUNCTION|PROCEDURE|TRIGGER|SEQUENCE)\b|TRUNCATE\b|DELETE\s+FROM\b)`)
+	dropActionRegexp  = regexp.MustCompile(`(?i)\b(?:DROP\s+(?:COLUMN|CONSTRAINT|PRIMARY\s+KEY|FOREIGN\s+KEY|INDEX|KEY)|RENAME)\b`)
+	renameRegexp      = regexp.MustCompile(`(?is)^(?:RENAME\s+TABLE|ALTER\s+(?:TABLE|VIEW|INDEX|SEQUENCE)\s+\S+\s+RENAME)\b`)
+	addColumnRegexp   = regexp.MustCompile(`(?is)^ADD\s+COLUMN\b`)
+	rewritingRegexp   = regexp.MustCompile(`(?is)^(?:UPDATE\b|VACUUM\s+(?:\([^)]*\bFULL\b[^)]*\)|FULL\b)|CLUSTER\b|OPTIMIZE\s+TABLE\b)`)
+)
+
+// StatementRisk is how risky a statement of a migration is: additive statements only add to the
+// schema, locking ones block other sessions while they run, rewriting ones rewrite or scan a whole
+// table, and destructive ones drop or rename objects or delete data.
+type StatementRisk struct {
+	Version   uint   `json:"version"`
+	Direction string `json:"direction"`
+	Statement string `json:"statement"`
+	Risk      string `json:"risk"`
+}
+
+// classifyRisk returns the risk of statement on the dialect d.
+func classifyRisk(d Dialect, statement string) string {
+	statement = strings.TrimSpace(stripComments(statement))
+
+	if destructiveRegexp.MatchString(statement) || renameRegexp.MatchString(statement) {
+		return RiskDestructive
+	}
+
+	if match := alterTableLockRegexp.FindStringSubmatch(statement); match != nil && dropActionRegexp.MatchString(match[2]) {
+		return RiskDestructive
+	}
+
+	if _, rewrites := rewrittenTable(d, statement); rewrites || rewritingRegexp.MatchString(statement) {
+		return RiskRewriting
+	}
+
+	if match := alterTableLockRegexp.FindStringSubmatch(statement); match != nil && addColumnRegexp.MatchString(match[2]) {
+		return RiskAdditive
+	}
+
+	if d == DialectPostgres {
+		if impact := analyzeLock(statement); impact != nil && impact.Lock != LockRowExclusive && impact.Lock != LockShareUpdateExclusive {
+			return RiskLocking
+		}
+	} else if createIndexRegexp.MatchString(statement) || alterTableLockRegexp.MatchString(statement) {
+		return RiskLocking
+	}
+
+	return RiskAdditive
+}
+
+// ClassifyRisks returns the risk of each statement of the SQL migrations of plan, the most
+// dangerous first and in the order they run otherwise, so reviewers look at them first.
+func (m *Migrator) ClassifyRisks(plan []*PlannedMigration) ([]*StatementRisk, error) {
+	risks := make([]*StatementRisk, 0)
+	for _, planned := range plan {
+		statements, err := m.plannedStatements(planned)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, statement := range statements {
+			risks = append(risks, &StatementRisk{Version: planned.Version, Direction: planned.Direction,
+				Statement: strings.TrimSpace(stripComments(statement)), Risk: classifyRisk(m.dialect, statement)})
+		}
+	}
+
+	sort.SliceStable(risks, func(i, j int) bool {
+		return riskLevels[risks[i].Risk] > riskLevels[risks[j].Risk]
+	})
+	return risks, nil
+}
+
+// printRisks prints the statements of plan annotated with their risk, colored on a terminal.
+func (builder *migratorCobraCommandBuilder) printRisks(plan []*PlannedMigration) {
+	risks, err := builder.migrator.ClassifyRisks(plan)
+	if err != nil {
+		builder.migrator.logger.Fatal(err.Error())
+	}
+
+	if len(risks) > 0 {
+		fmt.Println()
+	}
+
+	color := isTerminal(os.Stdout)
+	for _, risk := range risks {
+		statement := strings.Join(strings.Fields(risk.Statement), " ")
+		if len(statement) > 80 {
+			statement = statement[:77] + "..."
+		}
+
+		label := fmt.Sprintf("%-11s", risk.Risk)
+		if color {
+			label = riskColors[risk.Risk] + label + ansiReset
+		}
+		fmt.Printf("%s %s %d: %s\n", label, risk.Direction, risk.Version, statement)
+	}
+}
@@ -0,0 +1,155 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4"
+)
+
+var (
+	errTagAhead     = errors.New("tag is not behind the current version")
+	errVersionAhead = errors.New("version is above the current version")
+	errNoVersion    = errors.New("no migration has this version, revert every migration with down --all instead")
+)
+
+type PlannedMigration struct {
+	Version    uint   `json:"version"`
+	Identifier string `json:"identifier"`
+	Direction  string `json:"direction"`
+	Missing    bool   `json:"missing,omitempty"`
+	Checksum   string `json:"checksum,omitempty"`
+}
+
+// planDown lists the down migrations that take the database from its current version to target.
+func (m *Migrator) planDown(target uint) ([]*PlannedMigration, error) {
+	current, dirty, err := m.migrate.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	if dirty {
+		return nil, migrate.ErrDirty{Version: int(current)}
+	}
+
+	plan := make([]*PlannedMigration, 0)
+	for version := current; version > target; {
+		planned := &PlannedMigration{Version: version, Direction: directionDown}
+		if migration, ok := m.source.migrations.Down(version); ok {
+			planned.Identifier = migration.Identifier
+		} else if migration, ok = m.source.migrations.Up(version); ok {
+			planned.Identifier = migration.Identifier
+			planned.Missing = true
+		} else {
+			return nil, fmt.Errorf("no migration found for applied version %d", version)
+		}
+		plan = append(plan, planned)
+
+		prev, ok := m.source.migrations.Prev(version)
+		if !ok {
+			break
+		}
+		version = prev
+	}
+
+	return plan, nil
+}
+
+func (m *Migrator) rollbackTarget(name string) (uint, error) {
+	if m.db == nil {
+		return 0, errNoDB
+	}
+
+	tag, err := m.lookupTag(name)
+	if err != nil {
+		return 0, err
+	}
+
+	if tag == nil {
+		return 0, fmt.Errorf("tag %s doesn't exist", name)
+	}
+
+	current, _, err := m.migrate.Version()
+	if err != nil {
+		return 0, err
+	}
+
+	if tag.Version > current {
+		return 0, errTagAhead
+	}
+	return tag.Version, nil
+}
+
+// RollbackToTagPlan lists the migrations RollbackToTag would revert, without running them.
+func (m *Migrator) RollbackToTagPlan(name string) ([]*PlannedMigration, error) {
+	target, err := m.rollbackTarget(name)
+	if err != nil {
+		return nil, err
+	}
+	return m.planDown(target)
+}
+
+// RollbackToTag applies the down migrations back to the version tagged name.
+func (m *Migrator) RollbackToTag(name string) ([]*PlannedMigration, error) {
+	target, err := m.rollbackTarget(name)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := m.planDown(target)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(plan) == 0 {
+		return plan, migrate.ErrNoChange
+	}
+
+	return plan, m.audited("rollback", func() error {
+		if err := m.checkPrivileges("down"); err != nil {
+			return err
+		}
+
+		m.startProgress(0, int(target))
+		return m.migrate.Migrate(target)
+	})
+}
+
+// DownToPlan lists the migrations DownTo would revert, without running them.
+func (m *Migrator) DownToPlan(version uint) ([]*PlannedMigration, error) {
+	if _, ok := m.source.migrations.Up(version); !ok {
+		if _, ok = m.source.migrations.Down(version); !ok {
+			return nil, fmt.Errorf("%w: %d", errNoVersion, version)
+		}
+	}
+
+	current, _, err := m.migrate.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	if version > current {
+		return nil, fmt.Errorf("%w: %d > %d", errVersionAhead, version, current)
+	}
+	return m.planDown(version)
+}
+
+// DownTo applies the down migrations back to version, refusing a version above the current one.
+func (m *Migrator) DownTo(version uint) ([]*PlannedMigration, error) {
+	plan, err := m.DownToPlan(version)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(plan) == 0 {
+		return plan, migrate.ErrNoChange
+	}
+
+	return plan, m.audited("down", func() error {
+		if err := m.checkPrivileges("down"); err != nil {
+			return err
+		}
+
+		m.startProgress(0, int(version))
+		return m.migrate.Migrate(version)
+	})
+}
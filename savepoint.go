@@ -0,0 +1,86 @@
+package migrator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StatementError tells which statement of a migration failed, Committed being the number of its
+// statements that are applied.
+type StatementError struct {
+	Version   uint
+	Index     int
+	Total     int
+	Committed int
+	Statement string
+	Err       error
+}
+
+func (e *StatementError) Error() string {
+	return fmt.Sprintf("statement %d of %d of version %d failed, %d committed: %v\n%s",
+		e.Index+1, e.Total, e.Version, e.Committed, e.Err, e.Statement)
+}
+
+func (e *StatementError) Unwrap() error {
+	return e.Err
+}
+
+// EnableSavepoints runs the statements of SQL migrations one by one in a transaction, each within
+// a savepoint. When one fails, the statements before it are committed and a StatementError tells
+// which one failed, instead of a bare dirty version.
+func (m *Migrator) EnableSavepoints() {
+	m.savepoints = true
+}
+
+func WithSavepoints() Option {
+	return func(m *Migrator) {
+		m.savepoints = true
+	}
+}
+
+func (d *databaseDriver) exec(query string) error {
+	return d.Driver.Run(strings.NewReader(query))
+}
+
+// runSavepoints runs statements from the start-th one within savepoints of a single transaction.
+func (d *databaseDriver) runSavepoints(statements []string, start int) error {
+	var version uint
+	if d.running != nil {
+		version = d.running.version
+	}
+
+	if err := d.exec("BEGIN"); err != nil {
+		return err
+	}
+
+	for i := start; i < len(statements); i++ {
+		savepoint := fmt.Sprintf("migrator_statement_%d", i)
+		if err := d.exec("SAVEPOINT " + savepoint); err != nil {
+			_ = d.exec("ROLLBACK")
+			return err
+		}
+
+		if err := d.exec(statements[i]); err != nil {
+			statementErr := &StatementError{
+				Version: version, Index: i, Total: len(statements), Committed: start, Statement: statements[i], Err: err,
+			}
+
+			if rollbackErr := d.exec("ROLLBACK TO SAVEPOINT " + savepoint); rollbackErr != nil {
+				_ = d.exec("ROLLBACK")
+				return statementErr
+			}
+
+			if commitErr := d.exec("COMMIT"); commitErr == nil {
+				statementErr.Committed = i
+			}
+			return statementErr
+		}
+
+		if err := d.exec("RELEASE SAVEPOINT " + savepoint); err != nil {
+			_ = d.exec("ROLLBACK")
+			return err
+		}
+	}
+
+	return d.exec("COMMIT")
+}
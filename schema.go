@@ -0,0 +1,441 @@
+package migrator
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/anyufly/migrate-sql-result"
+	"sort"
+	"strings"
+)
+
+var (
+	errNoDB               = errors.New("no database handle, call SetDB first")
+	errNoSchemaFunc       = errors.New("no desired schema, call SetSchemaFunc first")
+	errUnsupportedDialect = errors.New("unsupported database dialect")
+)
+
+type Column struct {
+	Name       string
+	Type       string
+	Nullable   bool
+	PrimaryKey bool
+}
+
+type Table struct {
+	Name    string
+	Columns []*Column
+}
+
+type Schema struct {
+	Tables []*Table
+}
+
+type schemaFunc func() (*Schema, error)
+
+func (t *Table) Column(name string) *Column {
+	for _, column := range t.Columns {
+		if column.Name == name {
+			return column
+		}
+	}
+	return nil
+}
+
+func (s *Schema) Table(name string) *Table {
+	for _, table := range s.Tables {
+		if table.Name == name {
+			return table
+		}
+	}
+	return nil
+}
+
+func (s *Schema) sortTables() {
+	sort.Slice(s.Tables, func(i, j int) bool {
+		return s.Tables[i].Name < s.Tables[j].Name
+	})
+}
+
+var typeAliases = map[string]string{
+	"character varying":           "varchar",
+	"character":                   "char",
+	"timestamp with time zone":    "timestamptz",
+	"timestamp without time zone": "timestamp",
+	"time with time zone":         "timetz",
+	"time without time zone":      "time",
+	"double precision":            "float8",
+	"bigserial":                   "bigint",
+	"serial":                      "integer",
+	"smallserial":                 "smallint",
+	"int":                         "integer",
+	"int4":                        "integer",
+	"int8":                        "bigint",
+	"int2":                        "smallint",
+	"bool":                        "boolean",
+}
+
+func normalizeType(columnType string) string {
+	t := strings.ToLower(strings.TrimSpace(columnType))
+	t = strings.TrimSpace(strings.ReplaceAll(t, "auto_increment", ""))
+	t = strings.TrimSpace(strings.ReplaceAll(t, "autoincrement", ""))
+
+	name, args := t, ""
+	if idx := strings.Index(t, "("); idx >= 0 {
+		name, args = strings.TrimSpace(t[:idx]), t[idx:]
+	}
+
+	if alias, ok := typeAliases[name]; ok {
+		name = alias
+	}
+
+	return name + args
+}
+
+func columnDefinition(d Dialect, column *Column) string {
+	definition := fmt.Sprintf("%s %s", d.quote(column.Name), column.Type)
+	if !column.Nullable {
+		definition += " NOT NULL"
+	}
+	return definition
+}
+
+func createTableSQL(d Dialect, table *Table) string {
+	definitions := make([]string, 0, len(table.Columns)+1)
+	primaryKeys := make([]string, 0, 1)
+
+	for _, column := range table.Columns {
+		definitions = append(definitions, columnDefinition(d, column))
+		if column.PrimaryKey {
+			primaryKeys = append(primaryKeys, d.quote(column.Name))
+		}
+	}
+
+	if len(primaryKeys) > 0 {
+		definitions = append(definitions, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(primaryKeys, ", ")))
+	}
+
+	return fmt.Sprintf("CREATE TABLE %s (\n\t%s\n)", d.quote(table.Name), strings.Join(definitions, ",\n\t"))
+}
+
+func alterColumnSQL(d Dialect, tableName string, from, to *Column) ([]string, error) {
+	table, column := d.quote(tableName), d.quote(to.Name)
+
+	switch d {
+	case DialectPostgres:
+		sqlList := make([]string, 0, 2)
+		if normalizeType(from.Type) != normalizeType(to.Type) {
+			sqlList = append(sqlList, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s", table, column, to.Type))
+		}
+		if from.Nullable != to.Nullable {
+			if to.Nullable {
+				sqlList = append(sqlList, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL", table, column))
+			} else {
+				sqlList = append(sqlList, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL", table, column))
+			}
+		}
+		return sqlList, nil
+	case DialectMySQL:
+		return []string{fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s", table, columnDefinition(d, to))}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s can't alter column %s.%s", errUnsupportedDialect, d, tableName, to.Name)
+	}
+}
+
+func columnChanged(from, to *Column) bool {
+	return normalizeType(from.Type) != normalizeType(to.Type) || from.Nullable != to.Nullable
+}
+
+// extraTables returns the tables of live missing from desired.
+func extraTables(live, desired *Schema) []*Table {
+	tables := make([]*Table, 0)
+	for _, table := range live.Tables {
+		if desired.Table(table.Name) == nil {
+			tables = append(tables, table)
+		}
+	}
+	return tables
+}
+
+// diffSchema returns the statements bringing live to desired, dropping the tables of live missing
+// from desired only when dropTables is set.
+func diffSchema(d Dialect, live, desired *Schema, dropTables bool) (*result.MigrateSQLResult, error) {
+	migrateResult := result.NewMigrateSQLResult()
+
+	for _, desiredTable := range desired.Tables {
+		tableName := desiredTable.Name
+		liveTable := live.Table(tableName)
+
+		if liveTable == nil {
+			migrateResult.AppendUp(result.NewSQLForTable(tableName, createTableSQL(d, desiredTable)))
+			migrateResult.AppendDown(result.NewSQLForTable(tableName, fmt.Sprintf("DROP TABLE %s", d.quote(tableName))))
+			continue
+		}
+
+		for _, desiredColumn := range desiredTable.Columns {
+			liveColumn := liveTable.Column(desiredColumn.Name)
+
+			if liveColumn == nil {
+				migrateResult.AppendUp(result.NewSQLForTable(tableName,
+					fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", d.quote(tableName), columnDefinition(d, desiredColumn))))
+				migrateResult.AppendDown(result.NewSQLForTable(tableName,
+					fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", d.quote(tableName), d.quote(desiredColumn.Name))))
+				continue
+			}
+
+			if !columnChanged(liveColumn, desiredColumn) {
+				continue
+			}
+
+			up, err := alterColumnSQL(d, tableName, liveColumn, desiredColumn)
+			if err != nil {
+				return nil, err
+			}
+			down, err := alterColumnSQL(d, tableName, desiredColumn, liveColumn)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, sql := range up {
+				migrateResult.AppendUp(result.NewSQLForTable(tableName, sql))
+			}
+			for _, sql := range down {
+				migrateResult.AppendDown(result.NewSQLForTable(tableName, sql))
+			}
+		}
+
+		for _, liveColumn := range liveTable.Columns {
+			if desiredTable.Column(liveColumn.Name) != nil {
+				continue
+			}
+
+			migrateResult.AppendUp(result.NewSQLForTable(tableName,
+				fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", d.quote(tableName), d.quote(liveColumn.Name))))
+			migrateResult.AppendDown(result.NewSQLForTable(tableName,
+				fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", d.quote(tableName), columnDefinition(d, liveColumn))))
+		}
+	}
+
+	if !dropTables {
+		return migrateResult, nil
+	}
+
+	for _, liveTable := range extraTables(live, desired) {
+		migrateResult.AppendUp(result.NewSQLForTable(liveTable.Name, fmt.Sprintf("DROP TABLE %s", d.quote(liveTable.Name))))
+		migrateResult.AppendDown(result.NewSQLForTable(liveTable.Name, createTableSQL(d, liveTable)))
+	}
+
+	return migrateResult, nil
+}
+
+// upStatements returns the up statements of migrateResult table by table, in the order of
+// resultTables.
+func upStatements(migrateResult *result.MigrateSQLResult) []string {
+	up := migrateResult.Up()
+
+	statements := make([]string, 0)
+	for _, table := range resultTables(migrateResult, directionUp) {
+		statements = append(statements, up[table]...)
+	}
+	return statements
+}
+
+const (
+	postgresColumnsQuery = `SELECT c.relname, a.attname, format_type(a.atttypid, a.atttypmod), NOT a.attnotnull,
+	EXISTS (SELECT 1 FROM pg_catalog.pg_index i WHERE i.indrelid = c.oid AND i.indisprimary AND a.attnum = ANY(i.indkey))
+FROM pg_catalog.pg_attribute a
+	JOIN pg_catalog.pg_class c ON a.attrelid = c.oid
+	JOIN pg_catalog.pg_namespace n ON c.relnamespace = n.oid
+WHERE c.relkind = 'r' AND n.nspname = current_schema() AND a.attnum > 0 AND NOT a.attisdropped
+ORDER BY c.relname, a.attnum`
+
+	mysqlColumnsQuery = `SELECT c.TABLE_NAME, c.COLUMN_NAME, c.COLUMN_TYPE, c.IS_NULLABLE = 'YES', c.COLUMN_KEY = 'PRI'
+FROM information_schema.COLUMNS c
+	JOIN information_schema.TABLES t ON t.TABLE_SCHEMA = c.TABLE_SCHEMA AND t.TABLE_NAME = c.TABLE_NAME
+WHERE c.TABLE_SCHEMA = DATABASE() AND t.TABLE_TYPE = 'BASE TABLE'
+ORDER BY c.TABLE_NAME, c.ORDINAL_POSITION`
+
+	sqliteTablesQuery = `SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name`
+)
+
+func introspectColumns(db *sql.DB, query string) (*Schema, error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	s := &Schema{}
+	var current *Table
+
+	for rows.Next() {
+		var tableName string
+		column := &Column{}
+
+		if err = rows.Scan(&tableName, &column.Name, &column.Type, &column.Nullable, &column.PrimaryKey); err != nil {
+			return nil, err
+		}
+
+		if current == nil || current.Name != tableName {
+			current = &Table{Name: tableName}
+			s.Tables = append(s.Tables, current)
+		}
+		current.Columns = append(current.Columns, column)
+	}
+
+	return s, rows.Err()
+}
+
+func introspectSQLite(db *sql.DB) (*Schema, error) {
+	rows, err := db.Query(sqliteTablesQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	var tableNames []string
+	for rows.Next() {
+		var tableName string
+		if err = rows.Scan(&tableName); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		tableNames = append(tableNames, tableName)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	s := &Schema{}
+
+	for _, tableName := range tableNames {
+		table := &Table{Name: tableName}
+
+		columnRows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", DialectSQLite.quote(tableName)))
+		if err != nil {
+			return nil, err
+		}
+
+		for columnRows.Next() {
+			var (
+				cid          int
+				column       = &Column{}
+				notNull      bool
+				defaultValue sql.NullString
+				pk           int
+			)
+
+			if err = columnRows.Scan(&cid, &column.Name, &column.Type, &notNull, &defaultValue, &pk); err != nil {
+				columnRows.Close()
+				return nil, err
+			}
+			column.Nullable = !notNull
+			column.PrimaryKey = pk > 0
+			table.Columns = append(table.Columns, column)
+		}
+		columnRows.Close()
+
+		if err = columnRows.Err(); err != nil {
+			return nil, err
+		}
+
+		s.Tables = append(s.Tables, table)
+	}
+
+	return s, nil
+}
+
+func introspectSchema(db *sql.DB, d Dialect) (*Schema, error) {
+	switch d {
+	case DialectPostgres:
+		return introspectColumns(db, postgresColumnsQuery)
+	case DialectMySQL:
+		return introspectColumns(db, mysqlColumnsQuery)
+	case DialectSQLite:
+		return introspectSQLite(db)
+	default:
+		return nil, errUnsupportedDialect
+	}
+}
+
+func (m *Migrator) SetDB(db *sql.DB) {
+	m.db = db
+}
+
+func (m *Migrator) SetSchemaFunc(fn schemaFunc) {
+	m.schemaFunc = fn
+}
+
+func (m *Migrator) isIgnoredTable(tableName string) bool {
+	_, ok := m.ignoredTables[tableName]
+	return ok
+}
+
+func (m *Migrator) LiveSchema() (*Schema, error) {
+	if m.db == nil {
+		return nil, errNoDB
+	}
+
+	return m.introspect(m.db, m.dialect)
+}
+
+// introspect returns the schema of db without the tables of the migrator.
+func (m *Migrator) introspect(db *sql.DB, d Dialect) (*Schema, error) {
+	s, err := introspectSchema(db, d)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := s.Tables[:0]
+	for _, table := range s.Tables {
+		if !m.isIgnoredTable(table.Name) {
+			tables = append(tables, table)
+		}
+	}
+	s.Tables = tables
+
+	return s, nil
+}
+
+// SetDropExtraTables makes DiffDB drop the live tables missing from the desired schema, which it
+// otherwise only logs, since they may hold data the models don't know about.
+func (m *Migrator) SetDropExtraTables(drop bool) {
+	m.dropExtraTables = drop
+}
+
+func WithDropExtraTables() Option {
+	return func(m *Migrator) {
+		m.dropExtraTables = true
+	}
+}
+
+// DiffDB returns the statements bringing the live schema to the desired schema of SetSchemaFunc.
+func (m *Migrator) DiffDB() (*result.MigrateSQLResult, error) {
+	return m.diffDB(m.dropExtraTables)
+}
+
+func (m *Migrator) diffDB(dropTables bool) (*result.MigrateSQLResult, error) {
+	if m.schemaFunc == nil {
+		return nil, errNoSchemaFunc
+	}
+
+	desired, err := m.schemaFunc()
+	if err != nil {
+		return nil, err
+	}
+	desired.sortTables()
+
+	live, err := m.LiveSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	if !dropTables {
+		for _, table := range extraTables(live, desired) {
+			m.logger.Error("live table isn't in the desired schema, drop it with SetDropExtraTables or --drop-tables", "table", table.Name)
+		}
+	}
+
+	return diffSchema(m.dialect, live, desired, dropTables)
+}
@@ -0,0 +1,55 @@
+package migrator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffSchemaExtraTables(t *testing.T) {
+	live := &Schema{Tables: []*Table{
+		{Name: "legacy", Columns: []*Column{{Name: "id", Type: "INTEGER", PrimaryKey: true}}},
+		{Name: "users", Columns: []*Column{{Name: "id", Type: "INTEGER", PrimaryKey: true}}},
+	}}
+	desired := &Schema{Tables: []*Table{
+		{Name: "users", Columns: []*Column{{Name: "id", Type: "INTEGER", PrimaryKey: true}}},
+	}}
+
+	tests := []struct {
+		name       string
+		dropTables bool
+		up         map[string][]string
+		down       map[string][]string
+	}{
+		{
+			name: "kept by default",
+			up:   map[string][]string{},
+			down: map[string][]string{},
+		},
+		{
+			name:       "dropped on request",
+			dropTables: true,
+			up:         map[string][]string{"legacy": {`DROP TABLE "legacy"`}},
+			down:       map[string][]string{"legacy": {"CREATE TABLE \"legacy\" (\n\t\"id\" INTEGER NOT NULL,\n\tPRIMARY KEY (\"id\")\n)"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			migrateResult, err := diffSchema(DialectSQLite, live, desired, test.dropTables)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if up := migrateResult.Up(); !reflect.DeepEqual(up, test.up) {
+				t.Errorf("up = %q, want %q", up, test.up)
+			}
+			if down := migrateResult.Down(); !reflect.DeepEqual(down, test.down) {
+				t.Errorf("down = %q, want %q", down, test.down)
+			}
+		})
+	}
+
+	if tables := extraTables(live, desired); len(tables) != 1 || tables[0].Name != "legacy" {
+		t.Errorf("extra tables = %v, want legacy", tables)
+	}
+}
@@ -0,0 +1,70 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	errSchemaChanged    = errors.New("the schema changed since the last migration")
+	errNoSchemaChecksum = errors.New("no schema checksum recorded in the history yet")
+)
+
+// SchemaChecksum returns the checksum of the CREATE TABLE statements of the live schema, sorted by
+// table, without the tables of the migrator.
+func (m *Migrator) SchemaChecksum() (string, error) {
+	live, err := m.LiveSchema()
+	if err != nil {
+		return "", err
+	}
+	live.sortTables()
+
+	return checksumOf(renderSchema(m.dialect, live)), nil
+}
+
+// attachSchemaChecksum records the checksum of the schema a successful migration left in its
+// history entry.
+func (m *Migrator) attachSchemaChecksum(entry *HistoryEntry) {
+	if !entry.Success || !m.historyEnabled || m.db == nil || m.dialect == DialectUnknown {
+		return
+	}
+
+	checksum, err := m.SchemaChecksum()
+	if err != nil {
+		m.logger.Error("can't compute the schema checksum", "version", entry.Version, "error", err)
+		return
+	}
+	entry.SchemaChecksum = checksum
+}
+
+// VerifySchema compares the checksum of the live schema with the one recorded in the history
+// after the last successful migration, failing with errSchemaChanged when the schema was changed
+// out of band since. It returns that history entry.
+func (m *Migrator) VerifySchema() (*HistoryEntry, error) {
+	entries, err := m.History()
+	if err != nil {
+		return nil, err
+	}
+
+	var last *HistoryEntry
+	for _, entry := range entries {
+		if entry.Success {
+			last = entry
+		}
+	}
+
+	if last == nil || last.SchemaChecksum == "" {
+		return last, errNoSchemaChecksum
+	}
+
+	checksum, err := m.SchemaChecksum()
+	if err != nil {
+		return last, err
+	}
+
+	if checksum != last.SchemaChecksum {
+		return last, fmt.Errorf("%w: %s %d left checksum %s, the schema now has %s",
+			errSchemaChanged, last.Direction, last.Version, last.SchemaChecksum, checksum)
+	}
+	return last, nil
+}
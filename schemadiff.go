@@ -0,0 +1,137 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	DifferenceOnlyInFirst  = "only in first"
+	DifferenceOnlyInSecond = "only in second"
+	DifferenceChanged      = "changed"
+)
+
+var errDialectMismatch = errors.New("both databases must be of the same dialect")
+
+// SchemaDifference is a table, or a column when Column is set, which is only in one of the
+// databases or differs between them. First and Second are the column definitions.
+type SchemaDifference struct {
+	Table  string `json:"table"`
+	Column string `json:"column,omitempty"`
+	Kind   string `json:"kind"`
+	First  string `json:"first,omitempty"`
+	Second string `json:"second,omitempty"`
+}
+
+// SchemaDiff is the outcome of DiffDatabases, SQL being the statements bringing the first
+// database to the schema of the second.
+type SchemaDiff struct {
+	Differences []*SchemaDifference `json:"differences"`
+	SQL         []string            `json:"sql"`
+}
+
+func (d *SchemaDiff) Empty() bool {
+	return len(d.Differences) == 0
+}
+
+func columnSummary(column *Column) string {
+	summary := normalizeType(column.Type)
+	if !column.Nullable {
+		summary += " not null"
+	}
+	if column.PrimaryKey {
+		summary += " primary key"
+	}
+	return summary
+}
+
+func compareSchemas(first, second *Schema) []*SchemaDifference {
+	differences := make([]*SchemaDifference, 0)
+
+	for _, table := range first.Tables {
+		if second.Table(table.Name) == nil {
+			differences = append(differences, &SchemaDifference{Table: table.Name, Kind: DifferenceOnlyInFirst})
+		}
+	}
+
+	for _, table := range second.Tables {
+		firstTable := first.Table(table.Name)
+		if firstTable == nil {
+			differences = append(differences, &SchemaDifference{Table: table.Name, Kind: DifferenceOnlyInSecond})
+			continue
+		}
+
+		for _, column := range firstTable.Columns {
+			if table.Column(column.Name) == nil {
+				differences = append(differences, &SchemaDifference{Table: table.Name, Column: column.Name,
+					Kind: DifferenceOnlyInFirst, First: columnSummary(column)})
+			}
+		}
+
+		for _, column := range table.Columns {
+			firstColumn := firstTable.Column(column.Name)
+			switch {
+			case firstColumn == nil:
+				differences = append(differences, &SchemaDifference{Table: table.Name, Column: column.Name,
+					Kind: DifferenceOnlyInSecond, Second: columnSummary(column)})
+			case columnChanged(firstColumn, column) || firstColumn.PrimaryKey != column.PrimaryKey:
+				differences = append(differences, &SchemaDifference{Table: table.Name, Column: column.Name,
+					Kind: DifferenceChanged, First: columnSummary(firstColumn), Second: columnSummary(column)})
+			}
+		}
+	}
+
+	sort.SliceStable(differences, func(i, j int) bool {
+		return differences[i].Table < differences[j].Table
+	})
+	return differences
+}
+
+func (m *Migrator) introspectURL(databaseURL string) (*Schema, Dialect, error) {
+	db, err := m.sqlOpen(databaseURL)
+	if err != nil {
+		return nil, DialectUnknown, err
+	}
+	defer db.Close()
+
+	scheme, _, _ := strings.Cut(databaseURL, "://")
+	d := dialectOf(scheme)
+
+	s, err := m.introspect(db, d)
+	if err != nil {
+		return nil, d, err
+	}
+	s.sortTables()
+
+	return s, d, nil
+}
+
+// DiffDatabases compares the schemas of the live databases at firstURL and secondURL, without
+// the tables of the migrator. The database/sql drivers of both must be registered.
+func (m *Migrator) DiffDatabases(firstURL, secondURL string) (*SchemaDiff, error) {
+	first, firstDialect, err := m.introspectURL(firstURL)
+	if err != nil {
+		return nil, fmt.Errorf("first database: %w", err)
+	}
+
+	second, secondDialect, err := m.introspectURL(secondURL)
+	if err != nil {
+		return nil, fmt.Errorf("second database: %w", err)
+	}
+
+	if firstDialect != secondDialect {
+		return nil, fmt.Errorf("%w: %s and %s", errDialectMismatch, firstDialect, secondDialect)
+	}
+
+	diff := &SchemaDiff{Differences: compareSchemas(first, second)}
+
+	migrateResult, err := diffSchema(firstDialect, first, second, true)
+	if err != nil {
+		return nil, err
+	}
+
+	diff.SQL = upStatements(migrateResult)
+	return diff, nil
+}
@@ -0,0 +1,100 @@
+package migrator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var (
+	errScriptGoMigration = errors.New("go migrations can't be written to a script")
+	errNothingToRevert   = errors.New("no migration to revert")
+)
+
+// DownPlan lists the next n down migrations from the current version, all of them when n is 0 or less.
+func (m *Migrator) DownPlan(n int) ([]*PlannedMigration, error) {
+	plan, err := m.planDown(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if n > 0 && n < len(plan) {
+		plan = plan[:n]
+	}
+	return plan, nil
+}
+
+// setVersionSQL returns the statements leaving version in the migrations table, as the driver
+// does, emptying it when version is nil.
+func (m *Migrator) setVersionSQL(version *uint) string {
+	table := m.dialect.quote(m.migrationsTable)
+	statements := fmt.Sprintf("DELETE FROM %s;\n", table)
+	if version != nil {
+		statements += fmt.Sprintf("INSERT INTO %s (version, dirty) VALUES (%d, false);\n", table, *version)
+	}
+	return statements
+}
+
+// WriteRollbackScript writes the next n down migrations, all of them when n is 0 or less, as one
+// SQL script to w, in the order they are reverted. Each migration runs in its own transaction,
+// unless it's marked -- migrator:no-transaction or on mysql, and updates the migrations table.
+func (m *Migrator) WriteRollbackScript(w io.Writer, n int) error {
+	plan, err := m.DownPlan(n)
+	if err != nil {
+		return err
+	}
+
+	if len(plan) == 0 {
+		return errNothingToRevert
+	}
+
+	toolVersion, _ := buildInfo()
+	var buffer bytes.Buffer
+	buffer.WriteString(fmt.Sprintf("-- rollback script of %d migrations of %s, written by file-migrator %s at %s\n",
+		len(plan), m.databaseName, toolVersion, time.Now().UTC().Format(time.RFC3339)))
+
+	for i, planned := range plan {
+		var target *uint
+		if i+1 < len(plan) {
+			target = &plan[i+1].Version
+		} else if prev, ok := m.source.migrations.Prev(planned.Version); ok {
+			target = &prev
+		}
+
+		buffer.WriteString(fmt.Sprintf("\n-- down %d %s\n", planned.Version, planned.Identifier))
+
+		var body []byte
+		if planned.Missing {
+			buffer.WriteString("-- no down migration, only the version is reverted\n")
+		} else {
+			migration, _ := m.source.migrations.Down(planned.Version)
+			if migration.Raw == "" {
+				return fmt.Errorf("%w: %d", errScriptGoMigration, planned.Version)
+			}
+
+			if body, err = m.source.readFile(migration); err != nil {
+				return err
+			}
+		}
+
+		_, noTransaction := directive(body, "no-transaction")
+		transaction := !noTransaction && m.dialect != DialectMySQL
+
+		if transaction {
+			buffer.WriteString("BEGIN;\n")
+		}
+		if len(body) > 0 {
+			buffer.Write(bytes.TrimRight(body, "\n"))
+			buffer.WriteString("\n")
+		}
+		buffer.WriteString(m.setVersionSQL(target))
+		if transaction {
+			buffer.WriteString("COMMIT;\n")
+		}
+	}
+
+	_, err = w.Write(buffer.Bytes())
+	return err
+}
@@ -0,0 +1,203 @@
+package migrator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	seedTable = "schema_seeds"
+	seedsDir  = "seeds"
+)
+
+var errNoSeedEnv = errors.New("seed environment must not be empty")
+
+type seedFile struct {
+	name     string
+	path     string
+	body     []byte
+	checksum string
+}
+
+func (m *Migrator) SetSeedsPath(seedsPath string) {
+	m.seedsPath = seedsPath
+}
+
+func (m *Migrator) seedsDirPath() string {
+	if m.seedsPath != "" {
+		return m.seedsPath
+	}
+	return filepath.Join(filepath.Dir(filepath.Clean(m.migrationsFilePath)), seedsDir)
+}
+
+func (m *Migrator) seedFiles(env string) ([]*seedFile, error) {
+	matches, err := filepath.Glob(filepath.Join(m.seedsDirPath(), env, "*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+
+	seeds := matches[:0]
+	for _, match := range matches {
+		if strings.EqualFold(filepath.Ext(match), ".sql") || isFixtureFile(match) {
+			seeds = append(seeds, match)
+		}
+	}
+	matches = seeds
+
+	files := make([]*seedFile, 0, len(matches))
+
+	for _, match := range matches {
+		body, err := os.ReadFile(match)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, &seedFile{
+			name:     filepath.Base(match),
+			path:     match,
+			body:     body,
+			checksum: checksumOf(body),
+		})
+	}
+
+	return files, nil
+}
+
+func (m *Migrator) ensureSeedTable() error {
+	_, err := m.db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	env VARCHAR(64) NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	checksum VARCHAR(64) NOT NULL,
+	applied_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (env, name)
+)`, seedTable))
+	return err
+}
+
+func (m *Migrator) appliedSeedChecksums(env string) (map[string]string, error) {
+	rows, err := m.db.Query(fmt.Sprintf("SELECT name, checksum FROM %s WHERE env = %s", seedTable, m.dialect.placeholder(1)), env)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checksums := make(map[string]string)
+	for rows.Next() {
+		var name, checksum string
+		if err = rows.Scan(&name, &checksum); err != nil {
+			return nil, err
+		}
+		checksums[name] = checksum
+	}
+
+	return checksums, rows.Err()
+}
+
+func (m *Migrator) recordSeed(env, name, checksum string) error {
+	d := m.dialect
+	_, err := m.db.Exec(fmt.Sprintf("INSERT INTO %s (env, name, checksum, applied_at) VALUES (%s, %s, %s, %s)",
+		seedTable, d.placeholder(1), d.placeholder(2), d.placeholder(3), d.placeholder(4)),
+		env, name, checksum, time.Now().UTC())
+	return err
+}
+
+// Seed applies the SQL and fixture files of seeds/<env>/ that haven't been applied to env yet,
+// and returns the number of seeds applied. The fixture files are loaded together, where the first
+// of them sorts, so that their tables are inserted in the order of their foreign keys.
+func (m *Migrator) Seed(env string) (int, error) {
+	if env == "" {
+		return 0, errNoSeedEnv
+	}
+
+	if m.db == nil {
+		return 0, errNoDB
+	}
+
+	files, err := m.seedFiles(env)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = m.ensureSeedTable(); err != nil {
+		return 0, err
+	}
+
+	if err = m.driver.Lock(); err != nil {
+		return 0, err
+	}
+	defer func() {
+		if e := m.driver.Unlock(); e != nil {
+			m.logger.Error("can't release lock after seeding", "error", e)
+		}
+	}()
+
+	checksums, err := m.appliedSeedChecksums(env)
+	if err != nil {
+		return 0, err
+	}
+
+	pending := make([]*seedFile, 0, len(files))
+	fixturePaths := make([]string, 0)
+	for _, file := range files {
+		if checksum, ok := checksums[file.name]; ok {
+			if checksum != file.checksum {
+				m.logger.Info("seed changed since it was applied, skipping", "env", env, "name", file.name)
+			}
+			continue
+		}
+
+		pending = append(pending, file)
+		if isFixtureFile(file.path) {
+			fixturePaths = append(fixturePaths, file.path)
+		}
+	}
+
+	applied := 0
+	fixturesLoaded := false
+	for _, file := range pending {
+		if isFixtureFile(file.path) {
+			if fixturesLoaded {
+				continue
+			}
+
+			// the fixtures are inserted together, their tables ordered by their foreign keys
+			m.logger.Info("applying fixture seeds", "env", env, "files", len(fixturePaths))
+			if err = m.LoadFixtures(context.Background(), fixturePaths, FixtureInsert); err != nil {
+				return applied, fmt.Errorf("seed fixtures: %w", err)
+			}
+			fixturesLoaded = true
+
+			for _, fixture := range pending {
+				if !isFixtureFile(fixture.path) {
+					continue
+				}
+				if err = m.recordSeed(env, fixture.name, fixture.checksum); err != nil {
+					return applied, err
+				}
+				applied++
+			}
+			continue
+		}
+
+		m.logger.Info("applying seed", "env", env, "name", file.name)
+
+		if err = m.driver.Run(bytes.NewReader(file.body)); err != nil {
+			return applied, fmt.Errorf("seed %s: %w", file.path, err)
+		}
+
+		if err = m.recordSeed(env, file.name, file.checksum); err != nil {
+			return applied, err
+		}
+		applied++
+	}
+
+	return applied, nil
+}
@@ -0,0 +1,186 @@
+package migrator
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	errSessionDialect   = errors.New("session settings are only supported on postgres and mysql")
+	errUnknownIsolation = errors.New("isolation level must be read-uncommitted, read-committed, repeatable-read or serializable")
+)
+
+var isolationLevels = map[string]sql.IsolationLevel{
+	"read-uncommitted": sql.LevelReadUncommitted,
+	"read-committed":   sql.LevelReadCommitted,
+	"repeatable-read":  sql.LevelRepeatableRead,
+	"serializable":     sql.LevelSerializable,
+}
+
+func mergeParams(params map[string]string, more map[string]string) map[string]string {
+	merged := make(map[string]string, len(params)+len(more))
+	for key, value := range params {
+		merged[key] = value
+	}
+	for key, value := range more {
+		merged[key] = value
+	}
+	return merged
+}
+
+// sessionTimeoutParams returns the driver url params and session variables setting the statement
+// and lock timeouts of the migration session, a zero timeout being left unset.
+func sessionTimeoutParams(dialect Dialect, statement time.Duration, lock time.Duration) (map[string]string, map[string]string, error) {
+	driverParams, sessionParams := make(map[string]string), make(map[string]string)
+
+	switch dialect {
+	case DialectPostgres:
+		if statement > 0 {
+			sessionParams["statement_timeout"] = fmt.Sprint(statement.Milliseconds())
+		}
+		if lock > 0 {
+			sessionParams["lock_timeout"] = fmt.Sprint(lock.Milliseconds())
+		}
+	case DialectMySQL:
+		if statement > 0 {
+			driverParams["x-statement-timeout"] = fmt.Sprint(statement.Milliseconds())
+		}
+		if lock > 0 {
+			// lock_wait_timeout is in seconds, and at least 1
+			sessionParams["lock_wait_timeout"] = fmt.Sprint(max(1, int64(lock.Round(time.Second)/time.Second)))
+		}
+	default:
+		return nil, nil, errSessionDialect
+	}
+
+	return driverParams, sessionParams, nil
+}
+
+// isolationParams returns the session variables making the transactions of the migration session
+// run at level, and never read-only.
+func isolationParams(dialect Dialect, level sql.IsolationLevel) (map[string]string, error) {
+	name := strings.ToLower(level.String())
+
+	switch dialect {
+	case DialectPostgres:
+		return map[string]string{"default_transaction_isolation": name, "default_transaction_read_only": "off"}, nil
+	case DialectMySQL:
+		return map[string]string{
+			"transaction_isolation": "'" + strings.ToUpper(strings.ReplaceAll(name, " ", "-")) + "'",
+			"transaction_read_only": "0",
+		}, nil
+	default:
+		return nil, errSessionDialect
+	}
+}
+
+// addSessionParams adds the session variables to the driver url: as -c options on postgres, as
+// params on mysql, where the driver sets unknown params as system variables.
+func (m *Migrator) addSessionParams(query url.Values) {
+	if len(m.sessionParams) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(m.sessionParams))
+	for key := range m.sessionParams {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if m.dialect != DialectPostgres {
+		for _, key := range keys {
+			query.Set(key, m.sessionParams[key])
+		}
+		return
+	}
+
+	options := make([]string, 0, len(keys)+1)
+	if existing := query.Get("options"); existing != "" {
+		options = append(options, existing)
+	}
+	for _, key := range keys {
+		options = append(options, fmt.Sprintf("-c %s=%s", key, strings.ReplaceAll(m.sessionParams[key], " ", `\ `)))
+	}
+	query.Set("options", strings.Join(options, " "))
+}
+
+// setSessionParams reopens the database driver with the driver params and session variables added.
+func (m *Migrator) setSessionParams(driverParams map[string]string, sessionParams map[string]string) error {
+	if m.databaseURL == "" {
+		return errDriverParamsNeedURL
+	}
+
+	previousDriverParams, previousSessionParams := m.driverParams, m.sessionParams
+	m.driverParams = mergeParams(previousDriverParams, driverParams)
+	m.sessionParams = mergeParams(previousSessionParams, sessionParams)
+
+	if err := m.reopenDriver(); err != nil {
+		m.driverParams, m.sessionParams = previousDriverParams, previousSessionParams
+		return err
+	}
+	return nil
+}
+
+// SetSessionTimeouts reopens the database driver so that no statement of a migration runs longer
+// than statement, nor waits longer than lock for a lock: statement_timeout and lock_timeout on
+// postgres, x-statement-timeout and lock_wait_timeout on mysql. A zero timeout is left unset.
+func (m *Migrator) SetSessionTimeouts(statement time.Duration, lock time.Duration) error {
+	driverParams, sessionParams, err := sessionTimeoutParams(m.dialect, statement, lock)
+	if err != nil {
+		return err
+	}
+	return m.setSessionParams(driverParams, sessionParams)
+}
+
+// WithSessionTimeouts see SetSessionTimeouts, for a migrator created with NewFromURL.
+func WithSessionTimeouts(statement time.Duration, lock time.Duration) Option {
+	return func(m *Migrator) {
+		driverParams, sessionParams, err := sessionTimeoutParams(m.dialect, statement, lock)
+		if err != nil {
+			m.logger.Error("can't set session timeouts", "error", err)
+			return
+		}
+
+		m.driverParams = mergeParams(m.driverParams, driverParams)
+		m.sessionParams = mergeParams(m.sessionParams, sessionParams)
+	}
+}
+
+func ParseIsolationLevel(name string) (sql.IsolationLevel, error) {
+	level, ok := isolationLevels[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", errUnknownIsolation, name)
+	}
+	return level, nil
+}
+
+// SetIsolationLevel runs the transactions of Go migrations at level, and for a migrator created
+// with NewFromURL reopens the database driver so that every transaction of the migration session
+// does, none being read-only.
+func (m *Migrator) SetIsolationLevel(level sql.IsolationLevel) error {
+	m.isolation = level
+	if m.databaseURL == "" {
+		return nil
+	}
+
+	sessionParams, err := isolationParams(m.dialect, level)
+	if err != nil {
+		return err
+	}
+	return m.setSessionParams(nil, sessionParams)
+}
+
+// WithIsolationLevel see SetIsolationLevel.
+func WithIsolationLevel(level sql.IsolationLevel) Option {
+	return func(m *Migrator) {
+		m.isolation = level
+		if sessionParams, err := isolationParams(m.dialect, level); err == nil {
+			m.sessionParams = mergeParams(m.sessionParams, sessionParams)
+		}
+	}
+}
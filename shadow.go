@@ -0,0 +1,143 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4"
+	"strings"
+)
+
+var (
+	errShadowIsTarget = errors.New("the shadow database must not be the migrated database")
+	errOpenDBNeedsURL = errors.New("only a migrator created with NewFromURL can open its database")
+	errNoScratchURL   = errors.New("testing the migrations needs the url of a disposable database")
+)
+
+// ShadowReport is the outcome of ShadowVerify: the migrations pending on the database, and the
+// statements still needed to bring the shadow database to the desired schema once they ran.
+type ShadowReport struct {
+	Pending    []uint
+	Version    uint
+	Mismatches []string
+}
+
+// sqlOpenArgs returns the database/sql driver name and data source name for a golang-migrate url,
+// without the x- parameters only golang-migrate understands.
+func sqlOpenArgs(databaseURL string) (string, string, error) {
+	scheme, rest, ok := strings.Cut(databaseURL, "://")
+	if !ok {
+		return "", "", fmt.Errorf("%w: %s", errUnsupportedDialect, databaseURL)
+	}
+
+	rest, query, _ := strings.Cut(rest, "?")
+	params := make([]string, 0)
+	for _, param := range strings.Split(query, "&") {
+		if param != "" && !strings.HasPrefix(param, "x-") {
+			params = append(params, param)
+		}
+	}
+	if len(params) > 0 {
+		rest += "?" + strings.Join(params, "&")
+	}
+
+	switch {
+	case strings.HasPrefix(scheme, "pgx"):
+		return "pgx", "postgres://" + rest, nil
+	case dialectOf(scheme) == DialectPostgres:
+		return "postgres", "postgres://" + rest, nil
+	case dialectOf(scheme) == DialectMySQL:
+		return "mysql", rest, nil
+	case dialectOf(scheme) == DialectSQLite:
+		return "sqlite3", rest, nil
+	default:
+		return "", "", fmt.Errorf("%w: %s", errUnsupportedDialect, scheme)
+	}
+}
+
+// OpenDB opens the database of a migrator created with NewFromURL with database/sql and uses it as
+// SetDB does, for the commands reading the schema. Its driver must be registered, as golang-migrate
+// drivers do, and it's closed along with the migrator.
+func (m *Migrator) OpenDB() error {
+	if m.databaseURL == "" {
+		return errOpenDBNeedsURL
+	}
+
+	db, err := m.sqlOpen(m.databaseURL)
+	if err != nil {
+		return err
+	}
+
+	m.db = db
+	m.ownsDB = true
+	return nil
+}
+
+// newShadow opens a Migrator on shadowURL running the migrations of m the way m runs them.
+func (m *Migrator) newShadow(shadowURL string) (*Migrator, error) {
+	db, err := m.sqlOpen(shadowURL)
+	if err != nil {
+		return nil, err
+	}
+
+	withMigrations := func(shadow *Migrator) {
+		shadow.goMigrations = m.goMigrations
+		shadow.versionHooks = m.versionHooks
+		shadow.schemaFunc = m.schemaFunc
+	}
+
+	// the shadow keeps its own ignored tables, made by newMigrator from its migrations table
+	shadow, err := NewFromURL(shadowURL, m.migrationsFilePath, nil,
+		WithLogger(m.logger), WithPrefetchMigrations(m.prefetchMigrations), WithLockTimeout(m.lockTimeout),
+		WithMigrationsTable(m.migrationsTable), WithDriverParams(m.driverParams), withRunSettingsOf(m), withMigrations)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	shadow.db = db
+	return shadow, nil
+}
+
+// ShadowVerify applies every migration to the scratch database at shadowURL, then compares its
+// schema with the desired schema of SetSchemaFunc, before the pending migrations touch the database.
+// The database/sql driver of the shadow database must be registered, as golang-migrate drivers do.
+func (m *Migrator) ShadowVerify(shadowURL string) (*ShadowReport, error) {
+	if m.schemaFunc == nil {
+		return nil, errNoSchemaFunc
+	}
+
+	if m.databaseURL != "" && shadowURL == m.databaseURL {
+		return nil, errShadowIsTarget
+	}
+
+	pending, err := m.pendingVersions(0)
+	if err != nil {
+		return nil, err
+	}
+
+	shadow, err := m.newShadow(shadowURL)
+	if err != nil {
+		return nil, fmt.Errorf("can't open shadow database: %w", err)
+	}
+	defer func() {
+		_, _ = shadow.Close()
+		_ = shadow.db.Close()
+	}()
+
+	if err = shadow.Up(0); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return nil, fmt.Errorf("shadow database: %w", err)
+	}
+
+	report := &ShadowReport{Pending: pending}
+	if report.Version, _, err = shadow.migrate.Version(); err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return nil, err
+	}
+
+	diff, err := shadow.diffDB(true)
+	if err != nil {
+		return nil, err
+	}
+	report.Mismatches = upStatements(diff)
+
+	return report, nil
+}
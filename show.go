@@ -0,0 +1,139 @@
+package migrator
+
+import (
+	"fmt"
+	"github.com/golang-migrate/migrate/v4/source"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	ansiReset   = "\033[0m"
+	ansiKeyword = "\033[1;34m"
+	ansiString  = "\033[32m"
+	ansiComment = "\033[90m"
+)
+
+var (
+	sqlTokenRegexp = regexp.MustCompile(`(?s)--[^\n]*|/\*.*?\*/|'(?:[^']|'')*'|\b[A-Za-z_]+\b`)
+
+	sqlKeywords = map[string]struct{}{}
+)
+
+func init() {
+	for _, keyword := range strings.Fields(`ADD ALTER AND AS ASC BEGIN BY CASCADE CHECK COLUMN COMMIT CONSTRAINT CREATE
+		DEFAULT DELETE DESC DISTINCT DROP EXISTS FOREIGN FROM FUNCTION GROUP HAVING IF IN INDEX INSERT INTO IS JOIN KEY
+		LEFT LIMIT NOT NULL ON OR ORDER PRIMARY REFERENCES RENAME RETURNS SELECT SET TABLE THEN TO TRIGGER UNIQUE UPDATE
+		USING VALUES VIEW WHEN WHERE WITH`) {
+		sqlKeywords[keyword] = struct{}{}
+	}
+}
+
+// highlightSQL colors keywords, strings and comments of sql with ANSI escape codes.
+func highlightSQL(sql string) string {
+	return sqlTokenRegexp.ReplaceAllStringFunc(sql, func(token string) string {
+		switch {
+		case strings.HasPrefix(token, "--"), strings.HasPrefix(token, "/*"):
+			return ansiComment + token + ansiReset
+		case strings.HasPrefix(token, "'"):
+			return ansiString + token + ansiReset
+		}
+
+		if _, ok := sqlKeywords[strings.ToUpper(token)]; ok {
+			return ansiKeyword + token + ansiReset
+		}
+		return token
+	})
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// MigrationSQL returns the name and body of the direction (up or down) of the migration of version.
+func (m *Migrator) MigrationSQL(version uint, direction string) (string, string, error) {
+	var (
+		migration *source.Migration
+		ok        bool
+	)
+
+	switch direction {
+	case directionUp:
+		migration, ok = m.source.migrations.Up(version)
+	case directionDown:
+		migration, ok = m.source.migrations.Down(version)
+	default:
+		return "", "", fmt.Errorf("direction must be up or down, got %s", direction)
+	}
+	if !ok {
+		return "", "", fmt.Errorf("no %s migration for version %d", direction, version)
+	}
+
+	r, _, err := m.source.read(migration)
+	if err != nil {
+		return "", "", err
+	}
+	defer r.Close()
+
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return "", "", err
+	}
+
+	name := migration.Raw
+	if name == "" {
+		name = fmt.Sprintf("go migration %d (%s)", version, migration.Identifier)
+	}
+	return name, string(body), nil
+}
+
+// Show prints the up and down migrations of version, or only the given direction, with a header
+// per file. The SQL is highlighted when w is a terminal.
+func (m *Migrator) Show(w io.Writer, version uint, directions ...string) error {
+	if len(directions) == 0 {
+		directions = []string{directionUp, directionDown}
+	}
+
+	f, ok := w.(*os.File)
+	highlight := ok && isTerminal(f)
+
+	shown := 0
+	for _, direction := range directions {
+		name, body, err := m.MigrationSQL(version, direction)
+		if err != nil {
+			if len(directions) == 1 {
+				return err
+			}
+			continue
+		}
+
+		if shown > 0 {
+			if _, err = fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+
+		header := fmt.Sprintf("-- ==> %s (%s)", name, direction)
+		if highlight {
+			header, body = ansiComment+header+ansiReset, highlightSQL(body)
+		}
+
+		if _, err = fmt.Fprintf(w, "%s\n%s", header, body); err != nil {
+			return err
+		}
+		if !strings.HasSuffix(body, "\n") {
+			if _, err = fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		shown++
+	}
+
+	if shown == 0 {
+		return fmt.Errorf("no migration for version %d", version)
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package migrator
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	errNotEd25519Key  = errors.New("key is not an ed25519 key")
+	errBadSignature   = errors.New("signature is invalid")
+	errMissingPEMData = errors.New("no PEM data found")
+	errNoVerifier     = errors.New("signed migrations are required but no key to verify them was given")
+)
+
+// Signer signs manifests, the signature being stored next to the manifest in SignatureFile.
+type Signer interface {
+	SignatureFile() string
+	Sign(payload []byte) ([]byte, error)
+}
+
+// SignatureVerifier checks signatures made by the matching Signer.
+type SignatureVerifier interface {
+	SignatureFile() string
+	Verify(payload []byte, signature []byte) error
+}
+
+func readPEM(path string) ([]byte, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(body)
+	if block == nil {
+		return nil, fmt.Errorf("%s: %w", path, errMissingPEMData)
+	}
+	return block.Bytes, nil
+}
+
+type ed25519Signer struct {
+	key ed25519.PrivateKey
+}
+
+type ed25519Verifier struct {
+	key ed25519.PublicKey
+}
+
+// LoadSigningKey reads an ed25519 private key in PKCS#8 PEM, as made by
+// openssl genpkey -algorithm ed25519.
+func LoadSigningKey(path string) (Signer, error) {
+	der, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errNotEd25519Key
+	}
+	return &ed25519Signer{key: privateKey}, nil
+}
+
+// LoadVerifyKey reads an ed25519 public key in PKIX PEM, as made by openssl pkey -pubout.
+func LoadVerifyKey(path string) (SignatureVerifier, error) {
+	der, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, errNotEd25519Key
+	}
+	return &ed25519Verifier{key: publicKey}, nil
+}
+
+func (s *ed25519Signer) SignatureFile() string {
+	return manifestFile + ".sig"
+}
+
+func (s *ed25519Signer) Sign(payload []byte) ([]byte, error) {
+	return []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, payload)) + "\n"), nil
+}
+
+func (v *ed25519Verifier) SignatureFile() string {
+	return manifestFile + ".sig"
+}
+
+func (v *ed25519Verifier) Verify(payload []byte, signature []byte) error {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil || !ed25519.Verify(v.key, payload, sig) {
+		return errBadSignature
+	}
+	return nil
+}
+
+// GPGSigner signs with the gpg binary, as user (a key id or email, the default key when empty).
+type GPGSigner struct {
+	User string
+}
+
+// GPGVerifier verifies with the gpg binary against keyring, the default keyring when empty.
+type GPGVerifier struct {
+	Keyring string
+}
+
+func (s *GPGSigner) SignatureFile() string {
+	return manifestFile + ".asc"
+}
+
+func (s *GPGSigner) Sign(payload []byte) ([]byte, error) {
+	args := []string{"--batch", "--armor", "--detach-sign"}
+	if s.User != "" {
+		args = append(args, "--local-user", s.User)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("gpg", append(args, "--output", "-", "-")...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stderr = &stderr
+
+	signature, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("gpg: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return signature, nil
+}
+
+func (v *GPGVerifier) SignatureFile() string {
+	return manifestFile + ".asc"
+}
+
+func (v *GPGVerifier) Verify(payload []byte, signature []byte) error {
+	dir, err := os.MkdirTemp("", "migrator-gpg-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	signaturePath := filepath.Join(dir, v.SignatureFile())
+	if err = os.WriteFile(signaturePath, signature, 0600); err != nil {
+		return err
+	}
+
+	args := []string{"--batch"}
+	if v.Keyring != "" {
+		args = append(args, "--no-default-keyring", "--keyring", v.Keyring)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("gpg", append(args, "--verify", signaturePath, "-")...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stderr = &stderr
+
+	if err = cmd.Run(); err != nil {
+		return fmt.Errorf("%w: %s", errBadSignature, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// verifyManifestSignature checks the signature of the manifest of fsys.
+func verifyManifestSignature(fsys fs.FS, manifest []byte, verifier SignatureVerifier) error {
+	signature, err := fs.ReadFile(fsys, verifier.SignatureFile())
+	if err != nil {
+		return fmt.Errorf("manifest isn't signed: %w", err)
+	}
+	return verifier.Verify(manifest, signature)
+}
+
+// SignManifest signs the MANIFEST.json of the migrations directory, see WriteManifest.
+func (m *Migrator) SignManifest(signer Signer) error {
+	manifest, err := fs.ReadFile(m.source.fsys, manifestFile)
+	if err != nil {
+		return err
+	}
+
+	signature, err := signer.Sign(manifest)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(m.migrationsFilePath, signer.SignatureFile()), signature, 0666)
+}
+
+// RequireSignedManifest makes up, down, goto and rollback refuse to run unless the migrations
+// match a manifest whose signature verifier accepts.
+func (m *Migrator) RequireSignedManifest(verifier SignatureVerifier) {
+	m.manifestVerifier = verifier
+	m.requireSigned = true
+}
@@ -0,0 +1,60 @@
+package migrator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4"
+	"os"
+	"path/filepath"
+)
+
+const snapshotFile = "schema.sql"
+
+// Snapshot returns the CREATE TABLE statements of the live schema, without the tables of the
+// migrator, headed by the version the database is at.
+func (m *Migrator) Snapshot() (string, error) {
+	live, err := m.LiveSchema()
+	if err != nil {
+		return "", err
+	}
+	live.sortTables()
+
+	header := "-- no migration applied"
+	version, dirty, err := m.migrate.Version()
+	switch {
+	case errors.Is(err, migrate.ErrNilVersion):
+	case err != nil:
+		return "", err
+	case dirty:
+		header = fmt.Sprintf("-- schema at version %d (dirty)", version)
+	default:
+		header = fmt.Sprintf("-- schema at version %d", version)
+	}
+
+	return header + "\n" + string(renderSchema(m.dialect, live)), nil
+}
+
+// renderSchema returns the CREATE TABLE statements of s, each preceded by an empty line.
+func renderSchema(d Dialect, s *Schema) []byte {
+	var buffer bytes.Buffer
+	for _, table := range s.Tables {
+		buffer.WriteString("\n")
+		renderStatements(&buffer, []string{createTableSQL(d, table)}, defaultTerminator)
+	}
+	return buffer.Bytes()
+}
+
+// WriteSnapshot writes the Snapshot to path, or to schema.sql in the migrations directory when
+// path is empty, and returns the path written.
+func (m *Migrator) WriteSnapshot(path string) (string, error) {
+	snapshot, err := m.Snapshot()
+	if err != nil {
+		return "", err
+	}
+
+	if path == "" {
+		path = filepath.Join(m.migrationsFilePath, snapshotFile)
+	}
+	return path, os.WriteFile(path, []byte(snapshot), 0666)
+}
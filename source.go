@@ -0,0 +1,225 @@
+package migrator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4/source"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+var errSourceOpenNotSupported = errors.New("migration source can't be opened from an url")
+
+// migrationSource serves the migration files of a directory merged with the registered Go migrations.
+type migrationSource struct {
+	fsys       fs.FS
+	path       string
+	migrations *source.Migrations
+	render     func(migration *source.Migration, body []byte) ([]byte, error)
+}
+
+func newMigrationSource(fsys fs.FS, path string, goMigrations map[uint]*goMigration) (*migrationSource, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := source.NewMigrations()
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		migration, err := source.DefaultParse(e.Name())
+		if err != nil {
+			continue
+		}
+
+		if !migrations.Append(migration) {
+			return nil, fmt.Errorf("duplicate migration file: %s", e.Name())
+		}
+	}
+
+	for version, goMigration := range goMigrations {
+
+		for direction, fn := range map[source.Direction]GoMigrationFunc{source.Up: goMigration.up, source.Down: goMigration.down} {
+			if fn == nil {
+				continue
+			}
+
+			migration := &source.Migration{
+				Version:    version,
+				Identifier: goMigrationIdentifier,
+				Direction:  direction,
+			}
+
+			if !migrations.Append(migration) {
+				return nil, fmt.Errorf("go migration %d conflicts with a migration file of the same version", version)
+			}
+		}
+	}
+
+	return &migrationSource{
+		fsys:       fsys,
+		path:       path,
+		migrations: migrations,
+	}, nil
+}
+
+func (s *migrationSource) notExist(op string) error {
+	return &fs.PathError{
+		Op:   op,
+		Path: s.path,
+		Err:  fs.ErrNotExist,
+	}
+}
+
+func (s *migrationSource) Open(url string) (source.Driver, error) {
+	return nil, errSourceOpenNotSupported
+}
+
+func (s *migrationSource) Close() error {
+	return nil
+}
+
+func (s *migrationSource) First() (uint, error) {
+	if version, ok := s.migrations.First(); ok {
+		return version, nil
+	}
+	return 0, s.notExist("first")
+}
+
+func (s *migrationSource) Prev(version uint) (uint, error) {
+	if prevVersion, ok := s.migrations.Prev(version); ok {
+		return prevVersion, nil
+	}
+	return 0, s.notExist("prev for version " + strconv.FormatUint(uint64(version), 10))
+}
+
+func (s *migrationSource) Next(version uint) (uint, error) {
+	if nextVersion, ok := s.migrations.Next(version); ok {
+		return nextVersion, nil
+	}
+	return 0, s.notExist("next for version " + strconv.FormatUint(uint64(version), 10))
+}
+
+func (s *migrationSource) read(migration *source.Migration) (io.ReadCloser, string, error) {
+	if migration.Identifier == goMigrationIdentifier && migration.Raw == "" {
+		return io.NopCloser(bytes.NewReader(goMigrationMarker(migration.Version, migration.Direction))), migration.Identifier, nil
+	}
+
+	body, err := s.fsys.Open(migration.Raw)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if s.render == nil {
+		return body, migration.Identifier, nil
+	}
+	defer body.Close()
+
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rendered, err := s.render(migration, raw)
+	if err != nil {
+		return nil, "", fmt.Errorf("can't render %s: %w", migration.Raw, err)
+	}
+	return io.NopCloser(bytes.NewReader(rendered)), migration.Identifier, nil
+}
+
+// readFile returns the body of the SQL migration, rendered when it's a template.
+func (s *migrationSource) readFile(migration *source.Migration) ([]byte, error) {
+	r, _, err := s.read(migration)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+func (s *migrationSource) ReadUp(version uint) (io.ReadCloser, string, error) {
+	if migration, ok := s.migrations.Up(version); ok {
+		return s.read(migration)
+	}
+	return nil, "", s.notExist("read up for version " + strconv.FormatUint(uint64(version), 10))
+}
+
+func (s *migrationSource) ReadDown(version uint) (io.ReadCloser, string, error) {
+	if migration, ok := s.migrations.Down(version); ok {
+		return s.read(migration)
+	}
+	return nil, "", s.notExist("read down for version " + strconv.FormatUint(uint64(version), 10))
+}
+
+// versions returns the versions of every migration of the source, in order.
+func (s *migrationSource) versions() []uint {
+	versions := make([]uint, 0)
+	for version, ok := s.migrations.First(); ok; version, ok = s.migrations.Next(version) {
+		versions = append(versions, version)
+	}
+	return versions
+}
+
+type migrationFiles struct {
+	version    uint
+	identifier string
+	up         string
+	down       string
+}
+
+// listMigrationFiles returns the versioned migration files of dir, ordered by version.
+func listMigrationFiles(dir string) ([]*migrationFiles, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint]*migrationFiles)
+	versions := make([]uint, 0, len(entries))
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		migration, err := source.DefaultParse(e.Name())
+		if err != nil {
+			continue
+		}
+
+		files, ok := byVersion[migration.Version]
+		if !ok {
+			files = &migrationFiles{version: migration.Version, identifier: migration.Identifier}
+			byVersion[migration.Version] = files
+			versions = append(versions, migration.Version)
+		}
+
+		path := filepath.Join(dir, e.Name())
+		if migration.Direction == source.Up {
+			files.up = path
+		} else {
+			files.down = path
+		}
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i] < versions[j]
+	})
+
+	list := make([]*migrationFiles, len(versions))
+	for i, version := range versions {
+		list[i] = byVersion[version]
+	}
+
+	return list, nil
+}
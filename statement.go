@@ -0,0 +1,163 @@
+package migrator
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+const defaultTerminator = ";"
+
+var (
+	delimiterRegexp  = regexp.MustCompile(`(?i)^\s*DELIMITER\s+(\S+)\s*$`)
+	dollarQuoteRegex = regexp.MustCompile(`^\$[A-Za-z_0-9]*\$`)
+)
+
+// SetStatementTerminator ends the statements of generated migrations with terminator instead of ";",
+// wrapping them in DELIMITER lines, and makes it the initial terminator of the statement splitter.
+func (m *Migrator) SetStatementTerminator(terminator string) {
+	m.terminator = terminator
+}
+
+// EnableStatementSplitting runs the statements of SQL migrations one by one, splitting them on the
+// terminator outside of quotes, comments and dollar-quoted bodies. DELIMITER lines change the
+// terminator for the rest of the file, as with the MySQL client.
+func (m *Migrator) EnableStatementSplitting() {
+	m.splitStatements = true
+}
+
+func WithStatementTerminator(terminator string) Option {
+	return func(m *Migrator) {
+		m.terminator = terminator
+	}
+}
+
+func WithStatementSplitting() Option {
+	return func(m *Migrator) {
+		m.splitStatements = true
+	}
+}
+
+func (m *Migrator) statementTerminator() string {
+	if m.terminator == "" {
+		return defaultTerminator
+	}
+	return m.terminator
+}
+
+func renderStatements(buffer *bytes.Buffer, statements []string, terminator string) {
+	for _, statement := range statements {
+		buffer.WriteString(statement)
+		buffer.WriteString(terminator)
+		buffer.WriteString("\n")
+	}
+}
+
+// splitStatements splits body into statements, dropping the DELIMITER lines and the terminators.
+func splitStatements(body string, terminator string) []string {
+	var (
+		statements = make([]string, 0)
+		current    strings.Builder
+		hasCode    bool
+	)
+
+	flush := func() {
+		if hasCode {
+			statements = append(statements, strings.TrimSpace(current.String()))
+		}
+		current.Reset()
+		hasCode = false
+	}
+
+	lineStart := true
+	for i := 0; i < len(body); {
+		if lineStart {
+			end := strings.IndexByte(body[i:], '\n')
+			if end < 0 {
+				end = len(body) - i
+			}
+
+			if match := delimiterRegexp.FindStringSubmatch(body[i : i+end]); match != nil {
+				flush()
+				terminator = match[1]
+				i += end + 1
+				continue
+			}
+			lineStart = false
+		}
+
+		rest := body[i:]
+		switch {
+		case strings.HasPrefix(rest, terminator):
+			flush()
+			i += len(terminator)
+			continue
+		case strings.HasPrefix(rest, "--"):
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			current.WriteString(rest[:end])
+			i += end
+			continue
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				end = len(rest)
+			} else {
+				end += 4
+			}
+			current.WriteString(rest[:end])
+			i += end
+			continue
+		case rest[0] == '\'', rest[0] == '"', rest[0] == '`':
+			end := closingQuote(rest, rest[0])
+			current.WriteString(rest[:end])
+			hasCode = true
+			i += end
+			continue
+		case rest[0] == '$':
+			if tag := dollarQuoteRegex.FindString(rest); tag != "" {
+				end := strings.Index(rest[len(tag):], tag)
+				if end < 0 {
+					end = len(rest)
+				} else {
+					end += 2 * len(tag)
+				}
+				current.WriteString(rest[:end])
+				hasCode = true
+				i += end
+				continue
+			}
+		}
+
+		if rest[0] == '\n' {
+			lineStart = true
+		} else if rest[0] != ' ' && rest[0] != '\t' && rest[0] != '\r' {
+			hasCode = true
+		}
+		current.WriteByte(rest[0])
+		i++
+	}
+
+	flush()
+	return statements
+}
+
+// closingQuote returns the length of the quoted string s starts with, doubled quotes and
+// backslashes escaping the quote.
+func closingQuote(s string, quote byte) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(s)
+}
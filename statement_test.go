@@ -0,0 +1,106 @@
+package migrator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		terminator string
+		want       []string
+	}{
+		{
+			name:       "statements",
+			body:       "CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);\n",
+			terminator: ";",
+			want:       []string{"CREATE TABLE a (id INT)", "CREATE TABLE b (id INT)"},
+		},
+		{
+			name:       "last statement without terminator",
+			body:       "SELECT 1;\nSELECT 2",
+			terminator: ";",
+			want:       []string{"SELECT 1", "SELECT 2"},
+		},
+		{
+			name:       "terminators in quotes",
+			body:       "INSERT INTO t VALUES ('a;b', \"c;d\", `e;f`);",
+			terminator: ";",
+			want:       []string{"INSERT INTO t VALUES ('a;b', \"c;d\", `e;f`)"},
+		},
+		{
+			name:       "escaped quotes",
+			body:       "SELECT 'it''s;', 'a\\';b';SELECT 2;",
+			terminator: ";",
+			want:       []string{"SELECT 'it''s;', 'a\\';b'", "SELECT 2"},
+		},
+		{
+			name:       "terminators in comments",
+			body:       "-- first; statement\nSELECT 1; /* second; */ SELECT 2;",
+			terminator: ";",
+			want:       []string{"-- first; statement\nSELECT 1", "/* second; */ SELECT 2"},
+		},
+		{
+			name:       "comment only statements are dropped",
+			body:       "SELECT 1;\n-- the end\n",
+			terminator: ";",
+			want:       []string{"SELECT 1"},
+		},
+		{
+			name:       "dollar quoted body",
+			body:       "CREATE FUNCTION f() RETURNS int AS $body$ BEGIN RETURN 1; END; $body$ LANGUAGE plpgsql;\nSELECT f();",
+			terminator: ";",
+			want: []string{
+				"CREATE FUNCTION f() RETURNS int AS $body$ BEGIN RETURN 1; END; $body$ LANGUAGE plpgsql",
+				"SELECT f()",
+			},
+		},
+		{
+			name:       "anonymous dollar quote",
+			body:       "DO $$ BEGIN PERFORM 1; END $$;",
+			terminator: ";",
+			want:       []string{"DO $$ BEGIN PERFORM 1; END $$"},
+		},
+		{
+			name:       "positional parameters aren't dollar quotes",
+			body:       "PREPARE p AS SELECT $1;SELECT 2;",
+			terminator: ";",
+			want:       []string{"PREPARE p AS SELECT $1", "SELECT 2"},
+		},
+		{
+			name:       "delimiter lines",
+			body:       "DELIMITER //\nCREATE TRIGGER t BEFORE INSERT ON a FOR EACH ROW BEGIN SET NEW.x = 1; END//\nDELIMITER ;\nSELECT 1;",
+			terminator: ";",
+			want:       []string{"CREATE TRIGGER t BEFORE INSERT ON a FOR EACH ROW BEGIN SET NEW.x = 1; END", "SELECT 1"},
+		},
+		{
+			name:       "lowercase delimiter line",
+			body:       "  delimiter $$\nSELECT 1; SELECT 2$$",
+			terminator: ";",
+			want:       []string{"SELECT 1; SELECT 2"},
+		},
+		{
+			name:       "initial terminator",
+			body:       "SELECT 1; SELECT 2//\nSELECT 3//",
+			terminator: "//",
+			want:       []string{"SELECT 1; SELECT 2", "SELECT 3"},
+		},
+		{
+			name:       "blank",
+			body:       " \n\t\n",
+			terminator: ";",
+			want:       []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := splitStatements(test.body, test.terminator)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
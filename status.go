@@ -0,0 +1,88 @@
+package migrator
+
+import (
+	"errors"
+	"github.com/golang-migrate/migrate/v4"
+)
+
+type VersionStatus struct {
+	Current         *uint  `json:"current"`
+	Dirty           bool   `json:"dirty"`
+	LatestAvailable *uint  `json:"latest_available"`
+	PendingCount    int    `json:"pending_count"`
+	EstimatedMs     *int64 `json:"estimated_ms,omitempty"`
+}
+
+// Status compares the version of the database with the migrations of the source. Current is nil
+// when no migration has been applied, LatestAvailable when the source is empty. With history
+// enabled, EstimatedMs is how long the pending migrations took on this database before,
+// or are estimated to take.
+func (m *Migrator) Status() (*VersionStatus, error) {
+	status, pending, err := m.status()
+	if err != nil {
+		return nil, err
+	}
+
+	if m.historyEnabled && len(pending) > 0 {
+		if estimate, ok, err := m.EstimateDuration(pending); err == nil && ok {
+			ms := estimate.Milliseconds()
+			status.EstimatedMs = &ms
+		}
+	}
+
+	return status, nil
+}
+
+// status is Status without the estimate, along with the pending versions.
+func (m *Migrator) status() (*VersionStatus, []uint, error) {
+	status := &VersionStatus{}
+
+	version, dirty, err := m.migrate.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return nil, nil, err
+	}
+
+	if err == nil {
+		status.Current = &version
+		status.Dirty = dirty
+	}
+
+	pending := make([]uint, 0)
+	for _, v := range m.source.versions() {
+		if _, ok := m.source.migrations.Up(v); !ok {
+			continue
+		}
+
+		latest := v
+		status.LatestAvailable = &latest
+		if status.Current == nil || v > *status.Current {
+			status.PendingCount++
+			pending = append(pending, v)
+		}
+	}
+
+	return status, pending, nil
+}
+
+// PendingCount returns the number of up migrations of the source above the applied version.
+func (m *Migrator) PendingCount() (int, error) {
+	status, _, err := m.status()
+	if err != nil {
+		return 0, err
+	}
+	return status.PendingCount, nil
+}
+
+// IsUpToDate tells whether every up migration of the source is applied, for readiness probes. It
+// fails with migrate.ErrDirty when the last migration failed.
+func (m *Migrator) IsUpToDate() (bool, error) {
+	status, _, err := m.status()
+	if err != nil {
+		return false, err
+	}
+
+	if status.Dirty {
+		return false, migrate.ErrDirty{Version: int(*status.Current)}
+	}
+	return status.PendingCount == 0, nil
+}
@@ -0,0 +1,150 @@
+package migrator
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const tagTable = "schema_migrations_tags"
+
+var (
+	errNoVersionToTag = errors.New("there is no applied version to tag")
+	errTagExists      = errors.New("tag already exists, use force to move it")
+	errDirtyTag       = errors.New("can't tag a dirty version")
+)
+
+type MigrationTag struct {
+	Name      string    `json:"name"`
+	Version   uint      `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (m *Migrator) ensureTagTable() error {
+	_, err := m.db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	name VARCHAR(255) NOT NULL PRIMARY KEY,
+	version BIGINT NOT NULL,
+	created_at TIMESTAMP NOT NULL
+)`, tagTable))
+	return err
+}
+
+// Tag associates the current version with name, so that it can be used instead of a version number.
+func (m *Migrator) Tag(name string, force bool) (*MigrationTag, error) {
+	if m.db == nil {
+		return nil, errNoDB
+	}
+
+	version, dirty, err := m.migrate.Version()
+	if err != nil {
+		return nil, errNoVersionToTag
+	}
+
+	if dirty {
+		return nil, errDirtyTag
+	}
+
+	existing, err := m.lookupTag(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil && !force {
+		return nil, errTagExists
+	}
+
+	tag := &MigrationTag{Name: name, Version: version, CreatedAt: time.Now().UTC()}
+
+	d := m.dialect
+	if existing != nil {
+		_, err = m.db.Exec(fmt.Sprintf("UPDATE %s SET version = %s, created_at = %s WHERE name = %s",
+			tagTable, d.placeholder(1), d.placeholder(2), d.placeholder(3)), int64(tag.Version), tag.CreatedAt, name)
+	} else {
+		_, err = m.db.Exec(fmt.Sprintf("INSERT INTO %s (name, version, created_at) VALUES (%s, %s, %s)",
+			tagTable, d.placeholder(1), d.placeholder(2), d.placeholder(3)), name, int64(tag.Version), tag.CreatedAt)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+func (m *Migrator) lookupTag(name string) (*MigrationTag, error) {
+	if err := m.ensureTagTable(); err != nil {
+		return nil, err
+	}
+
+	var (
+		tag     = &MigrationTag{Name: name}
+		version int64
+	)
+
+	err := m.db.QueryRow(fmt.Sprintf("SELECT version, created_at FROM %s WHERE name = %s", tagTable, m.dialect.placeholder(1)), name).
+		Scan(&version, &tag.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tag.Version = uint(version)
+	return tag, nil
+}
+
+func (m *Migrator) Tags() ([]*MigrationTag, error) {
+	if m.db == nil {
+		return nil, errNoDB
+	}
+
+	if err := m.ensureTagTable(); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.Query(fmt.Sprintf("SELECT name, version, created_at FROM %s ORDER BY version, created_at", tagTable))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make([]*MigrationTag, 0)
+	for rows.Next() {
+		var (
+			tag     = &MigrationTag{}
+			version int64
+		)
+
+		if err = rows.Scan(&tag.Name, &version, &tag.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		tag.Version = uint(version)
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// ResolveVersion reads target as a version number, or else as the name of a tag.
+func (m *Migrator) ResolveVersion(target string) (uint, error) {
+	if v, err := strconv.ParseUint(target, 10, 64); err == nil {
+		return uint(v), nil
+	}
+
+	if m.db == nil {
+		return 0, fmt.Errorf("%s is neither a version nor a tag: %w", target, errNoDB)
+	}
+
+	tag, err := m.lookupTag(target)
+	if err != nil {
+		return 0, err
+	}
+
+	if tag == nil {
+		return 0, fmt.Errorf("%s is neither a version nor a tag", target)
+	}
+	return tag.Version, nil
+}
@@ -0,0 +1,93 @@
+package migrator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/Masterminds/sprig/v3"
+	"github.com/golang-migrate/migrate/v4/source"
+	"os"
+	"strings"
+	"text/template"
+)
+
+const templateSuffix = ".tmpl"
+
+var errTemplateEnv = errors.New("environment variable isn't allowed in templates")
+
+// templateData is what .sql.tmpl migrations are rendered with.
+type templateData struct {
+	Version   uint
+	Direction string
+	Dialect   Dialect
+	Values    map[string]string
+}
+
+// SetTemplateValues sets the values .sql.tmpl migrations read as {{ .Values.name }}, a missing
+// value failing the rendering.
+func (m *Migrator) SetTemplateValues(values map[string]string) {
+	m.templateValues = mergeParams(nil, values)
+}
+
+func WithTemplateValues(values map[string]string) Option {
+	return func(m *Migrator) {
+		m.SetTemplateValues(values)
+	}
+}
+
+// AllowTemplateEnv lets .sql.tmpl migrations read the environment variables names with
+// {{ env "NAME" }}, the other ones failing the rendering.
+func (m *Migrator) AllowTemplateEnv(names ...string) {
+	if m.templateEnv == nil {
+		m.templateEnv = make(map[string]struct{}, len(names))
+	}
+	for _, name := range names {
+		m.templateEnv[name] = struct{}{}
+	}
+}
+
+func WithTemplateEnv(names ...string) Option {
+	return func(m *Migrator) {
+		m.AllowTemplateEnv(names...)
+	}
+}
+
+// templateFuncs returns the sprig functions, with env reading the allowed environment variables
+// only, and without expandenv.
+func (m *Migrator) templateFuncs() template.FuncMap {
+	funcs := sprig.TxtFuncMap()
+	delete(funcs, "expandenv")
+	funcs["env"] = func(name string) (string, error) {
+		if _, ok := m.templateEnv[name]; !ok {
+			return "", fmt.Errorf("%w: %s", errTemplateEnv, name)
+		}
+		return os.Getenv(name), nil
+	}
+	return funcs
+}
+
+// renderTemplate renders the body of a .sql.tmpl migration with Go templates, leaving the other
+// migrations as they are.
+func (m *Migrator) renderTemplate(migration *source.Migration, body []byte) ([]byte, error) {
+	if !strings.HasSuffix(migration.Raw, templateSuffix) {
+		return body, nil
+	}
+
+	tmpl, err := template.New(migration.Raw).Funcs(m.templateFuncs()).Option("missingkey=error").Parse(string(body))
+	if err != nil {
+		return nil, err
+	}
+
+	values := m.templateValues
+	if values == nil {
+		values = map[string]string{}
+	}
+
+	var rendered bytes.Buffer
+	err = tmpl.Execute(&rendered, &templateData{Version: migration.Version, Direction: string(migration.Direction),
+		Dialect: m.dialect, Values: values})
+	if err != nil {
+		return nil, err
+	}
+	return rendered.Bytes(), nil
+}
@@ -0,0 +1,79 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4"
+	"strconv"
+	"time"
+)
+
+var (
+	errNotTimestampVersion = errors.New("version isn't a timestamp")
+	errUntilTimeFormat     = errors.New("time must be RFC3339, YYYY-MM-DD HH:MM:SS or YYYY-MM-DD")
+)
+
+var untilTimeFormats = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"}
+
+// ParseUntilTime reads the time of UpUntil, in the local time zone unless it has an offset.
+func ParseUntilTime(value string) (time.Time, error) {
+	for _, format := range untilTimeFormats {
+		if t, err := time.ParseInLocation(format, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: %s", errUntilTimeFormat, value)
+}
+
+// versionTime returns the time of a version created by create with the default, unix or unixNano
+// format, the default one being read in the local time zone as create writes it.
+func versionTime(version uint) (time.Time, error) {
+	digits := strconv.FormatUint(uint64(version), 10)
+
+	switch len(digits) {
+	case len(defaultTimeFormat):
+		t, err := time.ParseInLocation(defaultTimeFormat, digits, time.Local)
+		if err == nil {
+			return t, nil
+		}
+	case 10:
+		return time.Unix(int64(version), 0), nil
+	case 19:
+		return time.Unix(0, int64(version)), nil
+	}
+	return time.Time{}, fmt.Errorf("%w: %d", errNotTimestampVersion, version)
+}
+
+// UntilPlan lists the pending up migrations whose timestamp version is before until.
+func (m *Migrator) UntilPlan(until time.Time) ([]*PlannedMigration, error) {
+	plan, err := m.UpPlan(0)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, planned := range plan {
+		created, err := versionTime(planned.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		if !created.Before(until) {
+			return plan[:i], nil
+		}
+	}
+	return plan, nil
+}
+
+// UpUntil applies the pending migrations whose timestamp version is before until, to reproduce
+// the schema as of a past release.
+func (m *Migrator) UpUntil(until time.Time) error {
+	plan, err := m.UntilPlan(until)
+	if err != nil {
+		return err
+	}
+
+	if len(plan) == 0 {
+		return migrate.ErrNoChange
+	}
+	return m.Up(len(plan))
+}
@@ -0,0 +1,93 @@
+package migrator
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVersionTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		version uint
+		want    time.Time
+		err     bool
+	}{
+		{
+			name:    "default format",
+			version: 20240310153000,
+			want:    time.Date(2024, time.March, 10, 15, 30, 0, 0, time.Local),
+		},
+		{
+			name:    "unix",
+			version: 1710084600,
+			want:    time.Unix(1710084600, 0),
+		},
+		{
+			name:    "unixNano",
+			version: 1710084600123456789,
+			want:    time.Unix(0, 1710084600123456789),
+		},
+		{
+			name:    "sequence",
+			version: 42,
+			err:     true,
+		},
+		{
+			name:    "14 digits not a date",
+			version: 20241399999999,
+			err:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := versionTime(test.version)
+			if test.err {
+				if !errors.Is(err, errNotTimestampVersion) {
+					t.Fatalf("err = %v, want %v", err, errNotTimestampVersion)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !got.Equal(test.want) {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseUntilTime(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+		err   bool
+	}{
+		{value: "2024-03-10T15:30:00+02:00", want: time.Date(2024, time.March, 10, 13, 30, 0, 0, time.UTC)},
+		{value: "2024-03-10 15:30:00", want: time.Date(2024, time.March, 10, 15, 30, 0, 0, time.Local)},
+		{value: "2024-03-10T15:30:00", want: time.Date(2024, time.March, 10, 15, 30, 0, 0, time.Local)},
+		{value: "2024-03-10", want: time.Date(2024, time.March, 10, 0, 0, 0, 0, time.Local)},
+		{value: "10/03/2024", err: true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseUntilTime(test.value)
+		if test.err {
+			if !errors.Is(err, errUntilTimeFormat) {
+				t.Errorf("ParseUntilTime(%q) err = %v, want %v", test.value, err, errUntilTimeFormat)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseUntilTime(%q) err = %v", test.value, err)
+			continue
+		}
+
+		if !got.Equal(test.want) {
+			t.Errorf("ParseUntilTime(%q) = %s, want %s", test.value, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package urfavecli
+
+import (
+	"github.com/anyufly/file-migrator"
+	"github.com/spf13/cobra"
+	"github.com/urfave/cli/v2"
+	"strings"
+)
+
+// Command returns the commands of m.CobraCommand as a urfave/cli command named after its root
+// command, migrate unless migrator.WithUse renames it. Each command hands its arguments, flags
+// included, to the cobra command, so both run the same handlers and parse the same flags.
+func Command(m *migrator.Migrator, opts ...migrator.CommandOption) *cli.Command {
+	root := m.CobraCommand(opts...)
+	return convert(root, root, nil)
+}
+
+func convert(root *cobra.Command, command *cobra.Command, path []string) *cli.Command {
+	converted := &cli.Command{
+		Name:            command.Name(),
+		Aliases:         command.Aliases,
+		Usage:           strings.SplitN(command.Short, "\n", 2)[0],
+		UsageText:       command.UseLine(),
+		Description:     command.Long,
+		Hidden:          command.Hidden,
+		SkipFlagParsing: true,
+		HideHelp:        true,
+		Action: func(ctx *cli.Context) error {
+			root.SetArgs(append(append([]string{}, path...), ctx.Args().Slice()...))
+			return root.ExecuteContext(ctx.Context)
+		},
+	}
+
+	for _, child := range command.Commands() {
+		childPath := append(append([]string{}, path...), child.Name())
+		converted.Subcommands = append(converted.Subcommands, convert(root, child, childPath))
+	}
+	return converted
+}
@@ -0,0 +1,84 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"github.com/golang-migrate/migrate/v4"
+	"time"
+)
+
+const defaultWaitInterval = time.Second
+
+// WithWaitInterval sets how often WaitForVersion reads the version of the database.
+func WithWaitInterval(interval time.Duration) Option {
+	return func(m *Migrator) {
+		m.waitInterval = interval
+	}
+}
+
+func (m *Migrator) reachedVersion(version uint) (bool, error) {
+	current, dirty, err := m.migrate.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return false, err
+	}
+	return err == nil && !dirty && current >= version, nil
+}
+
+// WaitForVersion blocks until the database reaches at least version with its migration finished,
+// or ctx is done, so that replicas not running migrations can wait for the schema they need. With
+// a listener, it waits for the notifications of the migrators instead of reading the version
+// periodically.
+func (m *Migrator) WaitForVersion(ctx context.Context, version uint) error {
+	var events <-chan *MigrationEvent
+	if m.listener != nil {
+		listenCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		var err error
+		if events, err = m.Subscribe(listenCtx); err != nil {
+			m.logger.Error("can't listen for migrations, reading the version periodically", "error", err)
+		}
+	}
+
+	if reached, err := m.reachedVersion(version); err != nil || reached {
+		return err
+	}
+	m.logger.Info("waiting for the database version", "version", version)
+
+	interval := m.waitInterval
+	if interval <= 0 {
+		interval = defaultWaitInterval
+	}
+
+	for events != nil {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-events:
+			if !ok && ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			if !ok {
+				m.logger.Error("stopped listening for migrations, reading the version periodically")
+				events = nil
+			} else if event.Direction == directionUp && event.Version >= version {
+				return nil
+			}
+		}
+	}
+
+	for {
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		if reached, err := m.reachedVersion(version); err != nil || reached {
+			return err
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var errWindowTooShort = errors.New("pending migrations would run past the maintenance window")
+
+// maintenanceWindow returns the window opening daily at the clock time notBefore (15:04) and lasting
+// length that now is in, or else the next one.
+func maintenanceWindow(now time.Time, notBefore string, length time.Duration) (start time.Time, end time.Time, err error) {
+	clock, err := time.ParseInLocation("15:04", notBefore, now.Location())
+	if err != nil {
+		return start, end, fmt.Errorf("not before must be HH:MM: %w", err)
+	}
+
+	start = time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	if start.After(now) {
+		start = start.AddDate(0, 0, -1)
+	}
+
+	if now.After(start.Add(length)) {
+		start = start.AddDate(0, 0, 1)
+	}
+	return start, start.Add(length), nil
+}
+
+// pendingVersions lists the versions Up(n) would apply.
+func (m *Migrator) pendingVersions(n int) ([]uint, error) {
+	plan, err := m.UpPlan(n)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]uint, len(plan))
+	for i, planned := range plan {
+		versions[i] = planned.Version
+	}
+	return versions, nil
+}
+
+// estimateDuration estimates how long versions take from the history: their last successful run
+// when they ran before, the mean of the successful up migrations otherwise.
+func estimateDuration(entries []*HistoryEntry, versions []uint) time.Duration {
+	last := make(map[uint]int64)
+	var total, count int64
+	for _, entry := range entries {
+		if !entry.Success || entry.Direction != directionUp {
+			continue
+		}
+		last[entry.Version] = entry.DurationMs
+		total += entry.DurationMs
+		count++
+	}
+
+	var estimate int64
+	for _, version := range versions {
+		if duration, ok := last[version]; ok {
+			estimate += duration
+		} else if count > 0 {
+			estimate += total / count
+		}
+	}
+	return time.Duration(estimate) * time.Millisecond
+}
+
+// EstimateDuration estimates how long the up migrations of versions take from the durations in
+// the history table, false when the history has no successful up migration to estimate from.
+func (m *Migrator) EstimateDuration(versions []uint) (time.Duration, bool, error) {
+	entries, err := m.History()
+	if err != nil {
+		return 0, false, err
+	}
+
+	for _, entry := range entries {
+		if entry.Success && entry.Direction == directionUp {
+			return estimateDuration(entries, versions), true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// UpInWindow waits for the maintenance window opening daily at notBefore (15:04, local time) and
+// lasting length, then runs Up(n). It fails without running anything when the durations in the
+// history say the pending migrations would not finish within the window.
+func (m *Migrator) UpInWindow(ctx context.Context, n int, notBefore string, length time.Duration) error {
+	start, end, err := maintenanceWindow(time.Now(), notBefore, length)
+	if err != nil {
+		return err
+	}
+
+	if wait := time.Until(start); wait > 0 {
+		m.logger.Info("waiting for the maintenance window", "start", start.Format(time.RFC3339), "end", end.Format(time.RFC3339))
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	versions, err := m.pendingVersions(n)
+	if err != nil {
+		return err
+	}
+
+	entries, err := m.History()
+	if err != nil {
+		m.logger.Error("no history to estimate durations from, not checking the window end", "error", err)
+	} else if estimate := estimateDuration(entries, versions); time.Now().Add(estimate).After(end) {
+		return fmt.Errorf("%w: %d migrations estimated at %s, the window ends at %s",
+			errWindowTooShort, len(versions), estimate, end.Format(time.RFC3339))
+	}
+
+	return m.Up(n)
+}
@@ -0,0 +1,120 @@
+package migrator
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaintenanceWindow(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, time.March, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		notBefore string
+		length    time.Duration
+		start     time.Time
+		err       bool
+	}{
+		{
+			name:      "before the window",
+			now:       at(10, 1, 0),
+			notBefore: "02:00",
+			length:    time.Hour,
+			start:     at(10, 2, 0),
+		},
+		{
+			name:      "at the opening",
+			now:       at(10, 2, 0),
+			notBefore: "02:00",
+			length:    time.Hour,
+			start:     at(10, 2, 0),
+		},
+		{
+			name:      "in the window",
+			now:       at(10, 2, 30),
+			notBefore: "02:00",
+			length:    time.Hour,
+			start:     at(10, 2, 0),
+		},
+		{
+			name:      "after the window",
+			now:       at(10, 3, 1),
+			notBefore: "02:00",
+			length:    time.Hour,
+			start:     at(11, 2, 0),
+		},
+		{
+			name:      "in a window opened the day before",
+			now:       at(10, 0, 30),
+			notBefore: "23:00",
+			length:    2 * time.Hour,
+			start:     at(9, 23, 0),
+		},
+		{
+			name:      "before a window opening later in the day",
+			now:       at(10, 1, 30),
+			notBefore: "23:00",
+			length:    2 * time.Hour,
+			start:     at(10, 23, 0),
+		},
+		{
+			name:      "not a clock time",
+			now:       at(10, 1, 0),
+			notBefore: "2am",
+			length:    time.Hour,
+			err:       true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start, end, err := maintenanceWindow(test.now, test.notBefore, test.length)
+			if test.err {
+				if err == nil {
+					t.Fatalf("got window %s, want an error", start)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !start.Equal(test.start) || !end.Equal(test.start.Add(test.length)) {
+				t.Errorf("got %s - %s, want %s - %s", start, end, test.start, test.start.Add(test.length))
+			}
+		})
+	}
+}
+
+func TestEstimateDuration(t *testing.T) {
+	entries := []*HistoryEntry{
+		{Version: 1, Direction: directionUp, DurationMs: 1000, Success: true},
+		{Version: 2, Direction: directionUp, DurationMs: 5000, Success: false},
+		{Version: 2, Direction: directionUp, DurationMs: 3000, Success: true},
+		{Version: 2, Direction: directionDown, DurationMs: 9000, Success: true},
+		{Version: 2, Direction: directionUp, DurationMs: 2000, Success: true},
+	}
+
+	tests := []struct {
+		name     string
+		entries  []*HistoryEntry
+		versions []uint
+		want     time.Duration
+	}{
+		{name: "last successful up run", entries: entries, versions: []uint{1, 2}, want: 3 * time.Second},
+		{name: "mean of the up runs for new versions", entries: entries, versions: []uint{3}, want: 2 * time.Second},
+		{name: "no history", versions: []uint{1}, want: 0},
+		{name: "no version", entries: entries, want: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := estimateDuration(test.entries, test.versions); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,425 @@
+package migrator
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var (
+	errWizardAborted    = errors.New("wizard aborted")
+	errTableChangeEmpty = errors.New("the table change has no column, index or constraint")
+	errSQLiteConstraint = errors.New("sqlite can't add constraints to an existing table")
+	errForeignKey       = errors.New("foreign keys must reference TABLE.COLUMN")
+)
+
+// TableChange is a table created, or an existing table altered, by MakeTableMigrate: the create
+// wizard asks for it.
+type TableChange struct {
+	Table       string
+	Create      bool
+	Columns     []*TableColumn
+	Indexes     []*TableIndex
+	ForeignKeys []*ForeignKey
+	Checks      []string
+}
+
+// TableColumn is a column of a TableChange, a NOT NULL column added to an existing table needing
+// a Default to backfill the existing rows.
+type TableColumn struct {
+	Column
+	Default string
+	Unique  bool
+}
+
+type TableIndex struct {
+	Columns []string
+	Unique  bool
+}
+
+type ForeignKey struct {
+	Column          string
+	ReferenceTable  string
+	ReferenceColumn string
+	// OnDelete is the action when the referenced row is deleted, e.g. CASCADE, none when empty.
+	OnDelete string
+}
+
+func (fk *ForeignKey) constraintName(table string) string {
+	return fmt.Sprintf("%s_%s_fkey", table, fk.Column)
+}
+
+func (fk *ForeignKey) definition(d Dialect) string {
+	definition := fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s)", d.quote(fk.Column), d.quote(fk.ReferenceTable), d.quote(fk.ReferenceColumn))
+	if fk.OnDelete != "" {
+		definition += " ON DELETE " + strings.ToUpper(fk.OnDelete)
+	}
+	return definition
+}
+
+func checkName(table string, n int) string {
+	return fmt.Sprintf("%s_check%d", table, n)
+}
+
+// createTableChangeSQL returns the CREATE TABLE of change with its constraints, and the
+// CREATE INDEX of its indexes.
+func createTableChangeSQL(d Dialect, change *TableChange) ([]string, []string) {
+	definitions := make([]string, 0, len(change.Columns)+len(change.ForeignKeys)+len(change.Checks)+1)
+	primaryKeys := make([]string, 0, 1)
+
+	for _, column := range change.Columns {
+		definition := columnDefinition(d, &column.Column)
+		if column.Default != "" {
+			definition += " DEFAULT " + column.Default
+		}
+		if column.Unique {
+			definition += " UNIQUE"
+		}
+		definitions = append(definitions, definition)
+
+		if column.PrimaryKey {
+			primaryKeys = append(primaryKeys, d.quote(column.Name))
+		}
+	}
+
+	if len(primaryKeys) > 0 {
+		definitions = append(definitions, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(primaryKeys, ", ")))
+	}
+
+	for _, fk := range change.ForeignKeys {
+		definitions = append(definitions, fmt.Sprintf("CONSTRAINT %s %s", d.quote(fk.constraintName(change.Table)), fk.definition(d)))
+	}
+
+	for i, check := range change.Checks {
+		definitions = append(definitions, fmt.Sprintf("CONSTRAINT %s CHECK (%s)", d.quote(checkName(change.Table, i+1)), check))
+	}
+
+	up := []string{fmt.Sprintf("CREATE TABLE %s (\n\t%s\n)", d.quote(change.Table), strings.Join(definitions, ",\n\t"))}
+	for _, index := range change.Indexes {
+		columns := make([]*Column, 0, len(index.Columns))
+		quoted := make([]string, 0, len(index.Columns))
+		for _, name := range index.Columns {
+			columns = append(columns, &Column{Name: name})
+			quoted = append(quoted, d.quote(name))
+		}
+
+		create := "CREATE INDEX"
+		if index.Unique {
+			create = "CREATE UNIQUE INDEX"
+		}
+		up = append(up, fmt.Sprintf("%s %s ON %s (%s)", create, d.quote(indexName(change.Table, columns, index.Unique)),
+			d.quote(change.Table), strings.Join(quoted, ", ")))
+	}
+
+	return up, []string{fmt.Sprintf("DROP TABLE %s", d.quote(change.Table))}
+}
+
+// addConstraintSQL adds a constraint to an existing table, on postgres without validating the
+// existing rows under the lock adding it.
+func addConstraintSQL(d Dialect, table string, name string, definition string) ([]string, []string) {
+	quotedTable, quotedName := d.quote(table), d.quote(name)
+
+	if d == DialectPostgres {
+		return []string{
+				fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s NOT VALID", quotedTable, quotedName, definition),
+				fmt.Sprintf("ALTER TABLE %s VALIDATE CONSTRAINT %s", quotedTable, quotedName),
+			},
+			[]string{fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", quotedTable, quotedName)}
+	}
+
+	drop := "CONSTRAINT"
+	if strings.HasPrefix(definition, "FOREIGN KEY") {
+		drop = "FOREIGN KEY"
+	} else if strings.HasPrefix(definition, "CHECK") {
+		drop = "CHECK"
+	}
+	return []string{fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s", quotedTable, quotedName, definition)},
+		[]string{fmt.Sprintf("ALTER TABLE %s DROP %s %s", quotedTable, drop, quotedName)}
+}
+
+// alterTableChangeSQL adds the columns, indexes and constraints of change to an existing table the
+// way the patterns do, the down statements undoing them in reverse order.
+func alterTableChangeSQL(d Dialect, change *TableChange) ([]string, []string, bool, error) {
+	if d == DialectSQLite && len(change.ForeignKeys)+len(change.Checks) > 0 {
+		return nil, nil, false, errSQLiteConstraint
+	}
+
+	var up, down []string
+	noTransaction := false
+	add := func(statementsUp []string, statementsDown []string) {
+		up = append(up, statementsUp...)
+		down = append(statementsDown, down...)
+	}
+
+	indexes := change.Indexes
+	for _, column := range change.Columns {
+		statementsUp, statementsDown, err := addColumnSQL(d, change.Table, &column.Column, column.Default)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		add(statementsUp, statementsDown)
+		noTransaction = noTransaction || (d == DialectPostgres && !column.Nullable)
+	}
+
+	for _, column := range change.Columns {
+		if column.Unique {
+			indexes = append(indexes, &TableIndex{Columns: []string{column.Name}, Unique: true})
+		}
+	}
+
+	for _, index := range indexes {
+		columns := make([]*Column, 0, len(index.Columns))
+		for _, name := range index.Columns {
+			columns = append(columns, &Column{Name: name})
+		}
+		add(addIndexSQL(d, change.Table, columns, index.Unique))
+		noTransaction = noTransaction || d == DialectPostgres
+	}
+
+	for _, fk := range change.ForeignKeys {
+		add(addConstraintSQL(d, change.Table, fk.constraintName(change.Table), fk.definition(d)))
+	}
+
+	for i, check := range change.Checks {
+		add(addConstraintSQL(d, change.Table, checkName(change.Table, i+1), fmt.Sprintf("CHECK (%s)", check)))
+	}
+
+	return up, down, noTransaction, nil
+}
+
+// tableChangeSQL returns the up and down statements of change on the dialect d, and whether they
+// must run outside of a transaction.
+func tableChangeSQL(d Dialect, change *TableChange) ([]string, []string, bool, error) {
+	if d == DialectUnknown {
+		return nil, nil, false, errUnsupportedDialect
+	}
+
+	if change.Table == "" {
+		return nil, nil, false, errPatternTable
+	}
+
+	if len(change.Columns)+len(change.Indexes)+len(change.ForeignKeys)+len(change.Checks) == 0 {
+		return nil, nil, false, errTableChangeEmpty
+	}
+
+	if change.Create {
+		if len(change.Columns) == 0 {
+			return nil, nil, false, errPatternColumns
+		}
+
+		up, down := createTableChangeSQL(d, change)
+		return up, down, false, nil
+	}
+	return alterTableChangeSQL(d, change)
+}
+
+func (change *TableChange) migrationName() string {
+	if change.Create {
+		return "create_" + change.Table
+	}
+	return "alter_" + change.Table
+}
+
+func (m *Migrator) generateTableChange(change *TableChange,
+	timeZoneName string, format string, name string, ext string, seq bool, seqDigits int) (*generatedMigration, error) {
+
+	up, down, noTransaction, err := tableChangeSQL(m.dialect, change)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		name = change.migrationName()
+	}
+
+	return m.generateStatements(change.Table, up, down, noTransaction, timeZoneName, format, name, ext, seq, seqDigits)
+}
+
+// MakeTableMigrate creates the migration of change for the dialect of the migrator, named
+// create_<table> or alter_<table> when name is empty.
+func (m *Migrator) MakeTableMigrate(change *TableChange,
+	timeZoneName string, format string, name string, ext string, seq bool, seqDigits int) error {
+
+	generated, err := m.generateTableChange(change, timeZoneName, format, name, ext, seq, seqDigits)
+	if err != nil {
+		return err
+	}
+	return m.writeMigrate(generated)
+}
+
+func (m *Migrator) MakeTableMigrateDryRun(w io.Writer, change *TableChange,
+	timeZoneName string, format string, name string, ext string, seq bool, seqDigits int) error {
+
+	generated, err := m.generateTableChange(change, timeZoneName, format, name, ext, seq, seqDigits)
+	if err != nil {
+		return err
+	}
+	return m.printMigrate(w, generated)
+}
+
+// prompter asks the questions of the create wizard on stdin.
+type prompter struct {
+	scanner *bufio.Scanner
+}
+
+// ask returns the answer to question, fallback when it's empty.
+func (p *prompter) ask(question string, fallback string) (string, error) {
+	if fallback != "" {
+		fmt.Printf("%s [%s]: ", question, fallback)
+	} else {
+		fmt.Printf("%s: ", question)
+	}
+
+	if !p.scanner.Scan() {
+		fmt.Println()
+		return "", errWizardAborted
+	}
+
+	if answer := strings.TrimSpace(p.scanner.Text()); answer != "" {
+		return answer, nil
+	}
+	return fallback, nil
+}
+
+func (p *prompter) confirm(question string, fallback bool) (bool, error) {
+	choices := "y/N"
+	if fallback {
+		choices = "Y/n"
+	}
+
+	answer, err := p.ask(fmt.Sprintf("%s (%s)", question, choices), "")
+	if err != nil || answer == "" {
+		return fallback, err
+	}
+	return strings.HasPrefix(strings.ToLower(answer), "y"), nil
+}
+
+// runWizard asks for the table, columns, indexes and constraints of a migration, and the name of
+// the migration, empty for the default one.
+func (builder *migratorCobraCommandBuilder) runWizard() (*TableChange, string, error) {
+	p := &prompter{scanner: bufio.NewScanner(os.Stdin)}
+	change := &TableChange{}
+
+	var err error
+	for change.Table == "" {
+		if change.Table, err = p.ask("Table", ""); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if change.Create, err = p.confirm("Create the table? No to alter an existing one", true); err != nil {
+		return nil, "", err
+	}
+
+	fmt.Println("Columns as NAME:TYPE, e.g. email:varchar(255), empty to finish")
+	for {
+		spec, err := p.ask("Column", "")
+		if err != nil {
+			return nil, "", err
+		}
+		if spec == "" {
+			break
+		}
+
+		column, err := ParseColumnSpec(spec, false)
+		if err != nil || column.Type == "" {
+			fmt.Printf("%v\n", errPatternColumn)
+			continue
+		}
+
+		notNull, err := p.confirm("  NOT NULL?", false)
+		if err != nil {
+			return nil, "", err
+		}
+		column.Nullable = !notNull
+
+		if change.Create {
+			if column.PrimaryKey, err = p.confirm("  Primary key?", false); err != nil {
+				return nil, "", err
+			}
+		}
+
+		tableColumn := &TableColumn{Column: *column}
+		if !column.PrimaryKey {
+			if tableColumn.Unique, err = p.confirm("  Unique?", false); err != nil {
+				return nil, "", err
+			}
+		}
+
+		if tableColumn.Default, err = p.ask("  Default SQL value, e.g. 0 or 'none', empty for none", ""); err != nil {
+			return nil, "", err
+		}
+		change.Columns = append(change.Columns, tableColumn)
+	}
+
+	fmt.Println("Indexes as comma separated columns, e.g. last_name,first_name, empty to finish")
+	for {
+		columns, err := p.ask("Index", "")
+		if err != nil {
+			return nil, "", err
+		}
+		if columns == "" {
+			break
+		}
+
+		index := &TableIndex{}
+		for _, column := range strings.Split(columns, ",") {
+			if column = strings.TrimSpace(column); column != "" {
+				index.Columns = append(index.Columns, column)
+			}
+		}
+
+		if index.Unique, err = p.confirm("  Unique?", false); err != nil {
+			return nil, "", err
+		}
+		change.Indexes = append(change.Indexes, index)
+	}
+
+	fmt.Println("Foreign keys as COLUMN TABLE.COLUMN, e.g. user_id users.id, empty to finish")
+	for {
+		answer, err := p.ask("Foreign key", "")
+		if err != nil {
+			return nil, "", err
+		}
+		if answer == "" {
+			break
+		}
+
+		fields := strings.Fields(answer)
+		var table, column string
+		if len(fields) == 2 {
+			table, column, _ = strings.Cut(fields[1], ".")
+		}
+		if table == "" || column == "" {
+			fmt.Printf("%v\n", errForeignKey)
+			continue
+		}
+
+		fk := &ForeignKey{Column: fields[0], ReferenceTable: table, ReferenceColumn: column}
+		if fk.OnDelete, err = p.ask("  On delete, e.g. cascade or set null, empty for none", ""); err != nil {
+			return nil, "", err
+		}
+		change.ForeignKeys = append(change.ForeignKeys, fk)
+	}
+
+	fmt.Println("Check constraints as SQL expressions, e.g. price > 0, empty to finish")
+	for {
+		check, err := p.ask("Check", "")
+		if err != nil {
+			return nil, "", err
+		}
+		if check == "" {
+			break
+		}
+		change.Checks = append(change.Checks, check)
+	}
+
+	name, err := p.ask("Migration name", change.migrationName())
+	if err != nil {
+		return nil, "", err
+	}
+	return change, name, nil
+}